@@ -9,10 +9,14 @@ import (
 	"net"
 	"os"
 	"os/signal"
+	"path/filepath"
 	"regexp"
+	"strconv"
+	"strings"
 	"syscall"
 	"time"
 
+	wgwrapper "github.com/aschmidt75/go-wg-wrapper/pkg/wgwrapper"
 	config "github.com/aschmidt75/wgmesh/config"
 	meshservice "github.com/aschmidt75/wgmesh/meshservice"
 	log "github.com/sirupsen/logrus"
@@ -49,11 +53,24 @@ func NewBootstrapCommand() *BootstrapCommand {
 	c.fs.StringVar(&c.meshConfig.MeshName, "name", c.meshConfig.MeshName, "name of the mesh network.\nenv:WGMESH_MESH_NAME")
 	c.fs.StringVar(&c.meshConfig.MeshName, "n", c.meshConfig.MeshName, "name of the mesh network (short).\nenv:WGMESH_MESH_NAME")
 	c.fs.StringVar(&c.meshConfig.NodeName, "node-name", c.meshConfig.NodeName, "(optional) name of this node.\nenv:WGMESH_NODE_NAME")
-	c.fs.StringVar(&c.meshConfig.Bootstrap.MeshCIDRRange, "cidr", c.meshConfig.Bootstrap.MeshCIDRRange, "CIDR range of this mesh (internal ips).\nenv:WGMESH_CIDR_RANGE")
+	c.fs.StringVar(&c.meshConfig.Bootstrap.MeshCIDRRange, "cidr", c.meshConfig.Bootstrap.MeshCIDRRange, "CIDR range of this mesh (internal ips). May be an IPv4 private range or an IPv6 ULA range.\nenv:WGMESH_CIDR_RANGE")
+	c.fs.StringVar(&c.meshConfig.Bootstrap.MeshCIDRRange6, "cidr6", c.meshConfig.Bootstrap.MeshCIDRRange6, "(optional) IPv6 ULA range for a dual-stack mesh, in addition to -cidr.\nenv:WGMESH_CIDR_RANGE6")
 	c.fs.StringVar(&c.meshConfig.Bootstrap.MeshIPAMCIDRRange, "cidr-ipam", c.meshConfig.Bootstrap.MeshIPAMCIDRRange, "CIDR (sub)range where this bootstrap mode may allocate ips from. Must be within -cidr range.\nenv:WGMESH_CIDR_RANGE_IPAM")
+	c.fs.StringVar(&c.meshConfig.Bootstrap.MeshIPAMCIDRRange6, "cidr-ipam6", c.meshConfig.Bootstrap.MeshIPAMCIDRRange6, "IPv6 CIDR (sub)range where this bootstrap node may allocate ipv6 addresses from in a dual-stack mesh. Must be within the ipv6 range of the mesh, required with -join-existing in dual-stack meshes.\nenv:WGMESH_CIDR_RANGE_IPAM6")
 	c.fs.StringVar(&c.meshConfig.Bootstrap.NodeIP, "ip", c.meshConfig.Bootstrap.NodeIP, "internal ip of the bootstrap node. Must be set fixed for bootstrap nodes.\nenv:WGMESH_MESH_IP")
+	c.fs.StringVar(&c.meshConfig.Bootstrap.NodeIP6, "ip6", c.meshConfig.Bootstrap.NodeIP6, "internal IPv6 ip of the bootstrap node in a dual-stack mesh, see -cidr6.\nenv:WGMESH_MESH_IP6")
 	c.fs.StringVar(&c.meshConfig.Wireguard.ListenAddr, "listen-addr", c.meshConfig.Wireguard.ListenAddr, "external wireguard ip.\nenv:WGMESH_WIREGUARD_LISTEN_ADDR")
 	c.fs.IntVar(&c.meshConfig.Wireguard.ListenPort, "listen-port", c.meshConfig.Wireguard.ListenPort, "set the (external) wireguard listen port.\nenv:WGMESH_WIREGUARD_LISTEN_PORT")
+	c.fs.StringVar(&c.meshConfig.Wireguard.NAT, "nat", c.meshConfig.Wireguard.NAT, "auto, on or off. Whether this node is behind NAT. auto compares the external ip with local interface addresses.\nenv:WGMESH_NAT")
+	c.fs.IntVar(&c.meshConfig.Wireguard.PersistentKeepaliveSecs, "persistent-keepalive", c.meshConfig.Wireguard.PersistentKeepaliveSecs, "keepalive interval in seconds for peers facing nodes behind NAT. 0 disables keepalives.\nenv:WGMESH_PERSISTENT_KEEPALIVE")
+	c.fs.StringVar(&c.meshConfig.STUN.Servers, "stun-servers", c.meshConfig.STUN.Servers, "comma-separated list of STUN servers (host:port) to detect the external ip.\nenv:WGMESH_STUN_SERVERS")
+	c.fs.BoolVar(&c.meshConfig.STUN.IPv6, "stun-ipv6", c.meshConfig.STUN.IPv6, "also query STUN servers via ipv6.\nenv:WGMESH_STUN_IPV6")
+	c.fs.IntVar(&c.meshConfig.STUN.TimeoutMsec, "stun-timeout", c.meshConfig.STUN.TimeoutMsec, "milliseconds to wait for responses of STUN servers.\nenv:WGMESH_STUN_TIMEOUT_MSEC")
+	c.fs.BoolVar(&c.meshConfig.Wireguard.Relay, "relay", c.meshConfig.Wireguard.Relay, "relay traffic between peers which cannot reach each other directly.\nenv:WGMESH_RELAY")
+	c.fs.IntVar(&c.meshConfig.Wireguard.RelayTimeoutSecs, "relay-timeout", c.meshConfig.Wireguard.RelayTimeoutSecs, "seconds without a handshake after sending traffic to a peer, after which its traffic is sent through a relay node. 0 disables relaying.\nenv:WGMESH_RELAY_TIMEOUT")
+	c.fs.BoolVar(&c.meshConfig.Wireguard.ExitNode, "exit-node", c.meshConfig.Wireguard.ExitNode, "offer this node as exit node, routing default traffic of other nodes.\nenv:WGMESH_EXIT_NODE")
+	c.fs.IntVar(&c.meshConfig.Wireguard.EndpointCheckIntervalSecs, "endpoint-check-interval", c.meshConfig.Wireguard.EndpointCheckIntervalSecs, "seconds after which the external ip is detected again and announced if it changed. 0 disables checks.\nenv:WGMESH_ENDPOINT_CHECK_INTERVAL")
+	c.fs.IntVar(&c.meshConfig.Wireguard.ReconcileIntervalSecs, "reconcile-interval", c.meshConfig.Wireguard.ReconcileIntervalSecs, "seconds between comparisons of wireguard peers with mesh members, fixing any drift. 0 disables reconciliation.\nenv:WGMESH_RECONCILE_INTERVAL")
 	c.fs.StringVar(&c.meshConfig.Bootstrap.GRPCBindAddr, "grpc-bind-addr", c.meshConfig.Bootstrap.GRPCBindAddr, "(public) address to bind grpc mesh service to.\nenv:WGMESH_GRPC_BIND_ADDR")
 	c.fs.IntVar(&c.meshConfig.Bootstrap.GRPCBindPort, "grpc-bind-port", c.meshConfig.Bootstrap.GRPCBindPort, "port to bind grpc mesh service to.\nenv:WGMESH_GRPC_BIND_PORT")
 	c.fs.StringVar(&c.meshConfig.Bootstrap.GRPCTLSConfig.GRPCServerKey, "grpc-server-key", c.meshConfig.Bootstrap.GRPCTLSConfig.GRPCServerKey, "points to PEM-encoded private key to be used by grpc server.\nenv:WGMESH_SERVER_KEY")
@@ -61,9 +78,26 @@ func NewBootstrapCommand() *BootstrapCommand {
 	c.fs.StringVar(&c.meshConfig.Bootstrap.GRPCTLSConfig.GRPCCaCert, "grpc-ca-cert", c.meshConfig.Bootstrap.GRPCTLSConfig.GRPCCaCert, "points to PEM-encoded CA certificate.\nenv:WGMESH_CA_CERT")
 	c.fs.StringVar(&c.meshConfig.Bootstrap.GRPCTLSConfig.GRPCCaPath, "grpc-ca-path", c.meshConfig.Bootstrap.GRPCTLSConfig.GRPCCaPath, "points to a directory containing PEM-encoded CA certificates.\nenv:WGMESH_CA_PATH")
 	c.fs.StringVar(&c.meshConfig.MemberlistFile, "memberlist-file", c.meshConfig.MemberlistFile, "optional name of file for a log of all current mesh members.\nenv:WGMESH_MEMBERLIST_FILE")
+	c.fs.StringVar(&c.meshConfig.Group, "group", c.meshConfig.Group, "optional isolation group of this node. It only peers with nodes of groups allowed by the group policy.\nenv:WGMESH_GROUP")
+	c.fs.IntVar(&c.meshConfig.Serf.BindPort, "serf-bind-port", c.meshConfig.Serf.BindPort, "port serf binds to on the mesh ip. Joining nodes take it from the bootstrap node.\nenv:WGMESH_SERF_BIND_PORT")
+	c.fs.StringVar(&c.meshConfig.SerfSnapshot, "serf-snapshot", c.meshConfig.SerfSnapshot, "optional file where serf persists the cluster state, to recover membership after a crash. Requires -state-dir.\nenv:WGMESH_SERF_SNAPSHOT")
+	c.fs.StringVar(&c.meshConfig.StateDir, "state-dir", c.meshConfig.StateDir, "optional directory where state such as ipam leases is persisted.\nenv:WGMESH_STATE_DIR")
 	c.fs.StringVar(&c.meshConfig.Bootstrap.MeshEncryptionKey, "mesh-encryption-key", c.meshConfig.Bootstrap.MeshEncryptionKey, "optional key for symmetric encryption of internal mesh traffic. Must be 32 Bytes base64-ed.\nenv:WGMESH_ENCRYPTION_KEY")
 	c.fs.BoolVar(&c.devMode, "dev", c.devMode, "Enables development mode which runs without encryption, authentication and without TLS")
 	c.fs.BoolVar(&c.meshConfig.Bootstrap.SerfModeLAN, "serf-mode-lan", c.meshConfig.Bootstrap.SerfModeLAN, "Activates LAN mode or cluster communication. Default is false (=WAN mode).\nenv:WGMESH_SERF_MODE_LAN")
+	c.fs.BoolVar(&c.meshConfig.Bootstrap.RequireToken, "require-token", c.meshConfig.Bootstrap.RequireToken, "Joining nodes must present a pre-shared token (see wgmesh token).\nenv:WGMESH_REQUIRE_TOKEN")
+	c.fs.BoolVar(&c.meshConfig.Bootstrap.RequireApproval, "require-approval", c.meshConfig.Bootstrap.RequireApproval, "Join requests wait until approved by an operator (see wgmesh pending).\nenv:WGMESH_REQUIRE_APPROVAL")
+	c.fs.StringVar(&c.meshConfig.Bootstrap.PSKMode, "psk-mode", c.meshConfig.Bootstrap.PSKMode, "off, mesh or pair. Use the mesh secret as wireguard preshared key for all peers, or derive a preshared key for each pair of nodes.\nenv:WGMESH_PSK_MODE")
+	c.fs.StringVar(&c.meshConfig.Bootstrap.PSKSecret, "psk-secret", c.meshConfig.Bootstrap.PSKSecret, "optional mesh secret for preshared keys. Must be 32 Bytes base64-ed. Randomized if not given.\nenv:WGMESH_PSK_SECRET")
+	c.fs.StringVar(&c.meshConfig.Bootstrap.NodeNamePolicy, "node-name-policy", c.meshConfig.Bootstrap.NodeNamePolicy, "reject or auto-suffix. What to do when joining nodes request a node name already in use.\nenv:WGMESH_NODE_NAME_POLICY")
+	c.fs.StringVar(&c.meshConfig.Bootstrap.RoutePolicy, "route-policy", c.meshConfig.Bootstrap.RoutePolicy, "manual or auto. Whether routes advertised by nodes wait for approval or are approved if they do not overlap.\nenv:WGMESH_ROUTE_POLICY")
+	c.fs.StringVar(&c.meshConfig.Bootstrap.Auth.PresharedSecret, "auth-preshared-secret", c.meshConfig.Bootstrap.Auth.PresharedSecret, "Joining nodes must prove knowledge of this pre-shared secret.\nenv:WGMESH_AUTH_PRESHARED_SECRET")
+	c.fs.StringVar(&c.meshConfig.Bootstrap.Auth.TOTPSecret, "auth-totp-secret", c.meshConfig.Bootstrap.Auth.TOTPSecret, "Joining nodes must send a TOTP code for this base32-encoded secret.\nenv:WGMESH_AUTH_TOTP_SECRET")
+	c.fs.StringVar(&c.meshConfig.Bootstrap.Auth.AuthorizedKeysFile, "auth-authorized-keys", c.meshConfig.Bootstrap.Auth.AuthorizedKeysFile, "Joining nodes must sign a nonce with one of the ssh keys in this authorized_keys file.\nenv:WGMESH_AUTH_AUTHORIZED_KEYS")
+	c.fs.StringVar(&c.meshConfig.Bootstrap.GroupPolicy, "group-policy", c.meshConfig.Bootstrap.GroupPolicy, "optional policy which groups of nodes peer with each other, e.g. edge:core,core:*. Groups not in the policy peer with all nodes.\nenv:WGMESH_GROUP_POLICY")
+	c.fs.StringVar(&c.meshConfig.Bootstrap.JoinExisting, "join-existing", c.meshConfig.Bootstrap.JoinExisting, "IP:Port of another bootstrap node. Joins its mesh instead of creating a new one. Requires -cidr-ipam.\nenv:WGMESH_JOIN_EXISTING")
+	c.fs.StringVar(&c.meshConfig.Join.ClientKey, "client-key", c.meshConfig.Join.ClientKey, "points to PEM-encoded private key of the client certificate for -join-existing.\nenv:WGMESH_CLIENT_KEY")
+	c.fs.StringVar(&c.meshConfig.Join.ClientCert, "client-cert", c.meshConfig.Join.ClientCert, "points to PEM-encoded client certificate for -join-existing.\nenv:WGMESH_CLIENT_CERT")
 	c.fs.StringVar(&c.meshConfig.Agent.GRPCBindSocket, "agent-grpc-bind-socket", c.meshConfig.Agent.GRPCBindSocket, "local socket file to bind grpc agent to.\nenv:WGMESH_AGENT_BIND_SOCKET")
 	c.fs.StringVar(&c.meshConfig.Agent.GRPCBindSocketIDs, "agent-grpc-bind-socket-id", c.meshConfig.Agent.GRPCBindSocketIDs, "<uid:gid> to change bind socket to.\nenv:WGMESH_AGENT_BIND_SOCKET_ID")
 	c.DefaultFields(c.fs)
@@ -109,24 +143,86 @@ func (g *BootstrapCommand) Init(args []string) error {
 		return errors.New("mesh name (--name, -n, mesh-name) must have maximum length of 10")
 	}
 
-	_, _, err = net.ParseCIDR(g.meshConfig.Bootstrap.MeshCIDRRange)
+	_, cidrRange, err := net.ParseCIDR(g.meshConfig.Bootstrap.MeshCIDRRange)
 	if err != nil {
 		return fmt.Errorf("%s is not a valid cidr range for -cidr / bootstrap.mesh-cidr-range", g.meshConfig.Bootstrap.MeshCIDRRange)
 	}
 
-	if net.ParseIP(g.meshConfig.Bootstrap.NodeIP) == nil {
+	nodeIP := net.ParseIP(g.meshConfig.Bootstrap.NodeIP)
+	if nodeIP == nil {
 		return fmt.Errorf("%s is not a valid ip for -ip", g.meshConfig.Bootstrap.NodeIP)
 	}
 
 	// ip must be a local one
 	if pr, _ := isPrivateIP(g.meshConfig.Bootstrap.NodeIP); pr == false {
-		return fmt.Errorf("-ip %s is not RFC1918, must be a private address", g.meshConfig.Bootstrap.NodeIP)
+		return fmt.Errorf("-ip %s is not RFC1918 or ULA, must be a private address", g.meshConfig.Bootstrap.NodeIP)
+	}
+
+	if (nodeIP.To4() == nil) != (cidrRange.IP.To4() == nil) {
+		return fmt.Errorf("-ip %s and -cidr %s must be of the same address family", g.meshConfig.Bootstrap.NodeIP, g.meshConfig.Bootstrap.MeshCIDRRange)
+	}
+
+	// dual-stack needs an ipv6 range and ip
+	if g.meshConfig.Bootstrap.MeshCIDRRange6 != "" || g.meshConfig.Bootstrap.NodeIP6 != "" {
+		if cidrRange.IP.To4() == nil {
+			return errors.New("-cidr6 can only be combined with an IPv4 -cidr range")
+		}
+
+		_, cidrRange6, err := net.ParseCIDR(g.meshConfig.Bootstrap.MeshCIDRRange6)
+		if err != nil || cidrRange6.IP.To4() != nil {
+			return fmt.Errorf("%s is not a valid IPv6 cidr range for -cidr6 / bootstrap.mesh-cidr-range6", g.meshConfig.Bootstrap.MeshCIDRRange6)
+		}
+
+		nodeIP6 := net.ParseIP(g.meshConfig.Bootstrap.NodeIP6)
+		if nodeIP6 == nil || nodeIP6.To4() != nil || !cidrRange6.Contains(nodeIP6) {
+			return fmt.Errorf("%s is not a valid ip for -ip6, must be within -cidr6", g.meshConfig.Bootstrap.NodeIP6)
+		}
+		if pr, _ := isPrivateIP(g.meshConfig.Bootstrap.NodeIP6); pr == false {
+			return fmt.Errorf("-ip6 %s is not ULA, must be a private address", g.meshConfig.Bootstrap.NodeIP6)
+		}
 	}
 
 	if g.meshConfig.Wireguard.ListenPort < 0 || g.meshConfig.Wireguard.ListenPort > 65535 {
 		return fmt.Errorf("%d is not valid for -listen-port", g.meshConfig.Wireguard.ListenPort)
 	}
 
+	if g.meshConfig.Wireguard.NAT != "auto" && g.meshConfig.Wireguard.NAT != "on" && g.meshConfig.Wireguard.NAT != "off" {
+		return fmt.Errorf("%s is not valid for -nat, use auto, on or off", g.meshConfig.Wireguard.NAT)
+	}
+
+	if g.meshConfig.Wireguard.PersistentKeepaliveSecs < 0 || g.meshConfig.Wireguard.PersistentKeepaliveSecs > 65535 {
+		return fmt.Errorf("%d is not valid for -persistent-keepalive", g.meshConfig.Wireguard.PersistentKeepaliveSecs)
+	}
+
+	if err := validateSTUNConfig(g.meshConfig.STUN); err != nil {
+		return err
+	}
+	if err := validateSerfConfig(g.meshConfig.Serf); err != nil {
+		return err
+	}
+	if _, err := parseACLRules(g.meshConfig.ACL); err != nil {
+		return err
+	}
+	if g.meshConfig.Wireguard.EndpointCheckIntervalSecs < 0 {
+		return fmt.Errorf("%d is not valid for -endpoint-check-interval", g.meshConfig.Wireguard.EndpointCheckIntervalSecs)
+	}
+
+	if g.meshConfig.Wireguard.RelayTimeoutSecs < 0 {
+		return fmt.Errorf("%d is not valid for -relay-timeout", g.meshConfig.Wireguard.RelayTimeoutSecs)
+	}
+	if g.meshConfig.Wireguard.ReconcileIntervalSecs < 0 {
+		return fmt.Errorf("%d is not valid for -reconcile-interval", g.meshConfig.Wireguard.ReconcileIntervalSecs)
+	}
+	if g.meshConfig.SerfSnapshot != "" && g.meshConfig.StateDir == "" {
+		// cached peers only accept this node with its former key and mesh ip
+		return errors.New("-serf-snapshot requires -state-dir, so that the node keeps its wireguard key and mesh ip")
+	}
+	if g.meshConfig.Group != "" {
+		if err := meshservice.ValidateGroupName(g.meshConfig.Group); err != nil {
+			return fmt.Errorf("%s is not valid for -group", g.meshConfig.Group)
+		}
+	}
+
 	if net.ParseIP(g.meshConfig.Bootstrap.GRPCBindAddr) == nil {
 		return fmt.Errorf("%s is not a valid ip for -grpc-bind-addr", g.meshConfig.Bootstrap.GRPCBindAddr)
 	}
@@ -150,6 +246,21 @@ func (g *BootstrapCommand) Init(args []string) error {
 		}
 	}
 
+	switch g.meshConfig.Bootstrap.PSKMode {
+	case meshservice.PSKModeOff, meshservice.PSKModeMesh, meshservice.PSKModePair:
+	default:
+		return fmt.Errorf("%s is not valid for -psk-mode, use off, mesh or pair", g.meshConfig.Bootstrap.PSKMode)
+	}
+	if g.meshConfig.Bootstrap.PSKSecret != "" {
+		b, err := base64.StdEncoding.DecodeString(g.meshConfig.Bootstrap.PSKSecret)
+		if err != nil || len(b) != 32 {
+			return errors.New("-psk-secret is not valid, must be 32 bytes, base64-encoded")
+		}
+		if g.meshConfig.Bootstrap.PSKMode == meshservice.PSKModeOff {
+			return errors.New("-psk-secret requires -psk-mode mesh or pair")
+		}
+	}
+
 	withGrpcSecure := false
 	if g.meshConfig.Bootstrap.GRPCTLSConfig.GRPCServerKey != "" {
 		withGrpcSecure = true
@@ -208,6 +319,97 @@ func (g *BootstrapCommand) Init(args []string) error {
 		}
 	}
 
+	if g.meshConfig.Bootstrap.NodeNamePolicy != meshservice.NodeNamePolicyReject && g.meshConfig.Bootstrap.NodeNamePolicy != meshservice.NodeNamePolicyAutoSuffix {
+		return fmt.Errorf("%s is not valid for -node-name-policy, use reject or auto-suffix", g.meshConfig.Bootstrap.NodeNamePolicy)
+	}
+	if g.meshConfig.Bootstrap.RoutePolicy != meshservice.RoutePolicyManual && g.meshConfig.Bootstrap.RoutePolicy != meshservice.RoutePolicyAuto {
+		return fmt.Errorf("%s is not valid for -route-policy, use manual or auto", g.meshConfig.Bootstrap.RoutePolicy)
+	}
+	if _, err := meshservice.ParseGroupPolicy(g.meshConfig.Bootstrap.GroupPolicy); err != nil {
+		return fmt.Errorf("-group-policy is not valid: %s", err)
+	}
+
+	if g.meshConfig.Bootstrap.Auth.AuthorizedKeysFile != "" && !fileExists(g.meshConfig.Bootstrap.Auth.AuthorizedKeysFile) {
+		return fmt.Errorf("%s not found for -auth-authorized-keys", g.meshConfig.Bootstrap.Auth.AuthorizedKeysFile)
+	}
+
+	if g.meshConfig.Bootstrap.MeshIPAMCIDRRange != "" {
+		_, ipamRange, err := net.ParseCIDR(g.meshConfig.Bootstrap.MeshIPAMCIDRRange)
+		if err != nil {
+			return fmt.Errorf("%s is not a valid cidr range for -cidr-ipam / bootstrap.mesh-ipam-cidr-range", g.meshConfig.Bootstrap.MeshIPAMCIDRRange)
+		}
+		ones, _ := ipamRange.Mask.Size()
+		meshOnes, _ := cidrRange.Mask.Size()
+		if !cidrRange.Contains(ipamRange.IP) || ones < meshOnes {
+			return fmt.Errorf("-cidr-ipam %s must be within -cidr %s", g.meshConfig.Bootstrap.MeshIPAMCIDRRange, g.meshConfig.Bootstrap.MeshCIDRRange)
+		}
+	}
+
+	// the ipv6 ipam range is checked against -cidr6 here, or by the
+	// other bootstrap node when joining an existing mesh
+	if g.meshConfig.Bootstrap.MeshIPAMCIDRRange6 != "" {
+		_, ipamRange6, err := net.ParseCIDR(g.meshConfig.Bootstrap.MeshIPAMCIDRRange6)
+		if err != nil || ipamRange6.IP.To4() != nil {
+			return fmt.Errorf("%s is not a valid IPv6 cidr range for -cidr-ipam6 / bootstrap.mesh-ipam-cidr-range6", g.meshConfig.Bootstrap.MeshIPAMCIDRRange6)
+		}
+		if g.meshConfig.Bootstrap.JoinExisting == "" {
+			_, cidrRange6, err := net.ParseCIDR(g.meshConfig.Bootstrap.MeshCIDRRange6)
+			if err != nil {
+				return errors.New("-cidr-ipam6 requires -cidr6")
+			}
+			ones, _ := ipamRange6.Mask.Size()
+			meshOnes, _ := cidrRange6.Mask.Size()
+			if !cidrRange6.Contains(ipamRange6.IP) || ones < meshOnes {
+				return fmt.Errorf("-cidr-ipam6 %s must be within -cidr6 %s", g.meshConfig.Bootstrap.MeshIPAMCIDRRange6, g.meshConfig.Bootstrap.MeshCIDRRange6)
+			}
+		}
+	}
+
+	// a bootstrap node joining an existing mesh takes most
+	// settings from there, but needs an ipam range of its own
+	if g.meshConfig.Bootstrap.JoinExisting != "" {
+		arr := strings.Split(g.meshConfig.Bootstrap.JoinExisting, ":")
+		if len(arr) != 2 || net.ParseIP(arr[0]) == nil {
+			return errors.New("-join-existing must be <IP>:<port>")
+		}
+		if _, err = strconv.Atoi(arr[1]); err != nil {
+			return fmt.Errorf("%s is not a valid port for -join-existing", arr[1])
+		}
+		if g.meshConfig.Bootstrap.MeshIPAMCIDRRange == "" {
+			return errors.New("-join-existing requires an ipam range of this node, use -cidr-ipam")
+		}
+		if g.meshConfig.Bootstrap.MeshEncryptionKey != "" {
+			return errors.New("cannot combine -mesh-encryption-key with -join-existing, the key is taken from the existing mesh")
+		}
+		if g.meshConfig.Bootstrap.PSKMode != meshservice.PSKModeOff {
+			return errors.New("cannot combine -psk-mode with -join-existing, preshared keys are taken from the existing mesh")
+		}
+		if g.meshConfig.Bootstrap.GroupPolicy != "" {
+			return errors.New("cannot combine -group-policy with -join-existing, the group policy is taken from the existing mesh")
+		}
+		if g.meshConfig.Bootstrap.MeshCIDRRange6 != "" {
+			return errors.New("cannot combine -cidr6 with -join-existing, the ipv6 range is taken from the existing mesh")
+		}
+		if g.meshConfig.MeshName == "" {
+			return errors.New("mesh name (--name, -n) may not be empty with -join-existing")
+		}
+
+		// the server certificate is not used as client certificate
+		if !g.devMode {
+			if g.meshConfig.Join.ClientKey == "" || g.meshConfig.Join.ClientCert == "" {
+				return errors.New("-join-existing requires a client certificate, use -client-key and -client-cert")
+			}
+			if !fileExists(g.meshConfig.Join.ClientKey) {
+				return fmt.Errorf("%s not found for -client-key", g.meshConfig.Join.ClientKey)
+			}
+			if !fileExists(g.meshConfig.Join.ClientCert) {
+				return fmt.Errorf("%s not found for -client-cert", g.meshConfig.Join.ClientCert)
+			}
+		}
+	} else if g.meshConfig.Join.ClientKey != "" || g.meshConfig.Join.ClientCert != "" {
+		return errors.New("-client-key and -client-cert are only used with -join-existing")
+	}
+
 	return nil
 }
 
@@ -231,33 +433,54 @@ func (g *BootstrapCommand) Run() error {
 		"created",
 	)
 	ms.SetMemberlistExportFile(cfg.MemberlistFile)
+	ms.SetSerfSnapshotPath(cfg.SerfSnapshot)
+
+	// persist ipam leases and pre-shared tokens if we have a state directory
+	if cfg.StateDir != "" {
+		if err := os.MkdirAll(cfg.StateDir, 0700); err != nil {
+			return err
+		}
+		if err := ms.SetLeaseFile(filepath.Join(cfg.StateDir, "leases.json")); err != nil {
+			log.WithError(err).Error("Unable to read ipam leases")
+			return fmt.Errorf("Unable to read ipam leases from %s", cfg.StateDir)
+		}
+		if err := ms.SetTokenFile(filepath.Join(cfg.StateDir, "tokens.json")); err != nil {
+			log.WithError(err).Error("Unable to read pre-shared tokens")
+			return fmt.Errorf("Unable to read pre-shared tokens from %s", cfg.StateDir)
+		}
+	}
 
 	// Set serf encryption key when given and we're not in dev mode
 	if !g.devMode && cfg.Bootstrap.MeshEncryptionKey != "" {
 		ms.SetEncryptionKey(cfg.Bootstrap.MeshEncryptionKey)
 	}
 
-	var wgListenAddr net.IP
-	if cfg.Wireguard.ListenAddr == "" {
-
-		st := meshservice.NewSTUNService()
-		ips, err := st.GetExternalIP()
+	// preshared keys are set up by the first bootstrap node, others
+	// take them from the existing mesh
+	if err := ms.SetPresharedKeys(cfg.Bootstrap.PSKMode, cfg.Bootstrap.PSKSecret); err != nil {
+		return err
+	}
+	if err := ms.SetGroupPolicy(cfg.Bootstrap.GroupPolicy); err != nil {
+		return err
+	}
 
-		if err != nil {
-			return err
-		}
-		if len(ips) > 0 {
-			wgListenAddr = ips[0]
-			log.WithField("ip", wgListenAddr).Info("Using external IP when connecting with mesh")
-		}
+	st := newSTUNService(cfg.STUN)
+	wgListenAddr, stunResult, err := detectListenIP(cfg.Wireguard.ListenAddr, &st)
+	if err != nil {
+		return err
 	}
-	if wgListenAddr == nil {
-		wgListenAddr = getIPFromIPOrIntfParam(cfg.Wireguard.ListenAddr)
-		log.WithField("ip", wgListenAddr).Trace("parsed -listen-addr")
-		if wgListenAddr == nil {
-			return errors.New("need -listen-addr")
-		}
+	if stunResult != nil {
+		ms.SetSTUNResult(stunResult)
+	}
+	log.WithField("ip", wgListenAddr).Info("Using external IP when connecting with mesh")
+	ms.BehindNAT = behindNAT(cfg.Wireguard.NAT, wgListenAddr)
+	ms.Relay = cfg.Wireguard.Relay
+	ms.IsExitNode = cfg.Wireguard.ExitNode
+	ms.Group = cfg.Group
+	if ms.Relay && ms.BehindNAT {
+		log.Warn("This node is a relay but behind NAT, other nodes behind NAT may not be able to reach it")
 	}
+	ms.PersistentKeepalive = time.Duration(cfg.Wireguard.PersistentKeepaliveSecs) * time.Second
 	// TODO make sure wgListenAddr matches one of the local interfaces addresses
 
 	_, cidrRangeIpnet, err := net.ParseCIDR(cfg.Bootstrap.MeshCIDRRange)
@@ -272,12 +495,30 @@ func (g *BootstrapCommand) Run() error {
 			return err
 		}
 
-		// TODO check if this is within cidr range above..
-
 		ms.CIDRRangeIPAM = cidrRangeIPAMIpnet
 
 	}
 
+	if cfg.Bootstrap.MeshIPAMCIDRRange6 != "" {
+		_, cidrRangeIPAM6Ipnet, err := net.ParseCIDR(cfg.Bootstrap.MeshIPAMCIDRRange6)
+		if err != nil {
+			return err
+		}
+		ms.CIDRRangeIPAM6 = cidrRangeIPAM6Ipnet
+	}
+
+	if cfg.Bootstrap.MeshCIDRRange6 != "" {
+		_, cidrRange6Ipnet, err := net.ParseCIDR(cfg.Bootstrap.MeshCIDRRange6)
+		if err != nil {
+			return err
+		}
+		ms.CIDRRange6 = cidrRange6Ipnet
+		ms.MeshIP6 = net.IPNet{
+			IP:   net.ParseIP(cfg.Bootstrap.NodeIP6),
+			Mask: cidrRange6Ipnet.Mask,
+		}
+	}
+
 	// MeshIP ist composed of what user specifies using -ip, but
 	// with the net mask of -cidr. e.g. 10.232.0.0/16 with an
 	// IP of 10.232.5.99 becomes 10.232.5.99/16
@@ -287,8 +528,15 @@ func (g *BootstrapCommand) Run() error {
 	}
 	log.WithField("meship", ms.MeshIP).Trace("using mesh ip")
 
-	// - prepare wireguard interface
-	pk, err := g.wireguardSetup(&ms, wgListenAddr)
+	// - prepare wireguard interface. When joining an existing mesh,
+	// it is configured from the join response of the other bootstrap node
+	var joinResponse *meshservice.JoinResponse
+	var meshPeerIPs []string
+	if cfg.Bootstrap.JoinExisting != "" {
+		joinResponse, meshPeerIPs, err = g.joinExistingMesh(&ms, wgListenAddr)
+	} else {
+		_, err = g.wireguardSetup(&ms, wgListenAddr)
+	}
 	if err != nil {
 		err2 := ms.RemoveWireguardInterfaceForMesh()
 		if err2 != nil {
@@ -302,11 +550,75 @@ func (g *BootstrapCommand) Run() error {
 		ms.RemoveWireguardInterfaceForMesh()
 	}()
 
+	// a restarted bootstrap node sets up peerings from the peers cached
+	// next to the serf snapshot, so that serf is able to rejoin them
+	if joinResponse == nil {
+		cachedPeers, err := ms.CachedPeers()
+		if err != nil {
+			log.WithError(err).Warn("Unable to read cached peers")
+		}
+		if len(cachedPeers) > 0 {
+			meshPeerIPs = ms.AddKnownPeers(wgwrapper.New(), cachedPeers)
+			log.WithField("n", len(meshPeerIPs)).Info("Added cached peers")
+		}
+	}
+
 	// set up serf
-	err = g.serfSetup(&ms, pk, wgListenAddr)
+	err = g.serfSetup(&ms, wgListenAddr, joinResponse, meshPeerIPs)
+	if err != nil {
+		return err
+	}
+
+	// keep the persisted identity up to date
+	if cfg.StateDir != "" {
+		ms.SetIdentityFile(g.identityFile())
+		ms.SaveIdentity()
+	}
+
+	// roaming nodes re-detect their external ip and announce changes
+	if cfg.Wireguard.EndpointCheckIntervalSecs > 0 {
+		ms.StartEndpointUpdater(time.Duration(cfg.Wireguard.EndpointCheckIntervalSecs)*time.Second, func() (net.IP, bool, error) {
+			ip, r, err := detectListenIP(cfg.Wireguard.ListenAddr, &st)
+			if r != nil {
+				ms.SetSTUNResult(r)
+			}
+			if err != nil {
+				return nil, false, err
+			}
+			return ip, behindNAT(cfg.Wireguard.NAT, ip), nil
+		})
+	}
+
+	// fix drift between wireguard peers and mesh members
+	if cfg.Wireguard.ReconcileIntervalSecs > 0 {
+		ms.StartPeerReconciler(time.Duration(cfg.Wireguard.ReconcileIntervalSecs) * time.Second)
+	}
+
+	// relay nodes forward traffic between peers, all others route
+	// traffic to peers they cannot reach directly through a relay
+	if cfg.Wireguard.Relay {
+		if err := ms.EnableForwarding(); err != nil {
+			return err
+		}
+	} else if cfg.Wireguard.RelayTimeoutSecs > 0 {
+		ms.StartRelayMonitor(time.Duration(cfg.Wireguard.RelayTimeoutSecs) * time.Second)
+	}
+
+	if cfg.Wireguard.ExitNode {
+		if err := ms.OfferExitNode(); err != nil {
+			return err
+		}
+	}
+
+	// enforce network policy, it is recompiled when members or tags change
+	aclRules, err := parseACLRules(cfg.ACL)
 	if err != nil {
 		return err
 	}
+	ms.SetACLRules(aclRules)
+	if err = ms.ApplyACL(); err != nil {
+		return err
+	}
 
 	// set up external gRPC interface, be able to listen
 	// for join requests
@@ -314,19 +626,35 @@ func (g *BootstrapCommand) Run() error {
 		return err
 	}
 
-	// we created this mesh now
-	ms.SetTimestamps(time.Now().Unix(), time.Now().Unix())
+	// we created this mesh now, unless we joined an existing one
+	if joinResponse == nil {
+		ms.SetTimestamps(time.Now().Unix(), time.Now().Unix())
+	}
 
 	// print out user information on how to connect to this mesh
 
 	fmt.Printf("** \n")
-	fmt.Printf("** Mesh '%s' has been bootstrapped. Other nodes can join now.\n", cfg.MeshName)
+	if joinResponse == nil {
+		fmt.Printf("** Mesh '%s' has been bootstrapped. Other nodes can join now.\n", cfg.MeshName)
+	} else {
+		fmt.Printf("** Mesh '%s' has been joined as bootstrap node. Other nodes can join now.\n", cfg.MeshName)
+	}
 	fmt.Printf("** \n")
 	fmt.Printf("** Mesh name:                       %s\n", cfg.MeshName)
 	fmt.Printf("** Mesh CIDR range:                 %s\n", ms.CIDRRange.String())
+	if ms.CIDRRangeIPAM != nil {
+		fmt.Printf("** IPAM range of this node:         %s\n", ms.CIDRRangeIPAM.String())
+	}
 	fmt.Printf("** gRPC Service listener endpoint:  %s:%d\n", ms.GrpcBindAddr, ms.GrpcBindPort)
 	fmt.Printf("** This node's name:                %s\n", ms.NodeName)
 	fmt.Printf("** This node's mesh IP:             %s\n", ms.MeshIP.IP.String())
+	if ms.MeshIP6.IP != nil {
+		fmt.Printf("** Mesh IPv6 CIDR range:            %s\n", ms.CIDRRange6.String())
+		if ms.CIDRRangeIPAM6 != nil {
+			fmt.Printf("** IPv6 IPAM range of this node:    %s\n", ms.CIDRRangeIPAM6.String())
+		}
+		fmt.Printf("** This node's mesh IPv6:           %s\n", ms.MeshIP6.IP.String())
+	}
 	if cfg.MemberlistFile != "" {
 		fmt.Printf("** Mesh node details export to:     %s\n", cfg.MemberlistFile)
 	}
@@ -342,6 +670,14 @@ func (g *BootstrapCommand) Run() error {
 		}
 		fmt.Printf("** wgmesh join -v -dev -n %s -bootstrap-addr %s:%d\n", cfg.MeshName, ba, ms.GrpcBindPort)
 		fmt.Printf("** \n")
+		if ms.RequireToken {
+			fmt.Printf("** Joining nodes need a token, use: wgmesh token create\n")
+			fmt.Printf("** \n")
+		}
+		if ms.RequireApproval {
+			fmt.Printf("** Join requests need to be approved, use: wgmesh pending list\n")
+			fmt.Printf("** \n")
+		}
 	} else {
 		if ms.TLSConfig != nil && len(ms.TLSConfig.Cert.Certificate) > 0 {
 			fmt.Printf("** TLS is enabled for gRPC mesh service\n")
@@ -383,9 +719,21 @@ func (g *BootstrapCommand) wireguardSetup(ms *meshservice.MeshService, wgListenA
 		return "", err
 	}
 	ms.WireguardPubKey = pk
+
+	if err = g.restoreWireguardKey(ms); err != nil {
+		return "", err
+	}
+	pk = ms.WireguardPubKey
 	ms.WireguardListenPort = cfg.Wireguard.ListenPort
 	ms.WireguardListenIP = wgListenAddr
 
+	// dual-stack meshes carry an additional ipv6 address
+	if ms.MeshIP6.IP != nil {
+		if err = ms.AssignMeshIP6(ms.MeshIP6.IP.String()); err != nil {
+			return "", err
+		}
+	}
+
 	// add a route so that all traffic regarding fiven cidr range
 	// goes to the wireguard interface.
 	err = ms.SetRoute()
@@ -398,28 +746,138 @@ func (g *BootstrapCommand) wireguardSetup(ms *meshservice.MeshService, wgListenA
 	return pk, nil
 }
 
-// serfSetup initializes the serf cluster from parameters
-func (g *BootstrapCommand) serfSetup(ms *meshservice.MeshService, pk string, wgListenAddr net.IP) (err error) {
+// serfSetup initializes the serf cluster from parameters. If this node
+// joined an existing mesh, serf settings are taken from the join response
+// and the cluster is joined via given peers.
+func (g *BootstrapCommand) serfSetup(ms *meshservice.MeshService, wgListenAddr net.IP, joinResponse *meshservice.JoinResponse, meshPeerIPs []string) (err error) {
 	cfg := g.meshConfig
 
+	settings := newSerfSettings(cfg.Serf, cfg.Bootstrap.SerfModeLAN, 0)
+	var tags map[string]string
+	if joinResponse != nil {
+		settings = newSerfSettings(cfg.Serf, joinResponse.SerfModeLAN, int(joinResponse.SerfBindPort))
+		tags = joinResponse.Tags
+	}
+
 	// create and start the serf cluster
-	ms.NewSerfCluster(cfg.Bootstrap.SerfModeLAN)
+	ms.NewSerfCluster(settings)
 
 	err = ms.StartSerfCluster(
 		true,
-		pk,
+		ms.WireguardPubKey,
 		wgListenAddr.String(),
 		cfg.Wireguard.ListenPort,
-		ms.MeshIP.IP.String())
+		ms.MeshIP.IP.String(),
+		tags)
 	if err != nil {
 		return err
 	}
 
 	ms.StartStatsUpdater()
 
+	if len(meshPeerIPs) > 0 {
+		ms.JoinSerfCluster(meshPeerIPs)
+	}
+
+	return nil
+}
+
+// identityFile returns the path of the persisted node identity
+func (g *BootstrapCommand) identityFile() string {
+	return filepath.Join(g.meshConfig.StateDir, "identity.json")
+}
+
+// restoreWireguardKey applies the wireguard key persisted in the state
+// directory, so that peers still knowing this node accept its traffic
+// after a restart. A rotated preshared key secret is restored as well.
+func (g *BootstrapCommand) restoreWireguardKey(ms *meshservice.MeshService) error {
+	if g.meshConfig.StateDir == "" {
+		return nil
+	}
+
+	identity, err := meshservice.LoadNodeIdentity(g.identityFile())
+	if err != nil {
+		log.WithError(err).Warn("Unable to read node identity")
+		return nil
+	}
+	if identity == nil || identity.MeshName != ms.MeshName {
+		return nil
+	}
+	if g.meshConfig.Bootstrap.JoinExisting == "" && identity.SerfBindPort > 0 && identity.SerfBindPort != g.meshConfig.Serf.BindPort {
+		log.WithFields(log.Fields{
+			"before": identity.SerfBindPort,
+			"now":    g.meshConfig.Serf.BindPort,
+		}).Warn("Serf bind port differs from the one used before, other nodes of the mesh may not be reachable. Set -serf-bind-port to keep it")
+	}
+	if err = ms.RestoreWireguardKey(identity); err != nil {
+		return err
+	}
+	log.Info("Restored persisted wireguard key")
+
+	// the mesh secret for preshared keys may have been rotated
+	if identity.PSKMode != "" && identity.PSKMode == g.meshConfig.Bootstrap.PSKMode {
+		if err = ms.SetPresharedKeys(identity.PSKMode, identity.PSKSecret); err != nil {
+			return err
+		}
+		ms.SetPresharedKeyEpoch(identity.PSKEpoch)
+	}
+
 	return nil
 }
 
+// joinExistingMesh joins the mesh of another bootstrap node. This node
+// presents its ipam range, which the other node checks for overlaps.
+// The mesh ip is requested from -ip if it lies within this range,
+// otherwise it is assigned by the other bootstrap node.
+func (g *BootstrapCommand) joinExistingMesh(ms *meshservice.MeshService, wgListenAddr net.IP) (*meshservice.JoinResponse, []string, error) {
+	cfg := g.meshConfig
+
+	pk, err := ms.CreateWireguardInterface(cfg.Wireguard.ListenPort)
+	if err != nil {
+		return nil, nil, err
+	}
+	ms.WireguardPubKey = pk
+	ms.WireguardListenPort = cfg.Wireguard.ListenPort
+	ms.WireguardListenIP = wgListenAddr
+
+	if err = g.restoreWireguardKey(ms); err != nil {
+		return nil, nil, err
+	}
+
+	// a client certificate authenticates us at the other bootstrap
+	// node, whose server certificate is verified with our CA
+	if !g.devMode {
+		ms.TLSConfig, err = meshservice.NewTLSConfigFromFiles(
+			cfg.Bootstrap.GRPCTLSConfig.GRPCCaCert,
+			cfg.Bootstrap.GRPCTLSConfig.GRPCCaPath,
+			cfg.Join.ClientCert,
+			cfg.Join.ClientKey)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	if ms.CIDRRangeIPAM.Contains(net.ParseIP(cfg.Bootstrap.NodeIP)) {
+		cfg.Join.RequestedIP = cfg.Bootstrap.NodeIP
+	}
+
+	// the ipam ranges are sent along, the other bootstrap node checks
+	// that they lie within the mesh cidr ranges before accepting the join
+	ipamRange6 := ""
+	if ms.CIDRRangeIPAM6 != nil {
+		ipamRange6 = ms.CIDRRangeIPAM6.String()
+	}
+	joinResponse, meshPeerIPs, err := joinMesh(ms, cfg, cfg.Bootstrap.JoinExisting, g.devMode, ms.CIDRRangeIPAM.String(), ipamRange6)
+	if err != nil {
+		return nil, nil, err
+	}
+	if joinResponse.Result == meshservice.JoinResponse_ERROR {
+		return nil, nil, fmt.Errorf("Unable to join mesh, message: '%s'", joinResponse.ErrorMessage)
+	}
+
+	return joinResponse, meshPeerIPs, nil
+}
+
 // GrpcSetup ...
 func (g *BootstrapCommand) grpcSetup(ms *meshservice.MeshService) (err error) {
 	cfg := g.meshConfig
@@ -439,6 +897,14 @@ func (g *BootstrapCommand) grpcSetup(ms *meshservice.MeshService) (err error) {
 	// set up grpc mesh service
 	ms.GrpcBindAddr = cfg.Bootstrap.GRPCBindAddr
 	ms.GrpcBindPort = cfg.Bootstrap.GRPCBindPort
+	ms.RequireToken = cfg.Bootstrap.RequireToken
+	ms.RequireApproval = cfg.Bootstrap.RequireApproval
+	ms.NodeNamePolicy = cfg.Bootstrap.NodeNamePolicy
+	ms.RoutePolicy = cfg.Bootstrap.RoutePolicy
+	ms.Authenticators, err = g.authenticators()
+	if err != nil {
+		return err
+	}
 
 	go func() {
 		log.Infof("Starting gRPC mesh Service at %s:%d", ms.GrpcBindAddr, ms.GrpcBindPort)
@@ -464,6 +930,32 @@ func (g *BootstrapCommand) grpcSetup(ms *meshservice.MeshService) (err error) {
 	return nil
 }
 
+// authenticators creates the auth requirements for joining nodes from parameters
+func (g *BootstrapCommand) authenticators() ([]meshservice.Authenticator, error) {
+	cfg := g.meshConfig.Bootstrap.Auth
+
+	res := make([]meshservice.Authenticator, 0)
+	if cfg.PresharedSecret != "" {
+		res = append(res, meshservice.NewHMACAuthenticator(cfg.PresharedSecret))
+	}
+	if cfg.TOTPSecret != "" {
+		a, err := meshservice.NewTOTPAuthenticator(cfg.TOTPSecret)
+		if err != nil {
+			return nil, fmt.Errorf("%s for -auth-totp-secret", err)
+		}
+		res = append(res, a)
+	}
+	if cfg.AuthorizedKeysFile != "" {
+		a, err := meshservice.NewSSHKeyAuthenticator(cfg.AuthorizedKeysFile)
+		if err != nil {
+			return nil, fmt.Errorf("%s for -auth-authorized-keys", err)
+		}
+		res = append(res, a)
+	}
+
+	return res, nil
+}
+
 // waits until being stopped
 func (g *BootstrapCommand) wait() {
 	stopCh := make(chan struct{})
@@ -487,6 +979,15 @@ func (g *BootstrapCommand) cleanUp(ms *meshservice.MeshService) error {
 
 	ms.MeshAgentServer.StopAgentGrpcService()
 
+	// persist the latest known peers before leaving
+	ms.SaveIdentity()
+
+	// ip rules, firewall rules of exit nodes and acls are not
+	// removed together with the wireguard interface
+	ms.StopUsingExitNode()
+	ms.StopOfferingExitNode()
+	ms.RemoveACL()
+
 	ms.LeaveSerfCluster()
 
 	ms.StopGrpcService()
@@ -30,6 +30,13 @@ var cmds = []Runner{
 	NewRTTCommand(),
 	NewInfoCommand(),
 	NewUICommand(),
+	NewTokenCommand(),
+	NewPendingCommand(),
+	NewIpamCommand(),
+	NewKeysCommand(),
+	NewRotateKeyCommand(),
+	NewRoutesCommand(),
+	NewExitNodeCommand(),
 }
 
 // ProcessCommands takes the command line arguments and
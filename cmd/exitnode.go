@@ -0,0 +1,156 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"strings"
+	"time"
+
+	config "github.com/aschmidt75/wgmesh/config"
+	meshservice "github.com/aschmidt75/wgmesh/meshservice"
+	log "github.com/sirupsen/logrus"
+	"google.golang.org/grpc"
+)
+
+// ExitNodeCommand struct
+type ExitNodeCommand struct {
+	CommandDefaults
+
+	fs *flag.FlagSet
+
+	// configuration file
+	config string
+	// configuration struct
+	meshConfig config.Config
+
+	// options not in config, only from parameters
+	action   string
+	nodeName string
+}
+
+// NewExitNodeCommand creates the ExitNode Command
+func NewExitNodeCommand() *ExitNodeCommand {
+	c := &ExitNodeCommand{
+		CommandDefaults: NewCommandDefaults(),
+		config:          envStrWithDefault("WGMESH_CONFIG", ""),
+		meshConfig:      config.NewDefaultConfig(),
+		fs:              flag.NewFlagSet("exit-node", flag.ContinueOnError),
+	}
+
+	c.fs.StringVar(&c.config, "config", c.config, "file name of config file (optional).\nenv:WGMESH_cONFIG")
+	c.fs.StringVar(&c.meshConfig.Agent.GRPCSocket, "agent-grpc-socket", c.meshConfig.Agent.GRPCSocket, "agent socket to dial")
+
+	c.DefaultFields(c.fs)
+
+	return c
+}
+
+// Name returns the name of the command
+func (g *ExitNodeCommand) Name() string {
+	return g.fs.Name()
+}
+
+// Init sets up the command struct from arguments
+func (g *ExitNodeCommand) Init(args []string) error {
+	g.action = "list"
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		g.action = args[0]
+		args = args[1:]
+	}
+	if g.action == "use" {
+		if len(args) < 1 || strings.HasPrefix(args[0], "-") {
+			return errors.New("Use exit-node use <name>")
+		}
+		g.nodeName = args[0]
+		args = args[1:]
+	}
+
+	err := g.fs.Parse(args)
+	if err != nil {
+		return err
+	}
+	g.ProcessDefaults()
+
+	// load config file if we have one
+	if g.config != "" {
+		err = g.meshConfig.LoadConfigFromFile(g.config)
+		if err != nil {
+			log.WithError(err).Error("Config read error")
+			return fmt.Errorf("Unable to read configuration from %s", g.config)
+		}
+	}
+
+	err = g.fs.Parse(args)
+	if err != nil {
+		return err
+	}
+	log.WithField("cfg", g.meshConfig).Trace("Read")
+	log.WithField("cfg.agent", g.meshConfig.Agent).Trace("Read")
+
+	switch g.action {
+	case "list", "use", "stop":
+	default:
+		return fmt.Errorf("Unknown exit-node command: %s. Use exit-node list|use|stop", g.action)
+	}
+
+	return nil
+}
+
+// Run queries the agent to list, use or stop using exit nodes
+func (g *ExitNodeCommand) Run() error {
+	log.WithField("g", g).Trace(
+		"Running cli command",
+	)
+
+	//
+	endpoint := fmt.Sprintf("unix://%s", g.meshConfig.Agent.GRPCSocket)
+
+	conn, err := grpc.Dial(endpoint, grpc.WithInsecure(), grpc.WithBlock())
+	if err != nil {
+		log.Error(err)
+		return fmt.Errorf("cannot connect to %s", endpoint)
+	}
+	defer conn.Close()
+
+	agent := meshservice.NewAgentClient(conn)
+	log.WithField("agent", agent).Trace("got grpc service client")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var r *meshservice.ExitNodeResult
+	switch g.action {
+	case "list":
+		r, err = agent.ExitNodes(ctx, &meshservice.AgentEmpty{})
+	case "use":
+		r, err = agent.UseExitNode(ctx, &meshservice.ExitNodeRequest{
+			NodeName: g.nodeName,
+		})
+	case "stop":
+		r, err = agent.StopExitNode(ctx, &meshservice.AgentEmpty{})
+	}
+	if err != nil {
+		log.Error(err)
+		return errors.New("unable to query exit nodes from agent")
+	}
+	log.WithField("r", r).Trace("got exitNodeResult")
+
+	if !r.Ok {
+		return fmt.Errorf("unable to %s exit node: %s", g.action, r.ErrorMessage)
+	}
+
+	for _, name := range r.ExitNodes {
+		if name == r.Current {
+			fmt.Printf("%s (in use)\n", name)
+		} else {
+			fmt.Println(name)
+		}
+	}
+	if r.Current == "" {
+		fmt.Println("No exit node in use")
+	}
+
+	return nil
+}
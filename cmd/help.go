@@ -14,5 +14,12 @@ func DisplayHelp(vi VersionInfo) {
 	fmt.Println("  tags         Set or remove tags on nodes")
 	fmt.Println("  rtt          Query RTTs for all nodes")
 	fmt.Println("  ui           Starts the web user interface")
+	fmt.Println("  token        Create, list or revoke pre-shared join tokens")
+	fmt.Println("  pending      List, approve or reject join requests waiting for approval")
+	fmt.Println("  ipam         List, reserve or release mesh ip leases")
+	fmt.Println("  keys         Install, use, remove or list serf encryption keys")
+	fmt.Println("  rotate-key   Replace the wireguard key of this node")
+	fmt.Println("  routes       Advertise, withdraw, approve or list subnet routes")
+	fmt.Println("  exit-node    Route default traffic through an exit node")
 	fmt.Println()
 }
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"strings"
 	"text/tabwriter"
 	"time"
 
@@ -156,6 +157,38 @@ func (g *InfoCommand) singleCycle(ctx context.Context, agent meshservice.AgentCl
 
 	fmt.Printf("Mesh '%s' has %d nodes, started %s\n", meshInfo.Name, meshInfo.NodeCount, time.Unix(int64(meshInfo.MeshCeationTS), 0))
 	fmt.Printf("This node '%s' joined %s\n", meshInfo.NodeName, time.Unix(int64(meshInfo.NodeJoinTS), 0))
+	if rs := meshInfo.Reconcile; rs != nil && rs.Runs > 0 {
+		fmt.Printf("Peer reconciliation: %d runs, %d added, %d removed, %d allowed ips fixed, %d endpoints fixed, %d preshared keys fixed, %d keepalives fixed, %d errors\n",
+			rs.Runs, rs.Added, rs.Removed, rs.AllowedIPsFixed, rs.EndpointsFixed, rs.PskFixed, rs.KeepaliveFixed, rs.Errors)
+		if rs.LastDriftTS > 0 {
+			fmt.Printf("Last drift fixed %s\n", time.Unix(rs.LastDriftTS, 0))
+		}
+	}
+	if meshInfo.Group != "" {
+		fmt.Printf("This node is in group '%s'\n", meshInfo.Group)
+	}
+	if meshInfo.GroupPolicy != "" {
+		fmt.Printf("Group policy: %s\n", meshInfo.GroupPolicy)
+	}
+	if meshInfo.ExitNode != "" {
+		fmt.Printf("Default traffic is routed through exit node %s\n", meshInfo.ExitNode)
+	}
+	for _, rp := range meshInfo.Relayed {
+		fmt.Printf("Peer %s is relayed via %s since %s\n", rp.NodeName, rp.RelayNodeName, time.Unix(rp.SinceTS, 0))
+	}
+	if st := meshInfo.Stun; st != nil {
+		fmt.Printf("External ip(s) by STUN: %s, queried %s\n", strings.Join(st.ExternalIPs, ", "), time.Unix(st.QueriedTS, 0))
+		if st.SymmetricNAT {
+			fmt.Println("STUN servers reported different mapped addresses, this node seems to be behind a symmetric NAT")
+		}
+		for _, si := range st.Servers {
+			if si.Error != "" {
+				fmt.Printf("  %s (%s): %s\n", si.Server, si.Network, si.Error)
+			} else {
+				fmt.Printf("  %s (%s): %s\n", si.Server, si.Network, si.MappedAddr)
+			}
+		}
+	}
 
 	r, err := agent.Nodes(ctx, &meshservice.AgentEmpty{})
 	if err != nil {
@@ -0,0 +1,219 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	config "github.com/aschmidt75/wgmesh/config"
+	meshservice "github.com/aschmidt75/wgmesh/meshservice"
+	log "github.com/sirupsen/logrus"
+	"google.golang.org/grpc"
+)
+
+// IpamCommand struct
+type IpamCommand struct {
+	CommandDefaults
+
+	fs *flag.FlagSet
+
+	// configuration file
+	config string
+	// configuration struct
+	meshConfig config.Config
+
+	// options not in config, only from parameters
+	action   string
+	meshIP   string
+	pubkey   string
+	nodeName string
+}
+
+// NewIpamCommand creates the Ipam Command
+func NewIpamCommand() *IpamCommand {
+	c := &IpamCommand{
+		CommandDefaults: NewCommandDefaults(),
+		config:          envStrWithDefault("WGMESH_CONFIG", ""),
+		meshConfig:      config.NewDefaultConfig(),
+		fs:              flag.NewFlagSet("ipam", flag.ContinueOnError),
+	}
+
+	c.fs.StringVar(&c.config, "config", c.config, "file name of config file (optional).\nenv:WGMESH_cONFIG")
+	c.fs.StringVar(&c.meshConfig.Agent.GRPCSocket, "agent-grpc-socket", c.meshConfig.Agent.GRPCSocket, "agent socket to dial")
+	c.fs.StringVar(&c.meshIP, "ip", c.meshIP, "(reserve, release) mesh ip of lease")
+	c.fs.StringVar(&c.pubkey, "pubkey", c.pubkey, "(reserve) wireguard public key of node to reserve ip for")
+	c.fs.StringVar(&c.nodeName, "node-name", c.nodeName, "(reserve) name of node to reserve ip for")
+
+	c.DefaultFields(c.fs)
+
+	return c
+}
+
+// Name returns the name of the command
+func (g *IpamCommand) Name() string {
+	return g.fs.Name()
+}
+
+// Init sets up the command struct from arguments
+func (g *IpamCommand) Init(args []string) error {
+	if len(args) < 1 || strings.HasPrefix(args[0], "-") {
+		return errors.New("Use ipam list|reserve|release")
+	}
+	g.action = args[0]
+	args = args[1:]
+
+	err := g.fs.Parse(args)
+	if err != nil {
+		return err
+	}
+	g.ProcessDefaults()
+
+	// load config file if we have one
+	if g.config != "" {
+		err = g.meshConfig.LoadConfigFromFile(g.config)
+		if err != nil {
+			log.WithError(err).Error("Config read error")
+			return fmt.Errorf("Unable to read configuration from %s", g.config)
+		}
+	}
+
+	err = g.fs.Parse(args)
+	if err != nil {
+		return err
+	}
+	log.WithField("cfg", g.meshConfig).Trace("Read")
+	log.WithField("cfg.agent", g.meshConfig.Agent).Trace("Read")
+
+	switch g.action {
+	case "list":
+	case "reserve":
+		if net.ParseIP(g.meshIP) == nil {
+			return fmt.Errorf("%s is not a valid ip for -ip", g.meshIP)
+		}
+		if g.pubkey == "" && g.nodeName == "" {
+			return errors.New("Reserve an ip using -pubkey and/or -node-name")
+		}
+	case "release":
+		if net.ParseIP(g.meshIP) == nil {
+			return fmt.Errorf("%s is not a valid ip for -ip", g.meshIP)
+		}
+	default:
+		return fmt.Errorf("Unknown ipam command: %s. Use ipam list|reserve|release", g.action)
+	}
+
+	return nil
+}
+
+// Run queries the agent to list, reserve or release ipam leases
+func (g *IpamCommand) Run() error {
+	log.WithField("g", g).Trace(
+		"Running cli command",
+	)
+
+	//
+	endpoint := fmt.Sprintf("unix://%s", g.meshConfig.Agent.GRPCSocket)
+
+	conn, err := grpc.Dial(endpoint, grpc.WithInsecure(), grpc.WithBlock())
+	if err != nil {
+		log.Error(err)
+		return fmt.Errorf("cannot connect to %s", endpoint)
+	}
+	defer conn.Close()
+
+	agent := meshservice.NewAgentClient(conn)
+	log.WithField("agent", agent).Trace("got grpc service client")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	switch g.action {
+	case "list":
+		return g.list(ctx, agent)
+	case "reserve":
+		return g.reserve(ctx, agent)
+	case "release":
+		return g.release(ctx, agent)
+	}
+
+	return nil
+}
+
+func (g *IpamCommand) list(ctx context.Context, agent meshservice.AgentClient) error {
+	client, err := agent.Leases(ctx, &meshservice.AgentEmpty{})
+	if err != nil {
+		log.Error(err)
+		return errors.New("unable to query leases")
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 1, ' ', tabwriter.Debug)
+
+	fmt.Fprintln(w, "IP\tNode name\tPublic key\tReserved\tUpdated\tReleased\t")
+
+	for {
+		li, err := client.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			log.WithError(err).Debug("error while retrieving lease list")
+			break
+		}
+
+		released := ""
+		if li.ReleasedTS > 0 {
+			released = time.Unix(li.ReleasedTS, 0).String()
+		}
+
+		fmt.Fprintf(w, "%s\t%s\t%s\t%t\t%s\t%s\t\n",
+			li.MeshIP, li.NodeName, li.Pubkey, li.Reserved,
+			time.Unix(li.UpdatedTS, 0).String(), released)
+	}
+	w.Flush()
+
+	return nil
+}
+
+func (g *IpamCommand) reserve(ctx context.Context, agent meshservice.AgentClient) error {
+	r, err := agent.ReserveLease(ctx, &meshservice.LeaseInfo{
+		MeshIP:   g.meshIP,
+		Pubkey:   g.pubkey,
+		NodeName: g.nodeName,
+	})
+	if err != nil {
+		log.Error(err)
+		return errors.New("unable to reserve lease")
+	}
+	log.WithField("r", r).Trace("got leaseResult")
+
+	if !r.Ok {
+		return fmt.Errorf("unable to reserve lease: %s", r.ErrorMessage)
+	}
+	log.Info("Lease reserved")
+
+	return nil
+}
+
+func (g *IpamCommand) release(ctx context.Context, agent meshservice.AgentClient) error {
+	r, err := agent.ReleaseLease(ctx, &meshservice.LeaseInfo{
+		MeshIP: g.meshIP,
+	})
+	if err != nil {
+		log.Error(err)
+		return errors.New("unable to release lease")
+	}
+	log.WithField("r", r).Trace("got leaseResult")
+
+	if !r.Ok {
+		return fmt.Errorf("unable to release lease: %s", r.ErrorMessage)
+	}
+	log.Info("Lease released")
+
+	return nil
+}
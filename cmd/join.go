@@ -1,8 +1,6 @@
 package cmd
 
 import (
-	"context"
-	"crypto/tls"
 	"crypto/x509"
 	"errors"
 	"flag"
@@ -10,6 +8,7 @@ import (
 	"net"
 	"os"
 	"os/signal"
+	"path/filepath"
 	"regexp"
 	"strconv"
 	"strings"
@@ -19,11 +18,7 @@ import (
 	wgwrapper "github.com/aschmidt75/go-wg-wrapper/pkg/wgwrapper"
 	config "github.com/aschmidt75/wgmesh/config"
 	meshservice "github.com/aschmidt75/wgmesh/meshservice"
-	"github.com/cristalhq/jwt/v3"
 	log "github.com/sirupsen/logrus"
-	"google.golang.org/grpc"
-	"google.golang.org/grpc/credentials"
-	"google.golang.org/grpc/metadata"
 )
 
 // JoinCommand struct
@@ -60,9 +55,30 @@ func NewJoinCommand() *JoinCommand {
 	c.fs.StringVar(&c.meshConfig.Join.BootstrapEndpoint, "bootstrap-addr", c.meshConfig.Join.BootstrapEndpoint, "IP:Port of remote mesh bootstrap node.\nenv:WGMESH_BOOTSTRAP_ADDR")
 	c.fs.StringVar(&c.meshConfig.Wireguard.ListenAddr, "listen-addr", c.meshConfig.Wireguard.ListenAddr, "external wireguard ip.\nenv:WGMESH_WIREGUARD_LISTEN_ADDR")
 	c.fs.IntVar(&c.meshConfig.Wireguard.ListenPort, "listen-port", c.meshConfig.Wireguard.ListenPort, "set the (external) wireguard listen port.\nenv:WGMESH_WIREGUARD_LISTEN_PORT")
+	c.fs.StringVar(&c.meshConfig.Wireguard.NAT, "nat", c.meshConfig.Wireguard.NAT, "auto, on or off. Whether this node is behind NAT. auto compares the external ip with local interface addresses.\nenv:WGMESH_NAT")
+	c.fs.IntVar(&c.meshConfig.Wireguard.PersistentKeepaliveSecs, "persistent-keepalive", c.meshConfig.Wireguard.PersistentKeepaliveSecs, "keepalive interval in seconds for peers facing nodes behind NAT. 0 disables keepalives.\nenv:WGMESH_PERSISTENT_KEEPALIVE")
+	c.fs.StringVar(&c.meshConfig.STUN.Servers, "stun-servers", c.meshConfig.STUN.Servers, "comma-separated list of STUN servers (host:port) to detect the external ip.\nenv:WGMESH_STUN_SERVERS")
+	c.fs.BoolVar(&c.meshConfig.STUN.IPv6, "stun-ipv6", c.meshConfig.STUN.IPv6, "also query STUN servers via ipv6.\nenv:WGMESH_STUN_IPV6")
+	c.fs.IntVar(&c.meshConfig.STUN.TimeoutMsec, "stun-timeout", c.meshConfig.STUN.TimeoutMsec, "milliseconds to wait for responses of STUN servers.\nenv:WGMESH_STUN_TIMEOUT_MSEC")
+	c.fs.BoolVar(&c.meshConfig.Wireguard.Relay, "relay", c.meshConfig.Wireguard.Relay, "relay traffic between peers which cannot reach each other directly.\nenv:WGMESH_RELAY")
+	c.fs.IntVar(&c.meshConfig.Wireguard.RelayTimeoutSecs, "relay-timeout", c.meshConfig.Wireguard.RelayTimeoutSecs, "seconds without a handshake after sending traffic to a peer, after which its traffic is sent through a relay node. 0 disables relaying.\nenv:WGMESH_RELAY_TIMEOUT")
+	c.fs.BoolVar(&c.meshConfig.Wireguard.ExitNode, "exit-node", c.meshConfig.Wireguard.ExitNode, "offer this node as exit node, routing default traffic of other nodes.\nenv:WGMESH_EXIT_NODE")
+	c.fs.IntVar(&c.meshConfig.Wireguard.EndpointCheckIntervalSecs, "endpoint-check-interval", c.meshConfig.Wireguard.EndpointCheckIntervalSecs, "seconds after which the external ip is detected again and announced if it changed. 0 disables checks.\nenv:WGMESH_ENDPOINT_CHECK_INTERVAL")
+	c.fs.IntVar(&c.meshConfig.Wireguard.ReconcileIntervalSecs, "reconcile-interval", c.meshConfig.Wireguard.ReconcileIntervalSecs, "seconds between comparisons of wireguard peers with mesh members, fixing any drift. 0 disables reconciliation.\nenv:WGMESH_RECONCILE_INTERVAL")
 	c.fs.StringVar(&c.meshConfig.Join.ClientKey, "client-key", c.meshConfig.Join.ClientKey, "points to PEM-encoded private key to be used.\nenv:WGMESH_CLIENT_KEY")
 	c.fs.StringVar(&c.meshConfig.Join.ClientCert, "client-cert", c.meshConfig.Join.ClientCert, "points to PEM-encoded certificate be used.\nenv:WGMESH_CLIENT_CERT")
 	c.fs.StringVar(&c.meshConfig.Join.ClientCaCert, "ca-cert", c.meshConfig.Join.ClientCaCert, "points to PEM-encoded CA certificate.\nenv:WGMESH_CA_CERT")
+	c.fs.StringVar(&c.meshConfig.Join.Token, "token", c.meshConfig.Join.Token, "pre-shared token to present to the bootstrap node.\nenv:WGMESH_TOKEN")
+	c.fs.StringVar(&c.meshConfig.Join.RequestedIP, "ip", c.meshConfig.Join.RequestedIP, "(optional) mesh ip to request from the bootstrap node.\nenv:WGMESH_REQUESTED_IP")
+	c.fs.IntVar(&c.meshConfig.Join.ApprovalTimeoutSecs, "approval-timeout", c.meshConfig.Join.ApprovalTimeoutSecs, "seconds to wait for the join request to be approved, if the bootstrap node requires approval.\nenv:WGMESH_APPROVAL_TIMEOUT")
+	c.fs.StringVar(&c.meshConfig.Join.Auth.PresharedSecret, "auth-preshared-secret", c.meshConfig.Join.Auth.PresharedSecret, "pre-shared secret to answer hmac auth requirement.\nenv:WGMESH_AUTH_PRESHARED_SECRET")
+	c.fs.StringVar(&c.meshConfig.Join.Auth.TOTPSecret, "auth-totp-secret", c.meshConfig.Join.Auth.TOTPSecret, "base32-encoded secret to compute TOTP codes from.\nenv:WGMESH_AUTH_TOTP_SECRET")
+	c.fs.StringVar(&c.meshConfig.Join.Auth.TOTPCode, "auth-totp-code", c.meshConfig.Join.Auth.TOTPCode, "TOTP code to answer totp auth requirement.\nenv:WGMESH_AUTH_TOTP_CODE")
+	c.fs.StringVar(&c.meshConfig.Join.Auth.SSHKey, "auth-ssh-key", c.meshConfig.Join.Auth.SSHKey, "points to an unencrypted ssh private key to answer sshkey auth requirement.\nenv:WGMESH_AUTH_SSH_KEY")
+	c.fs.StringVar(&c.meshConfig.Group, "group", c.meshConfig.Group, "optional isolation group of this node. It only peers with nodes of groups allowed by the group policy.\nenv:WGMESH_GROUP")
+	c.fs.IntVar(&c.meshConfig.Serf.BindPort, "serf-bind-port", c.meshConfig.Serf.BindPort, "port serf binds to on the mesh ip. Joining nodes take it from the bootstrap node.\nenv:WGMESH_SERF_BIND_PORT")
+	c.fs.StringVar(&c.meshConfig.SerfSnapshot, "serf-snapshot", c.meshConfig.SerfSnapshot, "optional file where serf persists the cluster state, to recover membership after a crash. Requires -state-dir.\nenv:WGMESH_SERF_SNAPSHOT")
+	c.fs.StringVar(&c.meshConfig.StateDir, "state-dir", c.meshConfig.StateDir, "optional directory where the identity of this node is persisted, to rejoin with it after restarts.\nenv:WGMESH_STATE_DIR")
 	c.fs.StringVar(&c.meshConfig.MemberlistFile, "memberlist-file", c.meshConfig.MemberlistFile, "optional name of file for a log of all current mesh members.\nenv:WGMESH_MEMBERLIST_FILE")
 	c.fs.StringVar(&c.meshConfig.Agent.GRPCBindSocket, "agent-grpc-bind-socket", c.meshConfig.Agent.GRPCBindSocket, "local socket file to bind grpc agent to.\nenv:WGMESH_AGENT_BIND_SOCKET")
 	c.fs.StringVar(&c.meshConfig.Agent.GRPCBindSocketIDs, "agent-grpc-bind-socket-id", c.meshConfig.Agent.GRPCBindSocketIDs, "<uid:gid> to change bind socket to.\nenv:WGMESH_AGENT_BIND_SOCKET_ID")
@@ -122,10 +138,56 @@ func (g *JoinCommand) Init(args []string) error {
 		return fmt.Errorf("%s is not a valid port for -bootstrap-addr", arr[1])
 	}
 
+	if g.meshConfig.Join.RequestedIP != "" {
+		if net.ParseIP(g.meshConfig.Join.RequestedIP) == nil {
+			return fmt.Errorf("%s is not a valid ip for -ip", g.meshConfig.Join.RequestedIP)
+		}
+		if pr, _ := isPrivateIP(g.meshConfig.Join.RequestedIP); pr == false {
+			return fmt.Errorf("-ip %s is not RFC1918 or ULA, must be a private address", g.meshConfig.Join.RequestedIP)
+		}
+	}
+
 	if g.meshConfig.Wireguard.ListenPort < 0 || g.meshConfig.Wireguard.ListenPort > 65535 {
 		return fmt.Errorf("%d is not valid for -listen-port", g.meshConfig.Wireguard.ListenPort)
 	}
 
+	if g.meshConfig.Wireguard.NAT != "auto" && g.meshConfig.Wireguard.NAT != "on" && g.meshConfig.Wireguard.NAT != "off" {
+		return fmt.Errorf("%s is not valid for -nat, use auto, on or off", g.meshConfig.Wireguard.NAT)
+	}
+
+	if g.meshConfig.Wireguard.PersistentKeepaliveSecs < 0 || g.meshConfig.Wireguard.PersistentKeepaliveSecs > 65535 {
+		return fmt.Errorf("%d is not valid for -persistent-keepalive", g.meshConfig.Wireguard.PersistentKeepaliveSecs)
+	}
+
+	if err := validateSTUNConfig(g.meshConfig.STUN); err != nil {
+		return err
+	}
+	if err := validateSerfConfig(g.meshConfig.Serf); err != nil {
+		return err
+	}
+	if _, err := parseACLRules(g.meshConfig.ACL); err != nil {
+		return err
+	}
+	if g.meshConfig.Wireguard.EndpointCheckIntervalSecs < 0 {
+		return fmt.Errorf("%d is not valid for -endpoint-check-interval", g.meshConfig.Wireguard.EndpointCheckIntervalSecs)
+	}
+
+	if g.meshConfig.Wireguard.RelayTimeoutSecs < 0 {
+		return fmt.Errorf("%d is not valid for -relay-timeout", g.meshConfig.Wireguard.RelayTimeoutSecs)
+	}
+	if g.meshConfig.Wireguard.ReconcileIntervalSecs < 0 {
+		return fmt.Errorf("%d is not valid for -reconcile-interval", g.meshConfig.Wireguard.ReconcileIntervalSecs)
+	}
+	if g.meshConfig.SerfSnapshot != "" && g.meshConfig.StateDir == "" {
+		// cached peers only accept this node with its former key and mesh ip
+		return errors.New("-serf-snapshot requires -state-dir, so that the node keeps its wireguard key and mesh ip")
+	}
+	if g.meshConfig.Group != "" {
+		if err := meshservice.ValidateGroupName(g.meshConfig.Group); err != nil {
+			return fmt.Errorf("%s is not valid for -group", g.meshConfig.Group)
+		}
+	}
+
 	if g.meshConfig.Agent.GRPCBindSocketIDs != "" {
 		re := regexp.MustCompile(`^[0-9]+:[0-9]+$`)
 
@@ -177,6 +239,14 @@ func (g *JoinCommand) Init(args []string) error {
 		}
 	}
 
+	if g.meshConfig.Join.ApprovalTimeoutSecs < 0 {
+		return fmt.Errorf("%d is not valid for -approval-timeout", g.meshConfig.Join.ApprovalTimeoutSecs)
+	}
+
+	if g.meshConfig.Join.Auth.SSHKey != "" && !fileExists(g.meshConfig.Join.Auth.SSHKey) {
+		return fmt.Errorf("%s not found for -auth-ssh-key", g.meshConfig.Join.Auth.SSHKey)
+	}
+
 	return nil
 }
 
@@ -189,35 +259,30 @@ func (g *JoinCommand) Run() error {
 
 	cfg := g.meshConfig
 
-	var listenIP net.IP
-	if cfg.Wireguard.ListenAddr == "" {
-
-		st := meshservice.NewSTUNService()
-		ips, err := st.GetExternalIP()
-
-		if err != nil {
-			return err
-		}
-		if len(ips) > 0 {
-			listenIP = ips[0]
-			log.WithField("ip", listenIP).Info("Using external IP when connecting with mesh")
-
-		}
-	}
-	if listenIP == nil {
-		listenIP = getIPFromIPOrIntfParam(cfg.Wireguard.ListenAddr)
-		log.WithField("ip", listenIP).Trace("parsed -listen-addr")
-		if listenIP == nil {
-			return errors.New("need -listen-addr")
-		}
-
+	st := newSTUNService(cfg.STUN)
+	listenIP, stunResult, err := detectListenIP(cfg.Wireguard.ListenAddr, &st)
+	if err != nil {
+		return err
 	}
+	log.WithField("ip", listenIP).Info("Using external IP when connecting with mesh")
 
 	ms := meshservice.NewMeshService(cfg.MeshName)
 	log.WithField("ms", ms).Trace("created")
+	if stunResult != nil {
+		ms.SetSTUNResult(stunResult)
+	}
 	ms.WireguardListenIP = listenIP
+	ms.BehindNAT = behindNAT(cfg.Wireguard.NAT, listenIP)
+	ms.Relay = cfg.Wireguard.Relay
+	ms.IsExitNode = cfg.Wireguard.ExitNode
+	ms.Group = cfg.Group
+	if ms.Relay && ms.BehindNAT {
+		log.Warn("This node is a relay but behind NAT, other nodes behind NAT may not be able to reach it")
+	}
+	ms.PersistentKeepalive = time.Duration(cfg.Wireguard.PersistentKeepaliveSecs) * time.Second
 
 	ms.SetMemberlistExportFile(cfg.MemberlistFile)
+	ms.SetSerfSnapshotPath(cfg.SerfSnapshot)
 
 	pk, err := ms.CreateWireguardInterface(cfg.Wireguard.ListenPort)
 	if err != nil {
@@ -238,152 +303,92 @@ func (g *JoinCommand) Run() error {
 		}
 	}
 
-	var opts []grpc.DialOption = []grpc.DialOption{
-		grpc.WithBlock(),
-		grpc.WithTimeout(5 * time.Second),
-	}
-	if ms.TLSConfig != nil {
-		transportCreds := credentials.NewTLS(&tls.Config{
-			Certificates: []tls.Certificate{ms.TLSConfig.Cert},
-			RootCAs:      ms.TLSConfig.CertPool,
-		})
-
-		opts = append(opts, grpc.WithTransportCredentials(transportCreds))
-		log.Debug("TLS-connecting to gRPC mesh service")
-
-	} else {
-		log.Warn("Using insecure connection to gRPC mesh service")
-		opts = append(opts, grpc.WithInsecure())
-	}
-
-	conn, err := grpc.Dial(g.meshConfig.Join.BootstrapEndpoint, opts...)
-	if err != nil {
-		log.Error(err)
-		return fmt.Errorf("cannot connect to %s", g.meshConfig.Join.BootstrapEndpoint)
-	}
-	defer conn.Close()
-
-	service := meshservice.NewMeshClient(conn)
-	log.WithField("service", service).Trace("got grpc service")
-
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second) // TODO make configurable
-	defer cancel()
-
-	token, authResponses, err := g.handleHandshake(ctx, service, &ms)
+	// a node which joined before has a persisted identity. It tries to
+	// rejoin through known peers first, and otherwise joins via the
+	// bootstrap node, asking for its former mesh ip and name.
+	identity, err := g.loadIdentity()
 	if err != nil {
 		return err
 	}
-	// build a jwt containing the auth responses, signing it with received token
-	signer, err := jwt.NewSignerHS(jwt.HS256, []byte(token))
-	if err != nil {
-		return err
-	}
-
-	now := time.Now()
-
-	claims := &jwt.RegisteredClaims{
-		IssuedAt:  jwt.NewNumericDate(now),
-		NotBefore: jwt.NewNumericDate(now),
-		ExpiresAt: jwt.NewNumericDate(now.Add(10 * time.Second)),
-		Subject:   strings.Join(authResponses, "::"),
-	}
-
-	builder := jwt.NewBuilder(signer)
-	jwt, err := builder.Build(claims)
-	if err != nil {
-		return err
+	rejoined := false
+	if identity != nil {
+		rejoined, err = g.rejoinMesh(&ms, listenIP, identity)
+		if err != nil {
+			return err
+		}
+		if cfg.NodeName == "" {
+			cfg.NodeName = identity.NodeName
+		}
+		if cfg.Join.RequestedIP == "" {
+			cfg.Join.RequestedIP = identity.MeshIP
+		}
 	}
 
-	mdCtx := metadata.NewOutgoingContext(ctx, metadata.Pairs("authorization", fmt.Sprintf("Bearer: %s", jwt)))
-
-	joinResponse, err := service.Join(mdCtx, &meshservice.JoinRequest{
-		Pubkey:       ms.WireguardPubKey,
-		EndpointIP:   listenIP.String(),
-		EndpointPort: int32(cfg.Wireguard.ListenPort),
-		MeshName:     cfg.MeshName,
-		NodeName:     cfg.NodeName,
-	})
-	if err != nil {
-		log.Error(err)
+	if !rejoined {
+		joinResponse, meshPeerIPs, err := joinMesh(&ms, cfg, cfg.Join.BootstrapEndpoint, g.devMode, "", "")
+		if err != nil {
+			return err
+		}
+		if joinResponse.Result == meshservice.JoinResponse_ERROR {
+			log.Errorf("Unable to join mesh, message: '%s'. Exiting", joinResponse.ErrorMessage)
+			return nil
+		}
 
-		// remove wireguard interface
-		err = ms.RemoveWireguardInterfaceForMesh()
+		// start the serf part. make it join all received peers
+		err = g.serfSetup(&ms, listenIP, meshPeerIPs, newSerfSettings(cfg.Serf, joinResponse.SerfModeLAN, int(joinResponse.SerfBindPort)), joinResponse.Tags)
 		if err != nil {
-			log.Error(err)
+			return err
 		}
-		return fmt.Errorf("cannot communicate with endpoint at %s", g.meshConfig.Join.BootstrapEndpoint)
 	}
-	log.WithField("jr", joinResponse).Trace("got joinResponse")
 
-	//
-	if joinResponse.Result == meshservice.JoinResponse_ERROR {
-		log.Errorf("Unable to join mesh, message: '%s'. Exiting", joinResponse.ErrorMessage)
-		return nil
+	// from now on, keep the persisted identity up to date
+	if cfg.StateDir != "" {
+		ms.SetIdentityFile(g.identityFile())
+		ms.SaveIdentity()
 	}
 
-	ms.SetTimestamps(joinResponse.CreationTS, time.Now().Unix())
-
-	if !g.devMode {
-		ms.SetEncryptionKey(string(joinResponse.SerfEncryptionKey))
+	// roaming nodes re-detect their external ip and announce changes
+	if cfg.Wireguard.EndpointCheckIntervalSecs > 0 {
+		ms.StartEndpointUpdater(time.Duration(cfg.Wireguard.EndpointCheckIntervalSecs)*time.Second, func() (net.IP, bool, error) {
+			ip, r, err := detectListenIP(cfg.Wireguard.ListenAddr, &st)
+			if r != nil {
+				ms.SetSTUNResult(r)
+			}
+			if err != nil {
+				return nil, false, err
+			}
+			return ip, behindNAT(cfg.Wireguard.NAT, ip), nil
+		})
 	}
 
-	// MeshIP ist composed of what user specifies using -ip, but
-	// with the net mask of -cidr. e.g. 10.232.0.0/16 with an
-	// IP of 10.232.5.99 becomes 10.232.5.99/16
-	ms.MeshIP = net.IPNet{
-		IP:   net.ParseIP(joinResponse.JoiningNodeMeshIP),
-		Mask: ms.CIDRRange.Mask,
+	// fix drift between wireguard peers and mesh members
+	if cfg.Wireguard.ReconcileIntervalSecs > 0 {
+		ms.StartPeerReconciler(time.Duration(cfg.Wireguard.ReconcileIntervalSecs) * time.Second)
 	}
-	log.WithField("meship", ms.MeshIP).Trace("using mesh ip")
-
-	// we have been assigned a local IP for the wireguard interface. Apply it.
-	err = ms.AssignJoiningNodeIP(joinResponse.JoiningNodeMeshIP)
-	if err != nil {
-		log.Error(err)
-		log.Error("Unable assign mesh ip. Exiting")
-
-		// TODO: inform bootstrap explicitly about this, because we're not able
-		// to inform the cluster via gossip. Need to leave explicitly
 
-		// take down interface
-		err2 := ms.RemoveWireguardInterfaceForMesh()
-		if err2 != nil {
-			return err2
+	// relay nodes forward traffic between peers, all others route
+	// traffic to peers they cannot reach directly through a relay
+	if cfg.Wireguard.Relay {
+		if err := ms.EnableForwarding(); err != nil {
+			return err
 		}
-		return err
+	} else if cfg.Wireguard.RelayTimeoutSecs > 0 {
+		ms.StartRelayMonitor(time.Duration(cfg.Wireguard.RelayTimeoutSecs) * time.Second)
 	}
 
-	// set my own node name. Can be empty, it is then derived from the
-	// local mesh ip to have unique names within the serf cluster
-	ms.SetNodeName(cfg.NodeName)
-
-	// query the list of all peers.
-	stream, err := service.Peers(ctx, &meshservice.Empty{})
-	if err != nil {
-		return err
+	if cfg.Wireguard.ExitNode {
+		if err := ms.OfferExitNode(); err != nil {
+			return err
+		}
 	}
 
-	wg := wgwrapper.New()
-
-	// apply peer updates. So we will have wireguard peerings
-	// to all nodes before we join the serf cluster.
-	meshPeerIPs := ms.ApplyPeerUpdatesFromStream(wg, stream)
-
-	// the interface is fully configured, up it
-	wg.SetInterfaceUp(ms.WireguardInterface)
-
-	// Add a route to the CIDR range of the mesh. All detail data
-	// comes from the join response
-	_, meshCidr, _ := net.ParseCIDR(joinResponse.MeshCidr)
-	ms.CIDRRange = *meshCidr
-	err = ms.SetRoute()
+	// enforce network policy, it is recompiled when members or tags change
+	aclRules, err := parseACLRules(cfg.ACL)
 	if err != nil {
 		return err
 	}
-
-	// start the serf part. make it join all received peers
-	err = g.serfSetup(&ms, listenIP, meshPeerIPs, joinResponse.SerfModeLAN)
-	if err != nil {
+	ms.SetACLRules(aclRules)
+	if err = ms.ApplyACL(); err != nil {
 		return err
 	}
 
@@ -393,12 +398,19 @@ func (g *JoinCommand) Run() error {
 	}
 
 	fmt.Printf("** \n")
-	fmt.Printf("** Mesh '%s' has been joined.\n", cfg.MeshName)
+	if rejoined {
+		fmt.Printf("** Mesh '%s' has been rejoined via known peers.\n", cfg.MeshName)
+	} else {
+		fmt.Printf("** Mesh '%s' has been joined.\n", cfg.MeshName)
+	}
 	fmt.Printf("** \n")
 	fmt.Printf("** Mesh name:                       %s\n", cfg.MeshName)
 	fmt.Printf("** Mesh CIDR range:                 %s\n", ms.CIDRRange.String())
 	fmt.Printf("** This node's name:                %s\n", ms.NodeName)
 	fmt.Printf("** This node's mesh IP:             %s\n", ms.MeshIP.IP.String())
+	if ms.MeshIP6.IP != nil {
+		fmt.Printf("** This node's mesh IPv6:           %s\n", ms.MeshIP6.IP.String())
+	}
 	if cfg.MemberlistFile != "" {
 		fmt.Printf("** Mesh node details export to:     %s\n", cfg.MemberlistFile)
 	}
@@ -434,28 +446,6 @@ func (g *JoinCommand) Run() error {
 	return nil
 }
 
-func (g *JoinCommand) handleHandshake(ctx context.Context, service meshservice.MeshClient, ms *meshservice.MeshService) (tokenStr string, authResponses []string, err error) {
-
-	// call Begin method of boostrap's grpc service
-	handshakeResponse, err := service.Begin(ctx, &meshservice.HandshakeRequest{
-		MeshName: g.meshConfig.MeshName,
-	})
-	if err != nil {
-		log.WithError(err).Error("unable to begin handshake")
-	}
-	log.WithField("hr", handshakeResponse).Trace("got handshakeResponse")
-	if handshakeResponse.Result != meshservice.HandshakeResponse_OK {
-		msg := "bootstrap node returned handshake error"
-		log.WithField("msg", handshakeResponse.ErrorMessage).Error(msg)
-		return "", []string{}, errors.New(msg)
-	}
-
-	// TODO process authn/authz requests ...
-	authResps := []string{}
-
-	return handshakeResponse.JoinToken, authResps, nil
-}
-
 // grpcSetup starts the local agent
 func (g *JoinCommand) grpcSetup(ms *meshservice.MeshService) (err error) {
 	cfg := g.meshConfig
@@ -475,11 +465,99 @@ func (g *JoinCommand) grpcSetup(ms *meshservice.MeshService) (err error) {
 	return nil
 }
 
+// identityFile returns the path of the persisted node identity
+func (g *JoinCommand) identityFile() string {
+	return filepath.Join(g.meshConfig.StateDir, "identity.json")
+}
+
+// loadIdentity reads the persisted identity of this node from the state
+// directory. Returns nil if there is none, or it belongs to another mesh.
+func (g *JoinCommand) loadIdentity() (*meshservice.NodeIdentity, error) {
+	if g.meshConfig.StateDir == "" {
+		return nil, nil
+	}
+	if err := os.MkdirAll(g.meshConfig.StateDir, 0700); err != nil {
+		return nil, err
+	}
+
+	identity, err := meshservice.LoadNodeIdentity(g.identityFile())
+	if err != nil {
+		log.WithError(err).Error("Unable to read node identity")
+		return nil, fmt.Errorf("Unable to read node identity from %s", g.meshConfig.StateDir)
+	}
+	if identity != nil && identity.MeshName != g.meshConfig.MeshName {
+		log.WithField("mesh", identity.MeshName).Warn("Ignoring persisted identity of another mesh")
+		return nil, nil
+	}
+	return identity, nil
+}
+
+// rejoinMesh restores the persisted identity of this node and rejoins the
+// serf cluster through the known peers. Peers keep the wireguard peer of a
+// failed node for some time, so this works after a crash, but not after the
+// node left the mesh. Returns false if no known
+// peer could be contacted. The wireguard interface is then reset, keeping
+// the private key, so that the node can join via the bootstrap node.
+func (g *JoinCommand) rejoinMesh(ms *meshservice.MeshService, listenIP net.IP, identity *meshservice.NodeIdentity) (bool, error) {
+	err := ms.RestoreNodeIdentity(identity)
+	if err != nil {
+		return false, err
+	}
+	log.WithFields(log.Fields{
+		"name": ms.NodeName,
+		"ip":   ms.MeshIP.IP.String(),
+	}).Info("Restored persisted node identity")
+
+	// peers cached next to the serf snapshot may be more recent
+	cachedPeers, err := ms.CachedPeers()
+	if err != nil {
+		log.WithError(err).Warn("Unable to read cached peers")
+	}
+	peers := meshservice.MergeKnownPeers(cachedPeers, identity.Peers)
+
+	if len(peers) > 0 {
+		wg := wgwrapper.New()
+		meshPeerIPs := ms.AddKnownPeers(wg, peers)
+		wg.SetInterfaceUp(ms.WireguardInterface)
+
+		if err = ms.SetRoute(); err != nil {
+			return false, err
+		}
+
+		ms.NewSerfCluster(newSerfSettings(g.meshConfig.Serf, identity.SerfModeLAN, identity.SerfBindPort))
+		err = ms.StartSerfCluster(false, ms.WireguardPubKey, listenIP.String(), g.meshConfig.Wireguard.ListenPort, ms.MeshIP.IP.String(), identity.Tags)
+		if err != nil {
+			return false, err
+		}
+
+		n, err := ms.JoinSerfCluster(meshPeerIPs)
+		if n > 0 {
+			log.WithField("n", n).Info("Rejoined mesh via known peers")
+			ms.StartStatsUpdater()
+			return true, nil
+		}
+		log.WithError(err).Warn("Unable to rejoin mesh via known peers, joining via bootstrap node")
+		ms.Serf().Shutdown()
+	}
+
+	// start over with a fresh interface, but keep the key
+	if err = ms.RemoveWireguardInterfaceForMesh(); err != nil {
+		return false, err
+	}
+	if _, err = ms.CreateWireguardInterface(g.meshConfig.Wireguard.ListenPort); err != nil {
+		return false, err
+	}
+	ms.CIDRRange6 = nil
+	ms.MeshIP6 = net.IPNet{}
+
+	return false, ms.RestoreWireguardKey(identity)
+}
+
 // serfSetup ...
-func (g *JoinCommand) serfSetup(ms *meshservice.MeshService, listenIP net.IP, meshIPs []string, lanMode bool) (err error) {
-	ms.NewSerfCluster(lanMode)
+func (g *JoinCommand) serfSetup(ms *meshservice.MeshService, listenIP net.IP, meshIPs []string, settings meshservice.SerfSettings, tags map[string]string) (err error) {
+	ms.NewSerfCluster(settings)
 
-	err = ms.StartSerfCluster(false, ms.WireguardPubKey, listenIP.String(), g.meshConfig.Wireguard.ListenPort, ms.MeshIP.IP.String())
+	err = ms.StartSerfCluster(false, ms.WireguardPubKey, listenIP.String(), g.meshConfig.Wireguard.ListenPort, ms.MeshIP.IP.String(), tags)
 	if err != nil {
 		return err
 	}
@@ -514,6 +592,15 @@ func (g *JoinCommand) cleanUp(ms *meshservice.MeshService) error {
 	// take everything down
 	ms.MeshAgentServer.StopAgentGrpcService()
 
+	// persist the latest known peers before leaving
+	ms.SaveIdentity()
+
+	// ip rules, firewall rules of exit nodes and acls are not
+	// removed together with the wireguard interface
+	ms.StopUsingExitNode()
+	ms.StopOfferingExitNode()
+	ms.RemoveACL()
+
 	ms.LeaveSerfCluster()
 
 	// delete memberlist-file
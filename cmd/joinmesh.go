@@ -0,0 +1,290 @@
+package cmd
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	wgwrapper "github.com/aschmidt75/go-wg-wrapper/pkg/wgwrapper"
+	config "github.com/aschmidt75/wgmesh/config"
+	meshservice "github.com/aschmidt75/wgmesh/meshservice"
+	"github.com/cristalhq/jwt/v3"
+	log "github.com/sirupsen/logrus"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+)
+
+// joinMesh joins the mesh via the gRPC mesh service of the bootstrap node at
+// endpoint. The wireguard interface must have been created before. It is
+// configured from the join response, and peerings to all nodes are applied.
+// Returns the join response and the mesh ips of all peers, so that the
+// serf cluster can be joined. If the join has been rejected, the response
+// has an error result. ipamRange and ipamRange6 are only set by bootstrap
+// nodes joining an existing mesh.
+func joinMesh(ms *meshservice.MeshService, cfg config.Config, endpoint string, devMode bool, ipamRange, ipamRange6 string) (*meshservice.JoinResponse, []string, error) {
+	var opts []grpc.DialOption = []grpc.DialOption{
+		grpc.WithBlock(),
+		grpc.WithTimeout(5 * time.Second),
+	}
+	if ms.TLSConfig != nil {
+		transportCreds := credentials.NewTLS(&tls.Config{
+			Certificates: []tls.Certificate{ms.TLSConfig.Cert},
+			RootCAs:      ms.TLSConfig.CertPool,
+		})
+
+		opts = append(opts, grpc.WithTransportCredentials(transportCreds))
+		log.Debug("TLS-connecting to gRPC mesh service")
+
+	} else {
+		log.Warn("Using insecure connection to gRPC mesh service")
+		opts = append(opts, grpc.WithInsecure())
+	}
+
+	conn, err := grpc.Dial(endpoint, opts...)
+	if err != nil {
+		log.Error(err)
+		return nil, nil, fmt.Errorf("cannot connect to %s", endpoint)
+	}
+	defer conn.Close()
+
+	service := meshservice.NewMeshClient(conn)
+	log.WithField("service", service).Trace("got grpc service")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second) // TODO make configurable
+	defer cancel()
+
+	token, authResponses, err := handleHandshake(ctx, service, cfg)
+	if err != nil {
+		return nil, nil, err
+	}
+	// build a jwt containing the auth responses, signing it with received token
+	signer, err := jwt.NewSignerHS(jwt.HS256, []byte(token))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	now := time.Now()
+
+	claims := &jwt.RegisteredClaims{
+		IssuedAt:  jwt.NewNumericDate(now),
+		NotBefore: jwt.NewNumericDate(now),
+		ExpiresAt: jwt.NewNumericDate(now.Add(10 * time.Second)),
+		ID:        meshservice.JoinTokenID(token),
+		Subject:   strings.Join(authResponses, "::"),
+	}
+
+	builder := jwt.NewBuilder(signer)
+	jwt, err := builder.Build(claims)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// the bootstrap node may park our join request until
+	// it has been approved, so allow for a longer timeout
+	joinCtx, joinCancel := context.WithTimeout(context.Background(), 5*time.Second+time.Duration(cfg.Join.ApprovalTimeoutSecs)*time.Second)
+	defer joinCancel()
+
+	mdCtx := metadata.NewOutgoingContext(joinCtx, metadata.Pairs("authorization", fmt.Sprintf("Bearer: %s", jwt)))
+
+	joinResponse, err := service.Join(mdCtx, &meshservice.JoinRequest{
+		Pubkey:          ms.WireguardPubKey,
+		EndpointIP:      ms.WireguardListenIP.String(),
+		EndpointPort:    int32(cfg.Wireguard.ListenPort),
+		MeshName:        cfg.MeshName,
+		NodeName:        cfg.NodeName,
+		RequestedMeshIP: cfg.Join.RequestedIP,
+		IpamRange:       ipamRange,
+		IpamRange6:      ipamRange6,
+		Nat:             ms.BehindNAT,
+		Group:           ms.Group,
+	})
+	if err != nil {
+		log.Error(err)
+		return nil, nil, fmt.Errorf("cannot communicate with endpoint at %s", endpoint)
+	}
+	log.WithField("jr", joinResponse).Trace("got joinResponse")
+
+	//
+	if joinResponse.Result == meshservice.JoinResponse_ERROR {
+		return joinResponse, nil, nil
+	}
+
+	ms.SetTimestamps(joinResponse.CreationTS, time.Now().Unix())
+
+	if !devMode {
+		ms.SetEncryptionKey(string(joinResponse.SerfEncryptionKey))
+	}
+
+	// preshared keys of all peers are derived from the mesh secret
+	if err = ms.SetPresharedKeys(joinResponse.PskMode, joinResponse.PskSecret); err != nil {
+		return nil, nil, fmt.Errorf("bootstrap node sent invalid preshared key settings: %s", err)
+	}
+	ms.SetPresharedKeyEpoch(joinResponse.PskEpoch)
+
+	// only peer with nodes of groups allowed by the mesh
+	if err = ms.SetGroupPolicy(joinResponse.GroupPolicy); err != nil {
+		return nil, nil, fmt.Errorf("bootstrap node sent an invalid group policy: %s", err)
+	}
+
+	_, meshCidr, err := net.ParseCIDR(joinResponse.MeshCidr)
+	if err != nil {
+		return nil, nil, fmt.Errorf("bootstrap node sent an invalid cidr range: %s", joinResponse.MeshCidr)
+	}
+	ms.CIDRRange = *meshCidr
+
+	// MeshIP ist composed of the ip we have been assigned, but
+	// with the net mask of the mesh cidr range. e.g. 10.232.0.0/16
+	// with an IP of 10.232.5.99 becomes 10.232.5.99/16
+	ms.MeshIP = net.IPNet{
+		IP:   net.ParseIP(joinResponse.JoiningNodeMeshIP),
+		Mask: ms.CIDRRange.Mask,
+	}
+	log.WithField("meship", ms.MeshIP).Trace("using mesh ip")
+
+	// we have been assigned a local IP for the wireguard interface. Apply it.
+	err = ms.AssignJoiningNodeIP(joinResponse.JoiningNodeMeshIP)
+	if err != nil {
+		log.Error(err)
+		log.Error("Unable assign mesh ip. Exiting")
+
+		// TODO: inform bootstrap explicitly about this, because we're not able
+		// to inform the cluster via gossip. Need to leave explicitly
+
+		return nil, nil, err
+	}
+
+	// in dual-stack meshes we have also been assigned an ipv6 mesh ip
+	if joinResponse.JoiningNodeMeshIP6 != "" {
+		_, meshCidr6, err := net.ParseCIDR(joinResponse.MeshCidr6)
+		if err != nil {
+			return nil, nil, fmt.Errorf("bootstrap node sent an invalid ipv6 cidr range: %s", joinResponse.MeshCidr6)
+		}
+		ms.CIDRRange6 = meshCidr6
+		ms.MeshIP6 = net.IPNet{
+			IP:   net.ParseIP(joinResponse.JoiningNodeMeshIP6),
+			Mask: meshCidr6.Mask,
+		}
+
+		err = ms.AssignMeshIP6(joinResponse.JoiningNodeMeshIP6)
+		if err != nil {
+			log.Error(err)
+			log.Error("Unable assign ipv6 mesh ip. Exiting")
+			return nil, nil, err
+		}
+	}
+
+	// set my own node name. Can be empty, it is then derived from the
+	// local mesh ip to have unique names within the serf cluster.
+	// The bootstrap node may also fix the name (e.g. by a token),
+	// or assign an alternative when the requested one is in use
+	nodeName := cfg.NodeName
+	if joinResponse.NodeName != "" {
+		if cfg.NodeName != "" && joinResponse.NodeName != cfg.NodeName {
+			log.WithField("nodeName", joinResponse.NodeName).Warn("Requested node name is in use, bootstrap node assigned an alternative")
+		}
+		nodeName = joinResponse.NodeName
+	}
+	ms.SetNodeName(nodeName)
+
+	// query the list of all peers. Waiting for approval may have
+	// taken some time, so this needs a context of its own.
+	peersCtx, peersCancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer peersCancel()
+
+	stream, err := service.Peers(peersCtx, &meshservice.Empty{})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	wg := wgwrapper.New()
+
+	// apply peer updates. So we will have wireguard peerings
+	// to all nodes before we join the serf cluster.
+	meshPeerIPs := ms.ApplyPeerUpdatesFromStream(wg, stream)
+
+	// the interface is fully configured, up it
+	wg.SetInterfaceUp(ms.WireguardInterface)
+
+	// Add a route to the CIDR range of the mesh. All detail data
+	// comes from the join response
+	err = ms.SetRoute()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return joinResponse, meshPeerIPs, nil
+}
+
+func handleHandshake(ctx context.Context, service meshservice.MeshClient, cfg config.Config) (tokenStr string, authResponses []string, err error) {
+
+	// call Begin method of boostrap's grpc service
+	handshakeResponse, err := service.Begin(ctx, &meshservice.HandshakeRequest{
+		MeshName: cfg.MeshName,
+		Token:    cfg.Join.Token,
+	})
+	if err != nil {
+		log.WithError(err).Error("unable to begin handshake")
+		return "", []string{}, err
+	}
+	log.WithField("hr", handshakeResponse).Trace("got handshakeResponse")
+	if handshakeResponse.Result != meshservice.HandshakeResponse_OK {
+		msg := "bootstrap node returned handshake error"
+		log.WithField("msg", handshakeResponse.ErrorMessage).Error(msg)
+		return "", []string{}, errors.New(msg)
+	}
+
+	// answer all auth requirements of the bootstrap node
+	responders, err := authResponders(cfg.Join.Auth)
+	if err != nil {
+		return "", []string{}, err
+	}
+	authResps := []string{}
+	for name, challenge := range handshakeResponse.AuthReqs {
+		responder, ok := responders[name]
+		if !ok {
+			return "", []string{}, fmt.Errorf("bootstrap node requires auth method %s, which is not configured", name)
+		}
+		response, err := responder.Respond(challenge)
+		if err != nil {
+			log.WithError(err).WithField("auth", name).Error("unable to answer auth requirement")
+			return "", []string{}, err
+		}
+		authResps = append(authResps, meshservice.FormatAuthResponse(name, response))
+	}
+
+	return handshakeResponse.JoinToken, authResps, nil
+}
+
+// authResponders creates responders for all auth methods configured
+// by parameters, by name of the auth requirement
+func authResponders(cfg *config.JoinAuthConfig) (map[string]meshservice.AuthResponder, error) {
+	res := make(map[string]meshservice.AuthResponder)
+	add := func(r meshservice.AuthResponder) {
+		res[r.Name()] = r
+	}
+
+	if cfg.PresharedSecret != "" {
+		add(meshservice.NewHMACAuthResponder(cfg.PresharedSecret))
+	}
+	if cfg.TOTPSecret != "" || cfg.TOTPCode != "" {
+		r, err := meshservice.NewTOTPAuthResponder(cfg.TOTPSecret, cfg.TOTPCode)
+		if err != nil {
+			return nil, fmt.Errorf("%s for -auth-totp-secret", err)
+		}
+		add(r)
+	}
+	if cfg.SSHKey != "" {
+		r, err := meshservice.NewSSHKeyAuthResponder(cfg.SSHKey)
+		if err != nil {
+			return nil, fmt.Errorf("%s for -auth-ssh-key", err)
+		}
+		add(r)
+	}
+
+	return res, nil
+}
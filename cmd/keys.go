@@ -0,0 +1,183 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	config "github.com/aschmidt75/wgmesh/config"
+	meshservice "github.com/aschmidt75/wgmesh/meshservice"
+	log "github.com/sirupsen/logrus"
+	"google.golang.org/grpc"
+)
+
+// KeysCommand struct
+type KeysCommand struct {
+	CommandDefaults
+
+	fs *flag.FlagSet
+
+	// configuration file
+	config string
+	// configuration struct
+	meshConfig config.Config
+
+	// options not in config, only from parameters
+	action string
+	key    string
+}
+
+// NewKeysCommand creates the Keys Command
+func NewKeysCommand() *KeysCommand {
+	c := &KeysCommand{
+		CommandDefaults: NewCommandDefaults(),
+		config:          envStrWithDefault("WGMESH_CONFIG", ""),
+		meshConfig:      config.NewDefaultConfig(),
+		fs:              flag.NewFlagSet("keys", flag.ContinueOnError),
+	}
+
+	c.fs.StringVar(&c.config, "config", c.config, "file name of config file (optional).\nenv:WGMESH_cONFIG")
+	c.fs.StringVar(&c.meshConfig.Agent.GRPCSocket, "agent-grpc-socket", c.meshConfig.Agent.GRPCSocket, "agent socket to dial")
+	c.fs.StringVar(&c.key, "key", c.key, "(install, use, remove) serf encryption key, 32 bytes base64-encoded")
+
+	c.DefaultFields(c.fs)
+
+	return c
+}
+
+// Name returns the name of the command
+func (g *KeysCommand) Name() string {
+	return g.fs.Name()
+}
+
+// Init sets up the command struct from arguments
+func (g *KeysCommand) Init(args []string) error {
+	if len(args) < 1 || strings.HasPrefix(args[0], "-") {
+		return errors.New("Use keys install|use|remove|list")
+	}
+	g.action = args[0]
+	args = args[1:]
+
+	err := g.fs.Parse(args)
+	if err != nil {
+		return err
+	}
+	g.ProcessDefaults()
+
+	// load config file if we have one
+	if g.config != "" {
+		err = g.meshConfig.LoadConfigFromFile(g.config)
+		if err != nil {
+			log.WithError(err).Error("Config read error")
+			return fmt.Errorf("Unable to read configuration from %s", g.config)
+		}
+	}
+
+	err = g.fs.Parse(args)
+	if err != nil {
+		return err
+	}
+	log.WithField("cfg", g.meshConfig).Trace("Read")
+	log.WithField("cfg.agent", g.meshConfig.Agent).Trace("Read")
+
+	switch g.action {
+	case "list":
+	case "install", "use", "remove":
+		if g.key == "" {
+			return fmt.Errorf("Use keys %s -key=<key>", g.action)
+		}
+	default:
+		return fmt.Errorf("Unknown keys command: %s. Use keys install|use|remove|list", g.action)
+	}
+
+	return nil
+}
+
+// Run queries the agent to manage the serf encryption keys of the mesh
+func (g *KeysCommand) Run() error {
+	log.WithField("g", g).Trace(
+		"Running cli command",
+	)
+
+	//
+	endpoint := fmt.Sprintf("unix://%s", g.meshConfig.Agent.GRPCSocket)
+
+	conn, err := grpc.Dial(endpoint, grpc.WithInsecure(), grpc.WithBlock())
+	if err != nil {
+		log.Error(err)
+		return fmt.Errorf("cannot connect to %s", endpoint)
+	}
+	defer conn.Close()
+
+	agent := meshservice.NewAgentClient(conn)
+	log.WithField("agent", agent).Trace("got grpc service client")
+
+	// keyring operations query all nodes of the mesh
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	var r *meshservice.KeyringResponse
+	kr := &meshservice.KeyringRequest{
+		Key: g.key,
+	}
+	switch g.action {
+	case "list":
+		r, err = agent.ListKeys(ctx, &meshservice.AgentEmpty{})
+	case "install":
+		r, err = agent.InstallKey(ctx, kr)
+	case "use":
+		r, err = agent.UseKey(ctx, kr)
+	case "remove":
+		r, err = agent.RemoveKey(ctx, kr)
+	}
+	if err != nil {
+		log.Error(err)
+		return fmt.Errorf("unable to %s key", g.action)
+	}
+	log.WithField("r", r).Trace("got keyringResponse")
+
+	g.print(r)
+
+	if !r.Ok {
+		return fmt.Errorf("unable to %s key: %s", g.action, r.ErrorMessage)
+	}
+	if r.NumResp < r.NumNodes {
+		log.Warnf("%d of %d nodes did not respond", r.NumNodes-r.NumResp, r.NumNodes)
+	}
+
+	return nil
+}
+
+func (g *KeysCommand) print(r *meshservice.KeyringResponse) {
+	fmt.Printf("Nodes: %d, responses: %d, errors: %d\n", r.NumNodes, r.NumResp, r.NumErr)
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 1, ' ', tabwriter.Debug)
+
+	if g.action == "list" {
+		keys := make([]string, 0, len(r.Keys))
+		for key := range r.Keys {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		fmt.Fprintln(w, "Key\tNodes\tPrimary (local)\t")
+		for _, key := range keys {
+			fmt.Fprintf(w, "%s\t%d/%d\t%t\t\n", key, r.Keys[key], r.NumNodes, key == r.PrimaryKey)
+		}
+		w.Flush()
+	}
+
+	if len(r.FailedNodes) > 0 {
+		fmt.Fprintln(w, "Node\tError\t")
+		for _, n := range r.FailedNodes {
+			fmt.Fprintf(w, "%s\t%s\t\n", n.NodeName, n.Message)
+		}
+		w.Flush()
+	}
+}
@@ -0,0 +1,188 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	config "github.com/aschmidt75/wgmesh/config"
+	meshservice "github.com/aschmidt75/wgmesh/meshservice"
+	log "github.com/sirupsen/logrus"
+	"google.golang.org/grpc"
+)
+
+// PendingCommand struct
+type PendingCommand struct {
+	CommandDefaults
+
+	fs *flag.FlagSet
+
+	// configuration file
+	config string
+	// configuration struct
+	meshConfig config.Config
+
+	// options not in config, only from parameters
+	action string
+	id     string
+}
+
+// NewPendingCommand creates the Pending Command
+func NewPendingCommand() *PendingCommand {
+	c := &PendingCommand{
+		CommandDefaults: NewCommandDefaults(),
+		config:          envStrWithDefault("WGMESH_CONFIG", ""),
+		meshConfig:      config.NewDefaultConfig(),
+		fs:              flag.NewFlagSet("pending", flag.ContinueOnError),
+	}
+
+	c.fs.StringVar(&c.config, "config", c.config, "file name of config file (optional).\nenv:WGMESH_cONFIG")
+	c.fs.StringVar(&c.meshConfig.Agent.GRPCSocket, "agent-grpc-socket", c.meshConfig.Agent.GRPCSocket, "agent socket to dial")
+	c.fs.StringVar(&c.id, "id", c.id, "(approve, reject) id of pending join request")
+
+	c.DefaultFields(c.fs)
+
+	return c
+}
+
+// Name returns the name of the command
+func (g *PendingCommand) Name() string {
+	return g.fs.Name()
+}
+
+// Init sets up the command struct from arguments
+func (g *PendingCommand) Init(args []string) error {
+	if len(args) < 1 || strings.HasPrefix(args[0], "-") {
+		return errors.New("Use pending list|approve|reject")
+	}
+	g.action = args[0]
+	args = args[1:]
+
+	err := g.fs.Parse(args)
+	if err != nil {
+		return err
+	}
+	g.ProcessDefaults()
+
+	// load config file if we have one
+	if g.config != "" {
+		err = g.meshConfig.LoadConfigFromFile(g.config)
+		if err != nil {
+			log.WithError(err).Error("Config read error")
+			return fmt.Errorf("Unable to read configuration from %s", g.config)
+		}
+	}
+
+	err = g.fs.Parse(args)
+	if err != nil {
+		return err
+	}
+	log.WithField("cfg", g.meshConfig).Trace("Read")
+	log.WithField("cfg.agent", g.meshConfig.Agent).Trace("Read")
+
+	switch g.action {
+	case "list":
+	case "approve", "reject":
+		if g.id == "" {
+			return fmt.Errorf("Use pending %s -id=<id>", g.action)
+		}
+	default:
+		return fmt.Errorf("Unknown pending command: %s. Use pending list|approve|reject", g.action)
+	}
+
+	return nil
+}
+
+// Run queries the agent to list, approve or reject pending join requests
+func (g *PendingCommand) Run() error {
+	log.WithField("g", g).Trace(
+		"Running cli command",
+	)
+
+	//
+	endpoint := fmt.Sprintf("unix://%s", g.meshConfig.Agent.GRPCSocket)
+
+	conn, err := grpc.Dial(endpoint, grpc.WithInsecure(), grpc.WithBlock())
+	if err != nil {
+		log.Error(err)
+		return fmt.Errorf("cannot connect to %s", endpoint)
+	}
+	defer conn.Close()
+
+	agent := meshservice.NewAgentClient(conn)
+	log.WithField("agent", agent).Trace("got grpc service client")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	switch g.action {
+	case "list":
+		return g.list(ctx, agent)
+	case "approve":
+		return g.decide(ctx, agent, true)
+	case "reject":
+		return g.decide(ctx, agent, false)
+	}
+
+	return nil
+}
+
+func (g *PendingCommand) list(ctx context.Context, agent meshservice.AgentClient) error {
+	client, err := agent.PendingJoins(ctx, &meshservice.AgentEmpty{})
+	if err != nil {
+		log.Error(err)
+		return errors.New("unable to query pending join requests")
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 1, ' ', tabwriter.Debug)
+
+	fmt.Fprintln(w, "ID\tRequested\tNode name\tEndpoint\tPublic key\tCert subject\t")
+
+	for {
+		pj, err := client.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			log.WithError(err).Debug("error while retrieving pending join requests")
+			break
+		}
+
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s:%d\t%s\t%s\t\n",
+			pj.Id, time.Unix(pj.RequestTS, 0).String(), pj.NodeName,
+			pj.EndpointIP, pj.EndpointPort, pj.Pubkey, pj.CertSubject)
+	}
+	w.Flush()
+
+	return nil
+}
+
+func (g *PendingCommand) decide(ctx context.Context, agent meshservice.AgentClient, approve bool) error {
+	r, err := agent.ApproveJoin(ctx, &meshservice.PendingJoinDecision{
+		Id:      g.id,
+		Approve: approve,
+	})
+	if err != nil {
+		log.Error(err)
+		return fmt.Errorf("unable to %s join request", g.action)
+	}
+	log.WithField("r", r).Trace("got pendingJoinResult")
+
+	if !r.Ok {
+		log.Error("Pending join request not found")
+		return nil
+	}
+	if approve {
+		log.Info("Join request approved")
+	} else {
+		log.Info("Join request rejected")
+	}
+
+	return nil
+}
@@ -0,0 +1,151 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"time"
+
+	config "github.com/aschmidt75/wgmesh/config"
+	meshservice "github.com/aschmidt75/wgmesh/meshservice"
+	log "github.com/sirupsen/logrus"
+	"google.golang.org/grpc"
+)
+
+// RotateKeyCommand struct
+type RotateKeyCommand struct {
+	CommandDefaults
+
+	fs *flag.FlagSet
+
+	// configuration file
+	config string
+	// configuration struct
+	meshConfig config.Config
+
+	// options not in config, only from parameters
+	graceSecs int
+	psk       bool
+}
+
+// NewRotateKeyCommand creates the RotateKey Command
+func NewRotateKeyCommand() *RotateKeyCommand {
+	c := &RotateKeyCommand{
+		CommandDefaults: NewCommandDefaults(),
+		config:          envStrWithDefault("WGMESH_CONFIG", ""),
+		meshConfig:      config.NewDefaultConfig(),
+		fs:              flag.NewFlagSet("rotate-key", flag.ContinueOnError),
+		graceSecs:       60,
+	}
+
+	c.fs.StringVar(&c.config, "config", c.config, "file name of config file (optional).\nenv:WGMESH_cONFIG")
+	c.fs.StringVar(&c.meshConfig.Agent.GRPCSocket, "agent-grpc-socket", c.meshConfig.Agent.GRPCSocket, "agent socket to dial")
+	c.fs.IntVar(&c.graceSecs, "grace", c.graceSecs, "seconds until the new key becomes active, during which the previous key is still used. The previous key is announced for the same time afterwards.")
+	c.fs.BoolVar(&c.psk, "psk", c.psk, "rotate the mesh secret for preshared keys on all nodes instead of this node's key")
+
+	c.DefaultFields(c.fs)
+
+	return c
+}
+
+// Name returns the name of the command
+func (g *RotateKeyCommand) Name() string {
+	return g.fs.Name()
+}
+
+// Init sets up the command struct from arguments
+func (g *RotateKeyCommand) Init(args []string) error {
+	err := g.fs.Parse(args)
+	if err != nil {
+		return err
+	}
+	g.ProcessDefaults()
+
+	// load config file if we have one
+	if g.config != "" {
+		err = g.meshConfig.LoadConfigFromFile(g.config)
+		if err != nil {
+			log.WithError(err).Error("Config read error")
+			return fmt.Errorf("Unable to read configuration from %s", g.config)
+		}
+	}
+
+	err = g.fs.Parse(args)
+	if err != nil {
+		return err
+	}
+	log.WithField("cfg", g.meshConfig).Trace("Read")
+	log.WithField("cfg.agent", g.meshConfig.Agent).Trace("Read")
+
+	if time.Duration(g.graceSecs)*time.Second < meshservice.KeyRotationMinGrace {
+		return fmt.Errorf("-grace must be at least %d seconds", int(meshservice.KeyRotationMinGrace/time.Second))
+	}
+
+	return nil
+}
+
+// Run makes the agent replace the wireguard key of the local node
+func (g *RotateKeyCommand) Run() error {
+	log.WithField("g", g).Trace(
+		"Running cli command",
+	)
+
+	//
+	endpoint := fmt.Sprintf("unix://%s", g.meshConfig.Agent.GRPCSocket)
+
+	conn, err := grpc.Dial(endpoint, grpc.WithInsecure(), grpc.WithBlock())
+	if err != nil {
+		log.Error(err)
+		return fmt.Errorf("cannot connect to %s", endpoint)
+	}
+	defer conn.Close()
+
+	agent := meshservice.NewAgentClient(conn)
+	log.WithField("agent", agent).Trace("got grpc service client")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if g.psk {
+		return g.rotatePresharedKeys(ctx, agent)
+	}
+
+	r, err := agent.RotateKey(ctx, &meshservice.RotateKeyRequest{
+		GraceSecs: int32(g.graceSecs),
+	})
+	if err != nil {
+		log.Error(err)
+		return errors.New("unable to rotate key")
+	}
+	log.WithField("r", r).Trace("got rotateKeyResult")
+
+	if !r.Ok {
+		return fmt.Errorf("unable to rotate key: %s", r.ErrorMessage)
+	}
+
+	log.WithFields(log.Fields{
+		"pk":   r.Pubkey,
+		"prev": r.PreviousPubkey,
+		"in":   fmt.Sprintf("%ds", g.graceSecs),
+	}).Info("Announced new wireguard key, it becomes active after the grace period")
+
+	return nil
+}
+
+func (g *RotateKeyCommand) rotatePresharedKeys(ctx context.Context, agent meshservice.AgentClient) error {
+	r, err := agent.RotatePresharedKeys(ctx, &meshservice.AgentEmpty{})
+	if err != nil {
+		log.Error(err)
+		return errors.New("unable to rotate preshared keys")
+	}
+	log.WithField("r", r).Trace("got rotateKeyResult")
+
+	if !r.Ok {
+		return fmt.Errorf("unable to rotate preshared keys: %s", r.ErrorMessage)
+	}
+
+	log.WithField("at", time.Unix(r.ActivationTS, 0)).Info("Rotating preshared keys on all nodes")
+
+	return nil
+}
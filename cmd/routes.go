@@ -0,0 +1,172 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	config "github.com/aschmidt75/wgmesh/config"
+	meshservice "github.com/aschmidt75/wgmesh/meshservice"
+	log "github.com/sirupsen/logrus"
+	"google.golang.org/grpc"
+)
+
+// RoutesCommand struct
+type RoutesCommand struct {
+	CommandDefaults
+
+	fs *flag.FlagSet
+
+	// configuration file
+	config string
+	// configuration struct
+	meshConfig config.Config
+
+	// options not in config, only from parameters
+	action   string
+	nodeName string
+	routes   []string
+}
+
+// NewRoutesCommand creates the Routes Command
+func NewRoutesCommand() *RoutesCommand {
+	c := &RoutesCommand{
+		CommandDefaults: NewCommandDefaults(),
+		config:          envStrWithDefault("WGMESH_CONFIG", ""),
+		meshConfig:      config.NewDefaultConfig(),
+		fs:              flag.NewFlagSet("routes", flag.ContinueOnError),
+	}
+
+	c.fs.StringVar(&c.config, "config", c.config, "file name of config file (optional).\nenv:WGMESH_cONFIG")
+	c.fs.StringVar(&c.meshConfig.Agent.GRPCSocket, "agent-grpc-socket", c.meshConfig.Agent.GRPCSocket, "agent socket to dial")
+	c.fs.StringVar(&c.nodeName, "node", c.nodeName, "(approve) name of node to approve routes of")
+
+	c.DefaultFields(c.fs)
+
+	return c
+}
+
+// Name returns the name of the command
+func (g *RoutesCommand) Name() string {
+	return g.fs.Name()
+}
+
+// Init sets up the command struct from arguments
+func (g *RoutesCommand) Init(args []string) error {
+	g.action = "list"
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		g.action = args[0]
+		args = args[1:]
+	}
+
+	err := g.fs.Parse(args)
+	if err != nil {
+		return err
+	}
+	g.ProcessDefaults()
+
+	// load config file if we have one
+	if g.config != "" {
+		err = g.meshConfig.LoadConfigFromFile(g.config)
+		if err != nil {
+			log.WithError(err).Error("Config read error")
+			return fmt.Errorf("Unable to read configuration from %s", g.config)
+		}
+	}
+
+	err = g.fs.Parse(args)
+	if err != nil {
+		return err
+	}
+	log.WithField("cfg", g.meshConfig).Trace("Read")
+	log.WithField("cfg.agent", g.meshConfig.Agent).Trace("Read")
+
+	// remaining arguments are the routes
+	g.routes = g.fs.Args()
+	for _, route := range g.routes {
+		if _, _, err := net.ParseCIDR(route); err != nil {
+			return fmt.Errorf("%s is not a valid route, use cidr notation", route)
+		}
+	}
+
+	switch g.action {
+	case "list":
+	case "advertise", "withdraw":
+		if len(g.routes) == 0 {
+			return fmt.Errorf("Use routes %s <cidr> [<cidr>...]", g.action)
+		}
+	case "approve":
+		if g.nodeName == "" {
+			return errors.New("Use routes approve -node <name> [<cidr>...]")
+		}
+	default:
+		return fmt.Errorf("Unknown routes command: %s. Use routes list|advertise|withdraw|approve", g.action)
+	}
+
+	return nil
+}
+
+// Run queries the agent to list, advertise, withdraw or approve routes
+func (g *RoutesCommand) Run() error {
+	log.WithField("g", g).Trace(
+		"Running cli command",
+	)
+
+	//
+	endpoint := fmt.Sprintf("unix://%s", g.meshConfig.Agent.GRPCSocket)
+
+	conn, err := grpc.Dial(endpoint, grpc.WithInsecure(), grpc.WithBlock())
+	if err != nil {
+		log.Error(err)
+		return fmt.Errorf("cannot connect to %s", endpoint)
+	}
+	defer conn.Close()
+
+	agent := meshservice.NewAgentClient(conn)
+	log.WithField("agent", agent).Trace("got grpc service client")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	req := &meshservice.RoutesRequest{
+		NodeName: g.nodeName,
+		Routes:   g.routes,
+	}
+
+	var r *meshservice.RoutesResult
+	switch g.action {
+	case "list":
+		r, err = agent.Routes(ctx, &meshservice.AgentEmpty{})
+	case "advertise":
+		r, err = agent.AdvertiseRoutes(ctx, req)
+	case "withdraw":
+		r, err = agent.WithdrawRoutes(ctx, req)
+	case "approve":
+		r, err = agent.ApproveRoutes(ctx, req)
+	}
+	if err != nil {
+		log.Error(err)
+		return fmt.Errorf("unable to %s routes", g.action)
+	}
+	log.WithField("r", r).Trace("got routesResult")
+
+	if !r.Ok {
+		return fmt.Errorf("unable to %s routes: %s", g.action, r.ErrorMessage)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 1, ' ', tabwriter.Debug)
+
+	fmt.Fprintln(w, "Node name\tRoute\tApproved\t")
+	for _, ri := range r.Routes {
+		fmt.Fprintf(w, "%s\t%s\t%t\t\n", ri.NodeName, ri.Cidr, ri.Approved)
+	}
+	w.Flush()
+
+	return nil
+}
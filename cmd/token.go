@@ -0,0 +1,268 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	config "github.com/aschmidt75/wgmesh/config"
+	meshservice "github.com/aschmidt75/wgmesh/meshservice"
+	log "github.com/sirupsen/logrus"
+	"google.golang.org/grpc"
+)
+
+// TokenCommand struct
+type TokenCommand struct {
+	CommandDefaults
+
+	fs *flag.FlagSet
+
+	// configuration file
+	config string
+	// configuration struct
+	meshConfig config.Config
+
+	// options not in config, only from parameters
+	action   string
+	expires  time.Duration
+	maxUses  int
+	nodeName string
+	meshIP   string
+	tagsStr  string
+	id       string
+}
+
+// NewTokenCommand creates the Token Command
+func NewTokenCommand() *TokenCommand {
+	c := &TokenCommand{
+		CommandDefaults: NewCommandDefaults(),
+		config:          envStrWithDefault("WGMESH_CONFIG", ""),
+		meshConfig:      config.NewDefaultConfig(),
+		fs:              flag.NewFlagSet("token", flag.ContinueOnError),
+		expires:         24 * time.Hour,
+		maxUses:         1,
+	}
+
+	c.fs.StringVar(&c.config, "config", c.config, "file name of config file (optional).\nenv:WGMESH_cONFIG")
+	c.fs.StringVar(&c.meshConfig.Agent.GRPCSocket, "agent-grpc-socket", c.meshConfig.Agent.GRPCSocket, "agent socket to dial")
+	c.fs.DurationVar(&c.expires, "expires", c.expires, "(create) duration until token expires, 0 for no expiry")
+	c.fs.IntVar(&c.maxUses, "max-uses", c.maxUses, "(create) number of joins allowed with this token, 0 for unlimited")
+	c.fs.StringVar(&c.nodeName, "node-name", c.nodeName, "(create) optional node name which joining nodes have to use")
+	c.fs.StringVar(&c.meshIP, "ip", c.meshIP, "(create) optional mesh ip assigned to joining nodes")
+	c.fs.StringVar(&c.tagsStr, "tags", c.tagsStr, "(create) tags to set on joining nodes, as key=value,key2=value2")
+	c.fs.StringVar(&c.id, "id", c.id, "(revoke) id of token to revoke")
+
+	c.DefaultFields(c.fs)
+
+	return c
+}
+
+// Name returns the name of the command
+func (g *TokenCommand) Name() string {
+	return g.fs.Name()
+}
+
+// Init sets up the command struct from arguments
+func (g *TokenCommand) Init(args []string) error {
+	if len(args) < 1 || strings.HasPrefix(args[0], "-") {
+		return errors.New("Use token create|list|revoke")
+	}
+	g.action = args[0]
+	args = args[1:]
+
+	err := g.fs.Parse(args)
+	if err != nil {
+		return err
+	}
+	g.ProcessDefaults()
+
+	// load config file if we have one
+	if g.config != "" {
+		err = g.meshConfig.LoadConfigFromFile(g.config)
+		if err != nil {
+			log.WithError(err).Error("Config read error")
+			return fmt.Errorf("Unable to read configuration from %s", g.config)
+		}
+	}
+
+	err = g.fs.Parse(args)
+	if err != nil {
+		return err
+	}
+	log.WithField("cfg", g.meshConfig).Trace("Read")
+	log.WithField("cfg.agent", g.meshConfig.Agent).Trace("Read")
+
+	switch g.action {
+	case "create":
+		if g.expires < 0 {
+			return fmt.Errorf("%s is not valid for -expires", g.expires)
+		}
+		if g.maxUses < 0 {
+			return fmt.Errorf("%d is not valid for -max-uses", g.maxUses)
+		}
+		if g.meshIP != "" && net.ParseIP(g.meshIP) == nil {
+			return fmt.Errorf("%s is not a valid ip for -ip", g.meshIP)
+		}
+		if _, err := parseTagsParam(g.tagsStr); err != nil {
+			return err
+		}
+	case "list":
+	case "revoke":
+		if g.id == "" {
+			return errors.New("Revoke a token using -id=<id>")
+		}
+	default:
+		return fmt.Errorf("Unknown token command: %s. Use token create|list|revoke", g.action)
+	}
+
+	return nil
+}
+
+// Run queries the agent to create, list or revoke tokens
+func (g *TokenCommand) Run() error {
+	log.WithField("g", g).Trace(
+		"Running cli command",
+	)
+
+	//
+	endpoint := fmt.Sprintf("unix://%s", g.meshConfig.Agent.GRPCSocket)
+
+	conn, err := grpc.Dial(endpoint, grpc.WithInsecure(), grpc.WithBlock())
+	if err != nil {
+		log.Error(err)
+		return fmt.Errorf("cannot connect to %s", endpoint)
+	}
+	defer conn.Close()
+
+	agent := meshservice.NewAgentClient(conn)
+	log.WithField("agent", agent).Trace("got grpc service client")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	switch g.action {
+	case "create":
+		return g.create(ctx, agent)
+	case "list":
+		return g.list(ctx, agent)
+	case "revoke":
+		return g.revoke(ctx, agent)
+	}
+
+	return nil
+}
+
+func (g *TokenCommand) create(ctx context.Context, agent meshservice.AgentClient) error {
+	tags, _ := parseTagsParam(g.tagsStr)
+
+	tr := &meshservice.TokenRequest{
+		MaxUses:  int32(g.maxUses),
+		NodeName: g.nodeName,
+		MeshIP:   g.meshIP,
+		Tags:     tags,
+	}
+	if g.expires > 0 {
+		tr.ExpiresTS = time.Now().Add(g.expires).Unix()
+	}
+
+	ti, err := agent.CreateToken(ctx, tr)
+	if err != nil {
+		log.Error(err)
+		return errors.New("unable to create token")
+	}
+	log.WithField("ti", ti).Trace("got tokenInfo")
+
+	fmt.Printf("Created token with id %s. Use it to join:\n", ti.Id)
+	fmt.Printf("wgmesh join -token %s ...\n", ti.Token)
+
+	return nil
+}
+
+func (g *TokenCommand) list(ctx context.Context, agent meshservice.AgentClient) error {
+	client, err := agent.Tokens(ctx, &meshservice.AgentEmpty{})
+	if err != nil {
+		log.Error(err)
+		return errors.New("unable to query tokens")
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 1, ' ', tabwriter.Debug)
+
+	fmt.Fprintln(w, "ID\tExpires\tUses\tNode name\tIP\tTags\t")
+
+	for {
+		ti, err := client.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			log.WithError(err).Debug("error while retrieving token list")
+			break
+		}
+
+		expires := "never"
+		if ti.ExpiresTS > 0 {
+			expires = time.Unix(ti.ExpiresTS, 0).String()
+		}
+		uses := fmt.Sprintf("%d", ti.Uses)
+		if ti.MaxUses > 0 {
+			uses = fmt.Sprintf("%d/%d", ti.Uses, ti.MaxUses)
+		}
+		tagStr := ""
+		for _, tag := range ti.Tags {
+			tagStr = fmt.Sprintf("%s %s=%s,", tagStr, tag.Key, tag.Value)
+		}
+
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t\n", ti.Id, expires, uses, ti.NodeName, ti.MeshIP, tagStr)
+	}
+	w.Flush()
+
+	return nil
+}
+
+func (g *TokenCommand) revoke(ctx context.Context, agent meshservice.AgentClient) error {
+	r, err := agent.RevokeToken(ctx, &meshservice.TokenRevokeRequest{
+		Id: g.id,
+	})
+	if err != nil {
+		log.Error(err)
+		return errors.New("unable to revoke token")
+	}
+	log.WithField("r", r).Trace("got tokenResult")
+
+	if r.Ok {
+		log.Info("Token revoked")
+	} else {
+		log.Error("Token not found")
+	}
+
+	return nil
+}
+
+// parseTagsParam parses a list of key=value,key2=value2 pairs
+func parseTagsParam(s string) ([]*meshservice.NodeTag, error) {
+	res := make([]*meshservice.NodeTag, 0)
+	if s == "" {
+		return res, nil
+	}
+	for _, elem := range strings.Split(s, ",") {
+		arr := strings.SplitN(elem, "=", 2)
+		if len(arr) < 2 || arr[0] == "" {
+			return nil, fmt.Errorf("%s is not a valid tag, use key=value", elem)
+		}
+		if strings.HasPrefix(arr[0], "_") {
+			return nil, errors.New("Tag keys may not start with underscore _")
+		}
+		res = append(res, &meshservice.NodeTag{
+			Key:   arr[0],
+			Value: arr[1],
+		})
+	}
+	return res, nil
+}
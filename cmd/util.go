@@ -2,12 +2,17 @@ package cmd
 
 import (
 	"errors"
+	"fmt"
 	"net"
 	"regexp"
 	"strconv"
 	"strings"
+	"time"
 
 	wgwrapper "github.com/aschmidt75/go-wg-wrapper/pkg/wgwrapper"
+	config "github.com/aschmidt75/wgmesh/config"
+	meshservice "github.com/aschmidt75/wgmesh/meshservice"
+	log "github.com/sirupsen/logrus"
 )
 
 // given an IP address or interface name or empty, this returns the IP
@@ -64,6 +69,133 @@ func getIPFromIPOrIntfParam(i string) net.IP {
 	return net.ParseIP(s)
 }
 
+// newSTUNService creates a STUN service from the stun configuration
+func newSTUNService(cfg *config.STUNConfig) meshservice.STUNService {
+	servers := make([]string, 0)
+	for _, s := range strings.Split(cfg.Servers, ",") {
+		if s = strings.TrimSpace(s); s != "" {
+			servers = append(servers, s)
+		}
+	}
+	return meshservice.NewSTUNServiceWithServers(servers, cfg.IPv6, time.Duration(cfg.TimeoutMsec)*time.Millisecond)
+}
+
+// validateSTUNConfig checks the stun servers and timeout
+func validateSTUNConfig(cfg *config.STUNConfig) error {
+	if cfg.TimeoutMsec <= 0 {
+		return errors.New("-stun-timeout must be > 0")
+	}
+	if strings.TrimSpace(cfg.Servers) == "" {
+		return errors.New("need at least one server in -stun-servers")
+	}
+	for _, s := range strings.Split(cfg.Servers, ",") {
+		if _, _, err := net.SplitHostPort(strings.TrimSpace(s)); err != nil {
+			return fmt.Errorf("invalid stun server %s, must be host:port", s)
+		}
+	}
+	return nil
+}
+
+// newSerfSettings returns the serf settings of the configuration. A bind
+// port taken from the mesh (e.g. by a join response) overrides the
+// configured one, as all nodes need to use the same port.
+func newSerfSettings(cfg *config.SerfConfig, lanMode bool, meshBindPort int) meshservice.SerfSettings {
+	bindPort := cfg.BindPort
+	if meshBindPort > 0 {
+		bindPort = meshBindPort
+	}
+	return meshservice.SerfSettings{
+		LANMode:          lanMode,
+		BindPort:         bindPort,
+		ProbeInterval:    time.Duration(cfg.ProbeIntervalMsec) * time.Millisecond,
+		ProbeTimeout:     time.Duration(cfg.ProbeTimeoutMsec) * time.Millisecond,
+		GossipInterval:   time.Duration(cfg.GossipIntervalMsec) * time.Millisecond,
+		ReapInterval:     time.Duration(cfg.ReapIntervalSecs) * time.Second,
+		ReconnectTimeout: time.Duration(cfg.ReconnectTimeoutSecs) * time.Second,
+		TombstoneTimeout: time.Duration(cfg.TombstoneTimeoutSecs) * time.Second,
+		EventBuffer:      cfg.EventBuffer,
+		QueryBuffer:      cfg.QueryBuffer,
+	}
+}
+
+// validateSerfConfig checks the serf bind port and tuning parameters
+func validateSerfConfig(cfg *config.SerfConfig) error {
+	if cfg.BindPort < 1 || cfg.BindPort > 65535 {
+		return fmt.Errorf("%d is not valid for -serf-bind-port", cfg.BindPort)
+	}
+	if cfg.ProbeIntervalMsec < 0 || cfg.ProbeTimeoutMsec < 0 || cfg.GossipIntervalMsec < 0 {
+		return errors.New("serf probe and gossip intervals must be >= 0")
+	}
+	if cfg.ReapIntervalSecs < 0 || cfg.ReconnectTimeoutSecs < 0 || cfg.TombstoneTimeoutSecs < 0 {
+		return errors.New("serf reap interval and timeouts must be >= 0")
+	}
+	if cfg.EventBuffer < 0 || cfg.QueryBuffer < 0 {
+		return errors.New("serf event and query buffers must be >= 0")
+	}
+	return nil
+}
+
+// parseACLRules parses the network policy rules of the configuration
+func parseACLRules(cfg *config.ACLConfig) ([]meshservice.ACLRule, error) {
+	res := make([]meshservice.ACLRule, 0, len(cfg.Rules))
+	for _, s := range cfg.Rules {
+		rule, err := meshservice.ParseACLRule(s)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, rule)
+	}
+	return res, nil
+}
+
+// detectListenIP determines the external wireguard ip. If listenAddr
+// is empty, it is queried from the STUN servers, otherwise it is
+// taken from listenAddr, which may be an ip or an interface name.
+// The STUN result is returned as well, if servers have been queried.
+func detectListenIP(listenAddr string, st *meshservice.STUNService) (net.IP, *meshservice.STUNResult, error) {
+	if listenAddr == "" {
+		r, err := st.Query()
+		if err != nil {
+			return nil, r, err
+		}
+		if len(r.IPs) > 0 {
+			return r.IPs[0], r, nil
+		}
+	}
+
+	ip := getIPFromIPOrIntfParam(listenAddr)
+	log.WithField("ip", ip).Trace("parsed -listen-addr")
+	if ip == nil {
+		return nil, nil, errors.New("need -listen-addr")
+	}
+	return ip, nil, nil
+}
+
+// behindNAT decides whether this node is behind NAT. With mode auto, this
+// is the case if the external ip is not an address of a local interface.
+func behindNAT(mode string, listenIP net.IP) bool {
+	switch mode {
+	case "on":
+		return true
+	case "off":
+		return false
+	}
+
+	addrs, err := net.InterfaceAddrs()
+	if err != nil {
+		log.WithError(err).Warn("Unable to read interface addresses, assuming no NAT")
+		return false
+	}
+	for _, addr := range addrs {
+		if ipnet, ok := addr.(*net.IPNet); ok && ipnet.IP.Equal(listenIP) {
+			return false
+		}
+	}
+
+	log.WithField("ip", listenIP).Info("External IP is not a local address, this node is behind NAT")
+	return true
+}
+
 // https://stackoverflow.com/questions/41240761/check-if-ip-address-is-in-private-network-space/41273687#41273687
 func isPrivateIP(ip string) (bool, error) {
 	var err error
@@ -75,7 +207,8 @@ func isPrivateIP(ip string) (bool, error) {
 		_, private24BitBlock, _ := net.ParseCIDR("10.0.0.0/8")
 		_, private20BitBlock, _ := net.ParseCIDR("172.16.0.0/12")
 		_, private16BitBlock, _ := net.ParseCIDR("192.168.0.0/16")
-		private = private24BitBlock.Contains(IP) || private20BitBlock.Contains(IP) || private16BitBlock.Contains(IP)
+		_, ulaBlock, _ := net.ParseCIDR("fd00::/8")
+		private = private24BitBlock.Contains(IP) || private20BitBlock.Contains(IP) || private16BitBlock.Contains(IP) || ulaBlock.Contains(IP)
 	}
 	return private, err
 }
@@ -26,6 +26,15 @@ type Config struct {
 	// Wireguard is the configuration part for wireguard-related settings
 	Wireguard *WireguardConfig `yaml:"wireguard,omitempty"`
 
+	// STUN contains settings for detecting the external ip via STUN
+	STUN *STUNConfig `yaml:"stun,omitempty"`
+
+	// ACL contains network policy rules enforced on the wireguard interface
+	ACL *ACLConfig `yaml:"acl,omitempty"`
+
+	// Serf contains the bind port and tuning parameters of the serf cluster
+	Serf *SerfConfig `yaml:"serf,omitempty"`
+
 	// Agent contains optional agent configuration
 	Agent *AgentConfig `yaml:"agent,omitempty"`
 
@@ -35,6 +44,19 @@ type Config struct {
 	// MemberlistFile is an optional setting. If set, node information is written
 	// here periodically
 	MemberlistFile string `yaml:"memberlist-file"`
+
+	// StateDir is an optional directory where wgmesh persists state
+	// such as ipam leases across restarts
+	StateDir string `yaml:"state-dir"`
+
+	// SerfSnapshot is an optional file where serf persists the cluster
+	// state, so that a restarted node knows the members of the mesh.
+	// Requires StateDir.
+	SerfSnapshot string `yaml:"serf-snapshot"`
+
+	// Group is the optional isolation group of this node. It only peers
+	// with nodes of groups allowed by the group policy of the mesh.
+	Group string `yaml:"group"`
 }
 
 // BootstrapConfig contains condfiguration parts for bootstrap mode
@@ -43,13 +65,24 @@ type BootstrapConfig struct {
 	// when assigning new mesh-internal ip addresses
 	MeshCIDRRange string `yaml:"mesh-cidr-range"`
 
+	// MeshCIDRRange6 is an optional IPv6 ULA range (e.g. fd00:232::/64). If set, the mesh
+	// runs dual-stack and each node gets an additional IPv6 mesh address from this range.
+	MeshCIDRRange6 string `yaml:"mesh-cidr-range6"`
+
 	// MeshIPAMCIDRRange is an optional setting where this is a subnet of
 	// MeshCIDRRange and IP addresses are assigned only from this range
 	MeshIPAMCIDRRange string `yaml:"mesh-ipam-cidr-range"`
 
+	// MeshIPAMCIDRRange6 is an optional subnet of the IPv6 range of a dual-stack
+	// mesh, where IPv6 addresses are assigned only from this range
+	MeshIPAMCIDRRange6 string `yaml:"mesh-ipam-cidr-range6"`
+
 	// NodeIP sets the internal mesh ip of this node (e.g. .1 for a given subnet)
 	NodeIP string `yaml:"node-ip"`
 
+	// NodeIP6 sets the internal IPv6 mesh ip of this node in a dual-stack mesh
+	NodeIP6 string `yaml:"node-ip6"`
+
 	// GRPCBindAddr is the ip address where bootstrap node expose their
 	// gRPC intnerface and listen for join requests
 	GRPCBindAddr string `yaml:"grpc-bind-addr"`
@@ -65,8 +98,43 @@ type BootstrapConfig struct {
 	// Must be 32 Bytes base64-ed.
 	MeshEncryptionKey string `yaml:"mesh-encryption-key"`
 
+	// PSKMode is one of off, mesh or pair. In mesh mode, PSKSecret is used as
+	// wireguard preshared key for all peers. In pair mode, a preshared key is
+	// derived for each pair of nodes from PSKSecret and both public keys.
+	PSKMode string `yaml:"psk-mode"`
+
+	// PSKSecret is the optional mesh secret for preshared keys. Must be 32 Bytes
+	// base64-ed. If this is left out, a random secret is assigned.
+	PSKSecret string `yaml:"psk-secret"`
+
 	// SerfModeLAN activates LAN mode or cluster communication. Default is false (=WAN mode).
 	SerfModeLAN bool `yaml:"serf-mode-lan"`
+
+	// RequireToken makes joining nodes present a pre-shared token, created by `wgmesh token create`
+	RequireToken bool `yaml:"require-token"`
+
+	// RequireApproval parks join requests until an operator approves them using `wgmesh pending`
+	RequireApproval bool `yaml:"require-approval"`
+
+	// NodeNamePolicy is either "reject" or "auto-suffix". It decides what happens when
+	// a joining node requests a name which is already in use.
+	NodeNamePolicy string `yaml:"node-name-policy"`
+
+	// RoutePolicy is either "manual" or "auto". It decides whether subnet routes
+	// advertised by nodes wait for approval using `wgmesh routes approve`.
+	RoutePolicy string `yaml:"route-policy"`
+
+	// GroupPolicy tells which isolation groups peer with each other, e.g.
+	// "edge:core,core:*". Groups not in the policy peer with all nodes.
+	GroupPolicy string `yaml:"group-policy"`
+
+	// Auth contains optional auth requirements for joining nodes
+	Auth *BootstrapAuthConfig `yaml:"auth,omitempty"`
+
+	// JoinExisting is the optional IP:Port of the gRPC mesh service of another bootstrap node.
+	// If set, this bootstrap node joins the existing mesh instead of creating a new one, and
+	// serves join requests from its own MeshIPAMCIDRRange.
+	JoinExisting string `yaml:"join-existing"`
 }
 
 // JoinConfig contains condfiguration parts for join mode
@@ -82,6 +150,19 @@ type JoinConfig struct {
 
 	// ClientCaCert points to PEM-encoded CA certificate.
 	ClientCaCert string `yaml:"ca-cert"`
+
+	// Token is an optional pre-shared token presented to the bootstrap node
+	Token string `yaml:"token"`
+
+	// RequestedIP is an optional mesh ip this node asks the bootstrap node for
+	RequestedIP string `yaml:"requested-ip"`
+
+	// ApprovalTimeoutSecs is the number of seconds to wait for a join request to be approved,
+	// in case the bootstrap node requires approval
+	ApprovalTimeoutSecs int `yaml:"approval-timeout"`
+
+	// Auth contains settings to answer auth requirements of the bootstrap node
+	Auth *JoinAuthConfig `yaml:"auth,omitempty"`
 }
 
 // BootstrapGRPCTLSConfig contains settings necessary for configuration TLS for the bootstrap node
@@ -99,6 +180,34 @@ type BootstrapGRPCTLSConfig struct {
 	GRPCCaPath string `yaml:"grpc-ca-path"`
 }
 
+// BootstrapAuthConfig contains the auth requirements joining nodes have to fulfill
+type BootstrapAuthConfig struct {
+	// PresharedSecret requires joining nodes to send an HMAC of a nonce with this secret
+	PresharedSecret string `yaml:"preshared-secret"`
+
+	// TOTPSecret is a base32-encoded secret. Requires joining nodes to send a TOTP code.
+	TOTPSecret string `yaml:"totp-secret"`
+
+	// AuthorizedKeysFile points to a file in ssh authorized_keys format. Requires
+	// joining nodes to sign a nonce with one of these keys.
+	AuthorizedKeysFile string `yaml:"authorized-keys-file"`
+}
+
+// JoinAuthConfig contains settings to answer auth requirements of the bootstrap node
+type JoinAuthConfig struct {
+	// PresharedSecret is the secret shared with the bootstrap node
+	PresharedSecret string `yaml:"preshared-secret"`
+
+	// TOTPSecret is the base32-encoded secret to compute TOTP codes from
+	TOTPSecret string `yaml:"totp-secret"`
+
+	// TOTPCode is a TOTP code to send instead of computing one from TOTPSecret
+	TOTPCode string `yaml:"totp-code"`
+
+	// SSHKey points to an (unencrypted) ssh private key file to sign nonces with
+	SSHKey string `yaml:"ssh-key"`
+}
+
 // WireguardConfig contains wireguard-related settings
 type WireguardConfig struct {
 	// ListenAddr is the ip address where wireguard should listen for packets
@@ -106,6 +215,88 @@ type WireguardConfig struct {
 
 	// ListenPort is the (external) wireguard listen port
 	ListenPort int `yaml:"listen-port"`
+
+	// NAT is one of auto, on or off. It tells whether this node is behind NAT. With
+	// auto, this is detected by comparing the external ip with local interface addresses.
+	NAT string `yaml:"nat"`
+
+	// PersistentKeepaliveSecs is the keepalive interval for peers facing nodes behind NAT.
+	// 0 disables keepalives.
+	PersistentKeepaliveSecs int `yaml:"persistent-keepalive"`
+
+	// EndpointCheckIntervalSecs is the number of seconds after which the external
+	// ip is detected again, and announced to the mesh when it changed. 0 disables checks.
+	EndpointCheckIntervalSecs int `yaml:"endpoint-check-interval"`
+
+	// ReconcileIntervalSecs is the number of seconds between comparisons of the
+	// wireguard peers with the mesh members, fixing any drift. 0 disables reconciliation.
+	ReconcileIntervalSecs int `yaml:"reconcile-interval"`
+
+	// Relay makes this node forward traffic between peers
+	// which are not able to reach each other directly
+	Relay bool `yaml:"relay"`
+
+	// RelayTimeoutSecs is the number of seconds without a handshake after which
+	// traffic to a peer is routed through a relay node. 0 disables relaying.
+	RelayTimeoutSecs int `yaml:"relay-timeout"`
+
+	// ExitNode makes this node offer itself as exit node, routing
+	// default traffic of other nodes to its underlay network
+	ExitNode bool `yaml:"exit-node"`
+}
+
+// STUNConfig contains settings for querying STUN servers
+type STUNConfig struct {
+	// Servers is a comma-separated list of STUN servers (host:port) to query.
+	// More than one server allows for detecting symmetric NAT.
+	Servers string `yaml:"servers"`
+
+	// IPv6 enables querying the STUN servers via ipv6 as well
+	IPv6 bool `yaml:"ipv6"`
+
+	// TimeoutMsec is the number of milliseconds to wait for responses
+	TimeoutMsec int `yaml:"timeout-msec"`
+}
+
+// SerfConfig contains the bind port and tuning parameters of the serf
+// cluster. Probe and gossip intervals left at 0 keep the defaults of
+// the LAN or WAN profile (see serf-mode-lan).
+type SerfConfig struct {
+	// BindPort is the port serf binds to on the mesh ip. Joining
+	// nodes take it from the bootstrap node.
+	BindPort int `yaml:"bind-port"`
+
+	// ProbeIntervalMsec is the interval between failure detection probes
+	ProbeIntervalMsec int `yaml:"probe-interval-msec"`
+
+	// ProbeTimeoutMsec is the time to wait for an ack of a probe
+	ProbeTimeoutMsec int `yaml:"probe-timeout-msec"`
+
+	// GossipIntervalMsec is the interval between gossip messages
+	GossipIntervalMsec int `yaml:"gossip-interval-msec"`
+
+	// ReapIntervalSecs is the interval between reaping failed and left members
+	ReapIntervalSecs int `yaml:"reap-interval-secs"`
+
+	// ReconnectTimeoutSecs is the time after which failed members are reaped
+	ReconnectTimeoutSecs int `yaml:"reconnect-timeout-secs"`
+
+	// TombstoneTimeoutSecs is the time after which left members are reaped
+	TombstoneTimeoutSecs int `yaml:"tombstone-timeout-secs"`
+
+	// EventBuffer is the number of user events kept for late joining nodes
+	EventBuffer int `yaml:"event-buffer"`
+
+	// QueryBuffer is the number of queries kept for late joining nodes
+	QueryBuffer int `yaml:"query-buffer"`
+}
+
+// ACLConfig contains network policy rules, based on tags of the nodes
+type ACLConfig struct {
+	// Rules of the form "<selector> accepts <ports> from <selector>", e.g.
+	// "role=db accepts tcp/5432 from role=app". If set, all other traffic
+	// from the mesh is dropped.
+	Rules []string `yaml:"rules"`
 }
 
 // AgentConfig contains settings for the gRPC-based local agent
@@ -147,11 +338,14 @@ func NewDefaultConfig() Config {
 		MeshName: envStrWithDefault("WGMESH_MESH_NAME", ""),
 		NodeName: envStrWithDefault("WGMESH_NODE_NAME", ""),
 		Bootstrap: &BootstrapConfig{
-			MeshCIDRRange:     envStrWithDefault("WGMESH_CIDR_RANGE", "10.232.0.0/16"),
-			MeshIPAMCIDRRange: envStrWithDefault("WGMESH_CIDR_RANGE_IPAM", ""),
-			NodeIP:            envStrWithDefault("WGMESH_MESH_IP", "10.232.1.1"),
-			GRPCBindAddr:      envStrWithDefault("WGMESH_GRPC_BIND_ADDR", "0.0.0.0"),
-			GRPCBindPort:      envIntWithDefault("WGMESH_GRPC_BIND_PORT", 5000),
+			MeshCIDRRange:      envStrWithDefault("WGMESH_CIDR_RANGE", "10.232.0.0/16"),
+			MeshIPAMCIDRRange:  envStrWithDefault("WGMESH_CIDR_RANGE_IPAM", ""),
+			MeshIPAMCIDRRange6: envStrWithDefault("WGMESH_CIDR_RANGE_IPAM6", ""),
+			MeshCIDRRange6:     envStrWithDefault("WGMESH_CIDR_RANGE6", ""),
+			NodeIP:             envStrWithDefault("WGMESH_MESH_IP", "10.232.1.1"),
+			NodeIP6:            envStrWithDefault("WGMESH_MESH_IP6", ""),
+			GRPCBindAddr:       envStrWithDefault("WGMESH_GRPC_BIND_ADDR", "0.0.0.0"),
+			GRPCBindPort:       envIntWithDefault("WGMESH_GRPC_BIND_PORT", 5000),
 			GRPCTLSConfig: &BootstrapGRPCTLSConfig{
 				GRPCServerKey:  envStrWithDefault("WGMESH_SERVER_KEY", ""),
 				GRPCServerCert: envStrWithDefault("WGMESH_SERVER_CERT", ""),
@@ -159,17 +353,65 @@ func NewDefaultConfig() Config {
 				GRPCCaPath:     envStrWithDefault("WGMESH_CA_PATH", ""),
 			},
 			MeshEncryptionKey: envStrWithDefault("WGMESH_ENCRYPTION_KEY", ""),
+			PSKMode:           envStrWithDefault("WGMESH_PSK_MODE", "off"),
+			PSKSecret:         envStrWithDefault("WGMESH_PSK_SECRET", ""),
 			SerfModeLAN:       envBoolWithDefault("WGMESH_SERF_MODE_LAN", false),
+			RequireToken:      envBoolWithDefault("WGMESH_REQUIRE_TOKEN", false),
+			RequireApproval:   envBoolWithDefault("WGMESH_REQUIRE_APPROVAL", false),
+			NodeNamePolicy:    envStrWithDefault("WGMESH_NODE_NAME_POLICY", "reject"),
+			RoutePolicy:       envStrWithDefault("WGMESH_ROUTE_POLICY", "manual"),
+			GroupPolicy:       envStrWithDefault("WGMESH_GROUP_POLICY", ""),
+			JoinExisting:      envStrWithDefault("WGMESH_JOIN_EXISTING", ""),
+			Auth: &BootstrapAuthConfig{
+				PresharedSecret:    envStrWithDefault("WGMESH_AUTH_PRESHARED_SECRET", ""),
+				TOTPSecret:         envStrWithDefault("WGMESH_AUTH_TOTP_SECRET", ""),
+				AuthorizedKeysFile: envStrWithDefault("WGMESH_AUTH_AUTHORIZED_KEYS", ""),
+			},
 		},
 		Join: &JoinConfig{
-			BootstrapEndpoint: envStrWithDefault("WGMESH_BOOTSTRAP_ADDR", ""),
-			ClientKey:         envStrWithDefault("WGMESH_CLIENT_KEY", ""),
-			ClientCert:        envStrWithDefault("WGMESH_CLIENT_CERT", ""),
-			ClientCaCert:      envStrWithDefault("WGMESH_CA_CERT", ""),
+			BootstrapEndpoint:   envStrWithDefault("WGMESH_BOOTSTRAP_ADDR", ""),
+			ClientKey:           envStrWithDefault("WGMESH_CLIENT_KEY", ""),
+			ClientCert:          envStrWithDefault("WGMESH_CLIENT_CERT", ""),
+			ClientCaCert:        envStrWithDefault("WGMESH_CA_CERT", ""),
+			Token:               envStrWithDefault("WGMESH_TOKEN", ""),
+			RequestedIP:         envStrWithDefault("WGMESH_REQUESTED_IP", ""),
+			ApprovalTimeoutSecs: envIntWithDefault("WGMESH_APPROVAL_TIMEOUT", 300),
+			Auth: &JoinAuthConfig{
+				PresharedSecret: envStrWithDefault("WGMESH_AUTH_PRESHARED_SECRET", ""),
+				TOTPSecret:      envStrWithDefault("WGMESH_AUTH_TOTP_SECRET", ""),
+				TOTPCode:        envStrWithDefault("WGMESH_AUTH_TOTP_CODE", ""),
+				SSHKey:          envStrWithDefault("WGMESH_AUTH_SSH_KEY", ""),
+			},
 		},
 		Wireguard: &WireguardConfig{
-			ListenAddr: envStrWithDefault("WGMESH_WIREGUARD_LISTEN_ADDR", ""),
-			ListenPort: envIntWithDefault("WGMESH_WIREGUARD_LISTEN_PORT", 54540),
+			ListenAddr:                envStrWithDefault("WGMESH_WIREGUARD_LISTEN_ADDR", ""),
+			ListenPort:                envIntWithDefault("WGMESH_WIREGUARD_LISTEN_PORT", 54540),
+			NAT:                       envStrWithDefault("WGMESH_NAT", "auto"),
+			PersistentKeepaliveSecs:   envIntWithDefault("WGMESH_PERSISTENT_KEEPALIVE", 25),
+			EndpointCheckIntervalSecs: envIntWithDefault("WGMESH_ENDPOINT_CHECK_INTERVAL", 0),
+			ReconcileIntervalSecs:     envIntWithDefault("WGMESH_RECONCILE_INTERVAL", 30),
+			Relay:                     envBoolWithDefault("WGMESH_RELAY", false),
+			RelayTimeoutSecs:          envIntWithDefault("WGMESH_RELAY_TIMEOUT", 180),
+			ExitNode:                  envBoolWithDefault("WGMESH_EXIT_NODE", false),
+		},
+		STUN: &STUNConfig{
+			Servers:     envStrWithDefault("WGMESH_STUN_SERVERS", "stun.l.google.com:19302"),
+			IPv6:        envBoolWithDefault("WGMESH_STUN_IPV6", false),
+			TimeoutMsec: envIntWithDefault("WGMESH_STUN_TIMEOUT_MSEC", 3000),
+		},
+		ACL: &ACLConfig{
+			Rules: []string{},
+		},
+		Serf: &SerfConfig{
+			BindPort:             envIntWithDefault("WGMESH_SERF_BIND_PORT", 7946),
+			ProbeIntervalMsec:    envIntWithDefault("WGMESH_SERF_PROBE_INTERVAL_MSEC", 0),
+			ProbeTimeoutMsec:     envIntWithDefault("WGMESH_SERF_PROBE_TIMEOUT_MSEC", 0),
+			GossipIntervalMsec:   envIntWithDefault("WGMESH_SERF_GOSSIP_INTERVAL_MSEC", 0),
+			ReapIntervalSecs:     envIntWithDefault("WGMESH_SERF_REAP_INTERVAL", 15),
+			ReconnectTimeoutSecs: envIntWithDefault("WGMESH_SERF_RECONNECT_TIMEOUT", 86400),
+			TombstoneTimeoutSecs: envIntWithDefault("WGMESH_SERF_TOMBSTONE_TIMEOUT", 86400),
+			EventBuffer:          envIntWithDefault("WGMESH_SERF_EVENT_BUFFER", 512),
+			QueryBuffer:          envIntWithDefault("WGMESH_SERF_QUERY_BUFFER", 512),
 		},
 		Agent: &AgentConfig{
 			GRPCBindSocket:    envStrWithDefault("WGMESH_AGENT_BIND_SOCKET", "/var/run/wgmesh.sock"),
@@ -181,6 +423,9 @@ func NewDefaultConfig() Config {
 			HTTPBindPort: envIntWithDefault("WGMESH_HTTP_BIND_PORT", 9095),
 		},
 		MemberlistFile: envStrWithDefault("WGMESH_MEMBERLIST_FILE", ""),
+		StateDir:       envStrWithDefault("WGMESH_STATE_DIR", ""),
+		SerfSnapshot:   envStrWithDefault("WGMESH_SERF_SNAPSHOT", ""),
+		Group:          envStrWithDefault("WGMESH_GROUP", ""),
 	}
 }
 
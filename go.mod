@@ -23,7 +23,7 @@ require (
 	github.com/miekg/dns v1.1.38 // indirect
 	github.com/sirupsen/logrus v1.7.0
 	go.opencensus.io v0.22.6
-	golang.org/x/crypto v0.0.0-20201221181555-eec23a3978ad // indirect
+	golang.org/x/crypto v0.0.0-20201221181555-eec23a3978ad
 	golang.org/x/lint v0.0.0-20201208152925-83fdc39ff7b5 // indirect
 	golang.org/x/net v0.0.0-20210119194325-5f4716e94777
 	golang.org/x/sys v0.0.0-20210124154548-22da62e12c0c // indirect
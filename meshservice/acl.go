@@ -0,0 +1,296 @@
+package meshservice
+
+import (
+	"bytes"
+	"fmt"
+	"net"
+	"os/exec"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	serf "github.com/hashicorp/serf/serf"
+	log "github.com/sirupsen/logrus"
+)
+
+// ACLRule allows traffic to nodes matching To on given ports,
+// from nodes matching From. Selectors are tags in the form
+// key=value, a tag key only, or * for all nodes.
+type ACLRule struct {
+	To    string
+	From  string
+	Ports []ACLPort
+}
+
+// ACLPort is a protocol (tcp, udp, icmp or any) with
+// an optional port range, for tcp and udp only
+type ACLPort struct {
+	Proto    string
+	FromPort int
+	ToPort   int
+}
+
+// aclState keeps the rules and the latest nftables
+// ruleset applied to the wireguard interface
+type aclState struct {
+	sync.Mutex
+
+	rules   []ACLRule
+	applied string
+}
+
+// ParseACLRule parses a rule of the form
+// "<selector> accepts <proto>[/<port>[-<port>]][,...] from <selector>",
+// e.g. "role=db accepts tcp/5432 from role=app"
+func ParseACLRule(s string) (ACLRule, error) {
+	fields := strings.Fields(s)
+	if len(fields) != 5 || fields[1] != "accepts" || fields[3] != "from" {
+		return ACLRule{}, fmt.Errorf("invalid acl rule '%s', use <selector> accepts <ports> from <selector>", s)
+	}
+
+	rule := ACLRule{
+		To:    fields[0],
+		From:  fields[4],
+		Ports: make([]ACLPort, 0),
+	}
+	for _, sel := range []string{rule.To, rule.From} {
+		if sel == "" || strings.HasPrefix(sel, "=") {
+			return ACLRule{}, fmt.Errorf("invalid selector '%s' in acl rule '%s'", sel, s)
+		}
+	}
+
+	for _, p := range strings.Split(fields[2], ",") {
+		port, err := parseACLPort(p)
+		if err != nil {
+			return ACLRule{}, fmt.Errorf("%s in acl rule '%s'", err, s)
+		}
+		rule.Ports = append(rule.Ports, port)
+	}
+	return rule, nil
+}
+
+func parseACLPort(s string) (ACLPort, error) {
+	arr := strings.SplitN(s, "/", 2)
+	port := ACLPort{Proto: arr[0]}
+
+	switch port.Proto {
+	case "tcp", "udp":
+	case "icmp", "any":
+		if len(arr) > 1 {
+			return ACLPort{}, fmt.Errorf("%s does not have ports", port.Proto)
+		}
+		return port, nil
+	default:
+		return ACLPort{}, fmt.Errorf("invalid protocol '%s', use tcp, udp, icmp or any", port.Proto)
+	}
+	if len(arr) == 1 {
+		return port, nil
+	}
+
+	r := strings.SplitN(arr[1], "-", 2)
+	var err error
+	if port.FromPort, err = strconv.Atoi(r[0]); err != nil || port.FromPort < 1 || port.FromPort > 65535 {
+		return ACLPort{}, fmt.Errorf("invalid port '%s'", arr[1])
+	}
+	port.ToPort = port.FromPort
+	if len(r) > 1 {
+		if port.ToPort, err = strconv.Atoi(r[1]); err != nil || port.ToPort < port.FromPort || port.ToPort > 65535 {
+			return ACLPort{}, fmt.Errorf("invalid port range '%s'", arr[1])
+		}
+	}
+	return port, nil
+}
+
+// matchesSelector tells whether a member matches a selector
+func matchesSelector(member serf.Member, selector string) bool {
+	if selector == "*" {
+		return true
+	}
+	arr := strings.SplitN(selector, "=", 2)
+	value, ok := member.Tags[arr[0]]
+	if len(arr) == 1 {
+		return ok
+	}
+	return ok && value == arr[1]
+}
+
+// nftMatch returns the nftables match expression of a port
+func (p ACLPort) nftMatch() string {
+	switch p.Proto {
+	case "any":
+		return ""
+	case "icmp":
+		return "meta l4proto { icmp, ipv6-icmp }"
+	}
+	if p.FromPort == 0 {
+		return fmt.Sprintf("meta l4proto %s", p.Proto)
+	}
+	if p.FromPort == p.ToPort {
+		return fmt.Sprintf("%s dport %d", p.Proto, p.FromPort)
+	}
+	return fmt.Sprintf("%s dport %d-%d", p.Proto, p.FromPort, p.ToPort)
+}
+
+// SetACLRules sets the rules to be enforced on the wireguard interface.
+// Without rules, all traffic within the mesh is accepted.
+func (ms *MeshService) SetACLRules(rules []ACLRule) {
+	ms.acl.Lock()
+	defer ms.acl.Unlock()
+
+	ms.acl.rules = rules
+}
+
+// aclTableName is the name of the nftables table of this mesh
+func (ms *MeshService) aclTableName() string {
+	return fmt.Sprintf("wgmesh_%s", ms.MeshName)
+}
+
+// compileACL builds the nftables ruleset for this node from the rules
+// and the given members of the mesh. Traffic coming in through the
+// wireguard interface is accepted for established connections, for serf
+// and for all rules this node matches (To) from the mesh ips of matching
+// nodes (From). All other traffic from the mesh is dropped.
+func (ms *MeshService) compileACL(rules []ACLRule, members []serf.Member) string {
+	intf := ms.WireguardInterface.InterfaceName
+
+	var local serf.Member
+	for _, member := range members {
+		if member.Name == ms.NodeName {
+			local = member
+		}
+	}
+
+	lines := []string{
+		fmt.Sprintf("iifname \"%s\" ct state established,related accept", intf),
+	}
+	if ms.cfg != nil && ms.cfg.MemberlistConfig != nil {
+		port := ms.cfg.MemberlistConfig.BindPort
+		lines = append(lines,
+			fmt.Sprintf("iifname \"%s\" tcp dport %d accept", intf, port),
+			fmt.Sprintf("iifname \"%s\" udp dport %d accept", intf, port),
+		)
+	}
+
+	for _, rule := range rules {
+		if !matchesSelector(local, rule.To) {
+			continue
+		}
+
+		ips := make([]string, 0)
+		ip6s := make([]string, 0)
+		for _, member := range members {
+			if member.Name == ms.NodeName || member.Status != serf.StatusAlive || !matchesSelector(member, rule.From) {
+				continue
+			}
+			if ip := net.ParseIP(member.Tags[nodeTagMeshIP]); ip != nil {
+				if ip.To4() != nil {
+					ips = append(ips, ip.String())
+				} else {
+					ip6s = append(ip6s, ip.String())
+				}
+			}
+			if ip := net.ParseIP(member.Tags[nodeTagMeshIP6]); ip != nil {
+				ip6s = append(ip6s, ip.String())
+			}
+		}
+		sort.Strings(ips)
+		sort.Strings(ip6s)
+
+		for _, port := range rule.Ports {
+			for _, src := range []struct {
+				family string
+				ips    []string
+			}{{"ip", ips}, {"ip6", ip6s}} {
+				if len(src.ips) == 0 {
+					continue
+				}
+				line := fmt.Sprintf("iifname \"%s\" %s saddr { %s }", intf, src.family, strings.Join(src.ips, ", "))
+				if m := port.nftMatch(); m != "" {
+					line = line + " " + m
+				}
+				lines = append(lines, line+" accept")
+			}
+		}
+	}
+	lines = append(lines, fmt.Sprintf("iifname \"%s\" drop", intf))
+
+	// the table is created before it is deleted, so that deleting
+	// does not fail on the first run. nft applies all atomically.
+	table := ms.aclTableName()
+	var b strings.Builder
+	fmt.Fprintf(&b, "table inet %s {}\n", table)
+	fmt.Fprintf(&b, "delete table inet %s\n", table)
+	fmt.Fprintf(&b, "table inet %s {\n", table)
+	fmt.Fprintf(&b, "\tchain input {\n")
+	fmt.Fprintf(&b, "\t\ttype filter hook input priority 0; policy accept;\n")
+	for _, line := range lines {
+		fmt.Fprintf(&b, "\t\t%s\n", line)
+	}
+	fmt.Fprintf(&b, "\t}\n")
+	fmt.Fprintf(&b, "}\n")
+	return b.String()
+}
+
+// ApplyACL compiles the rules against the current members and tags, and
+// applies the ruleset using nft if it changed. Does nothing without rules.
+func (ms *MeshService) ApplyACL() error {
+	if ms.Serf() == nil {
+		return nil
+	}
+
+	ms.acl.Lock()
+	defer ms.acl.Unlock()
+
+	if len(ms.acl.rules) == 0 {
+		return nil
+	}
+
+	ruleset := ms.compileACL(ms.acl.rules, ms.Serf().Members())
+	if ruleset == ms.acl.applied {
+		return nil
+	}
+	log.WithField("ruleset", ruleset).Trace("applying acl")
+
+	if err := nft(ruleset); err != nil {
+		return err
+	}
+	ms.acl.applied = ruleset
+
+	log.Debug("Applied acl rules to wireguard interface")
+	return nil
+}
+
+// RemoveACL deletes the nftables table of this mesh, if rules have been applied
+func (ms *MeshService) RemoveACL() {
+	ms.acl.Lock()
+	defer ms.acl.Unlock()
+
+	if ms.acl.applied == "" {
+		return
+	}
+	if err := nft(fmt.Sprintf("delete table inet %s\n", ms.aclTableName())); err != nil {
+		log.WithError(err).Error("Unable to remove acl table")
+	}
+	ms.acl.applied = ""
+}
+
+// applyACLOnChange recompiles the acl after membership or tags changed
+func (ms *MeshService) applyACLOnChange() {
+	if err := ms.ApplyACL(); err != nil {
+		log.WithError(err).Error("Unable to apply acl rules")
+	}
+}
+
+// nft applies a ruleset read from stdin
+func nft(ruleset string) error {
+	cmd := exec.Command("nft", "-f", "-")
+	cmd.Stdin = strings.NewReader(ruleset)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		log.WithField("stderr", stderr.String()).Error("nft reported an error")
+		return err
+	}
+	return nil
+}
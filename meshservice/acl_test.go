@@ -0,0 +1,138 @@
+package meshservice
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	memberlist "github.com/hashicorp/memberlist"
+	serf "github.com/hashicorp/serf/serf"
+)
+
+func TestParseACLRule(t *testing.T) {
+	tests := []struct {
+		s       string
+		want    ACLRule
+		wantErr bool
+	}{
+		{"role=db accepts tcp/5432 from role=app", ACLRule{
+			To: "role=db", From: "role=app",
+			Ports: []ACLPort{{Proto: "tcp", FromPort: 5432, ToPort: 5432}},
+		}, false},
+		{"* accepts icmp,udp/1000-2000,tcp from monitoring", ACLRule{
+			To: "*", From: "monitoring",
+			Ports: []ACLPort{{Proto: "icmp"}, {Proto: "udp", FromPort: 1000, ToPort: 2000}, {Proto: "tcp"}},
+		}, false},
+		{"  web   accepts   any  from  * ", ACLRule{
+			To: "web", From: "*",
+			Ports: []ACLPort{{Proto: "any"}},
+		}, false},
+		{"", ACLRule{}, true},
+		{"role=db accepts tcp/5432", ACLRule{}, true},
+		{"role=db allows tcp/5432 from role=app", ACLRule{}, true},
+		{"role=db accepts tcp/5432 to role=app", ACLRule{}, true},
+		{"=db accepts tcp/5432 from role=app", ACLRule{}, true},
+		{"role=db accepts tcp/5432 from =app", ACLRule{}, true},
+		{"role=db accepts sctp/5432 from role=app", ACLRule{}, true},
+		{"role=db accepts icmp/8 from role=app", ACLRule{}, true},
+		{"role=db accepts any/80 from role=app", ACLRule{}, true},
+		{"role=db accepts tcp/0 from role=app", ACLRule{}, true},
+		{"role=db accepts tcp/65536 from role=app", ACLRule{}, true},
+		{"role=db accepts tcp/http from role=app", ACLRule{}, true},
+		{"role=db accepts udp/2000-1000 from role=app", ACLRule{}, true},
+		{"role=db accepts udp/1000-70000 from role=app", ACLRule{}, true},
+		{"role=db accepts tcp/5432, from role=app", ACLRule{}, true},
+	}
+
+	for _, tt := range tests {
+		rule, err := ParseACLRule(tt.s)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("'%s': expected error, got %+v", tt.s, rule)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("'%s': unexpected error %s", tt.s, err)
+			continue
+		}
+		if !reflect.DeepEqual(rule, tt.want) {
+			t.Errorf("'%s': got %+v, want %+v", tt.s, rule, tt.want)
+		}
+	}
+}
+
+func TestCompileACL(t *testing.T) {
+	members := []serf.Member{
+		{Name: "a", Status: serf.StatusAlive, Tags: map[string]string{"role": "db", nodeTagMeshIP: "10.232.0.1"}},
+		{Name: "b", Status: serf.StatusAlive, Tags: map[string]string{"role": "app", nodeTagMeshIP: "10.232.0.2", nodeTagMeshIP6: "fd00:232::2"}},
+		{Name: "c", Status: serf.StatusAlive, Tags: map[string]string{"role": "app", nodeTagMeshIP: "10.232.0.3"}},
+		{Name: "d", Status: serf.StatusFailed, Tags: map[string]string{"role": "app", nodeTagMeshIP: "10.232.0.4"}},
+		{Name: "e", Status: serf.StatusAlive, Tags: map[string]string{"role": "web", nodeTagMeshIP: "10.232.0.5"}},
+		{Name: "f", Status: serf.StatusAlive, Tags: map[string]string{"role": "web", nodeTagMeshIP: "fd00:232::6"}},
+	}
+
+	tests := []struct {
+		rules []string
+		want  []string
+	}{
+		{[]string{}, []string{}},
+		{[]string{"role=db accepts tcp/5432 from role=app"}, []string{
+			`iifname "wg0" ip saddr { 10.232.0.2, 10.232.0.3 } tcp dport 5432 accept`,
+			`iifname "wg0" ip6 saddr { fd00:232::2 } tcp dport 5432 accept`,
+		}},
+		// rules of other nodes
+		{[]string{"role=web accepts tcp/80 from *"}, []string{}},
+		{[]string{"* accepts icmp,udp/1000-2000,tcp from role=web"}, []string{
+			`iifname "wg0" ip saddr { 10.232.0.5 } meta l4proto { icmp, ipv6-icmp } accept`,
+			`iifname "wg0" ip6 saddr { fd00:232::6 } meta l4proto { icmp, ipv6-icmp } accept`,
+			`iifname "wg0" ip saddr { 10.232.0.5 } udp dport 1000-2000 accept`,
+			`iifname "wg0" ip6 saddr { fd00:232::6 } udp dport 1000-2000 accept`,
+			`iifname "wg0" ip saddr { 10.232.0.5 } meta l4proto tcp accept`,
+			`iifname "wg0" ip6 saddr { fd00:232::6 } meta l4proto tcp accept`,
+		}},
+		// no matching nodes, and the local node itself
+		{[]string{"role accepts any from role=none", "role accepts any from role=db"}, []string{}},
+		{[]string{"role=db accepts any from *"}, []string{
+			`iifname "wg0" ip saddr { 10.232.0.2, 10.232.0.3, 10.232.0.5 } accept`,
+			`iifname "wg0" ip6 saddr { fd00:232::2, fd00:232::6 } accept`,
+		}},
+	}
+
+	for _, tt := range tests {
+		ms := NewMeshService("test")
+		ms.NodeName = "a"
+		ms.WireguardInterface.InterfaceName = "wg0"
+		ms.cfg = &serf.Config{MemberlistConfig: &memberlist.Config{BindPort: 7946}}
+
+		rules := make([]ACLRule, 0, len(tt.rules))
+		for _, s := range tt.rules {
+			rule, err := ParseACLRule(s)
+			if err != nil {
+				t.Fatalf("%v: unexpected error %s", tt.rules, err)
+			}
+			rules = append(rules, rule)
+		}
+
+		want := append([]string{
+			`iifname "wg0" ct state established,related accept`,
+			`iifname "wg0" tcp dport 7946 accept`,
+			`iifname "wg0" udp dport 7946 accept`,
+		}, tt.want...)
+		want = append(want, `iifname "wg0" drop`)
+
+		ruleset := ms.compileACL(rules, members)
+		if !strings.HasPrefix(ruleset, "table inet wgmesh_test {}\ndelete table inet wgmesh_test\ntable inet wgmesh_test {\n") {
+			t.Errorf("%v: unexpected table in ruleset\n%s", tt.rules, ruleset)
+		}
+		got := make([]string, 0)
+		for _, line := range strings.Split(ruleset, "\n") {
+			if line = strings.TrimSpace(line); strings.HasPrefix(line, "iifname") {
+				got = append(got, line)
+			}
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("%v: got\n%s\nwant\n%s", tt.rules, strings.Join(got, "\n"), strings.Join(want, "\n"))
+		}
+	}
+}
@@ -48,6 +48,42 @@ func (as *MeshAgentServer) Info(ctx context.Context, ae *AgentEmpty) (*MeshInfo,
 	log.Trace("agent: Info requested")
 
 	creationTS, nodeJoinTS := as.meshService().GetTimestamps()
+	rs := as.meshService().ReconcileStats()
+
+	var stunInfo *STUNInfo
+	if r := as.meshService().STUNResult(); r != nil {
+		stunInfo = &STUNInfo{
+			Servers:      make([]*STUNServerInfo, 0, len(r.Responses)),
+			ExternalIPs:  make([]string, 0, len(r.IPs)),
+			SymmetricNAT: r.SymmetricNAT,
+			QueriedTS:    r.QueriedTS,
+		}
+		for _, resp := range r.Responses {
+			si := &STUNServerInfo{
+				Server:  resp.Server,
+				Network: resp.Network,
+			}
+			if resp.MappedAddr != nil {
+				si.MappedAddr = resp.MappedAddr.String()
+			}
+			if resp.Err != nil {
+				si.Error = resp.Err.Error()
+			}
+			stunInfo.Servers = append(stunInfo.Servers, si)
+		}
+		for _, ip := range r.IPs {
+			stunInfo.ExternalIPs = append(stunInfo.ExternalIPs, ip.String())
+		}
+	}
+
+	relayed := make([]*RelayedPeerInfo, 0)
+	for _, rp := range as.meshService().RelayedPeers() {
+		relayed = append(relayed, &RelayedPeerInfo{
+			NodeName:      rp.NodeName,
+			RelayNodeName: rp.RelayNodeName,
+			SinceTS:       rp.SinceTS,
+		})
+	}
 
 	return &MeshInfo{
 		Name:          as.meshService().MeshName,
@@ -55,6 +91,23 @@ func (as *MeshAgentServer) Info(ctx context.Context, ae *AgentEmpty) (*MeshInfo,
 		NodeCount:     int32(as.meshService().Serf().NumNodes()),
 		MeshCeationTS: int64(creationTS.Unix()),
 		NodeJoinTS:    int64(nodeJoinTS.Unix()),
+		Reconcile: &PeerReconcileStats{
+			Runs:            rs.Runs,
+			Added:           rs.Added,
+			Removed:         rs.Removed,
+			AllowedIPsFixed: rs.AllowedIPsFixed,
+			EndpointsFixed:  rs.EndpointsFixed,
+			Errors:          rs.Errors,
+			LastRunTS:       rs.LastRunTS,
+			LastDriftTS:     rs.LastDriftTS,
+			PskFixed:        rs.PSKsFixed,
+			KeepaliveFixed:  rs.KeepalivesFixed,
+		},
+		Stun:        stunInfo,
+		Relayed:     relayed,
+		ExitNode:    as.meshService().ExitNode(),
+		Group:       as.meshService().Group,
+		GroupPolicy: as.meshService().GroupPolicy(),
 	}, nil
 }
 
@@ -65,9 +118,10 @@ func (as *MeshAgentServer) Tag(ctx context.Context, tr *NodeTag) (*TagResult, er
 		"v": tr.Value,
 	}).Trace("agent: Tag requested")
 
-	t := as.meshService().Serf().LocalMember().Tags
-	t[tr.Key] = tr.Value
-	err := as.meshService().Serf().SetTags(t)
+	err := as.meshService().updateTags(func(tags map[string]string) error {
+		tags[tr.Key] = tr.Value
+		return nil
+	})
 	if err != nil {
 		log.WithError(err).Error("unable to set tags at serf node")
 		return &TagResult{
@@ -86,17 +140,17 @@ func (as *MeshAgentServer) Untag(ctx context.Context, tr *NodeTag) (*TagResult,
 		"v": tr.Value,
 	}).Trace("agent: Untag requested")
 
-	t := as.meshService().Serf().LocalMember().Tags
-
-	if _, ex := t[tr.Key]; ex == false {
+	found := false
+	err := as.meshService().updateTags(func(tags map[string]string) error {
+		_, found = tags[tr.Key]
+		delete(tags, tr.Key)
+		return nil
+	})
+	if !found {
 		return &TagResult{
 			Ok: false,
 		}, nil
 	}
-
-	delete(t, tr.Key)
-
-	err := as.meshService().Serf().SetTags(t)
 	if err != nil {
 		log.WithError(err).Error("unable to set tags at serf node")
 		return &TagResult{
@@ -280,3 +334,429 @@ func (as *MeshAgentServer) StopAgentGrpcService() {
 	as.grpcServer.GracefulStop()
 	log.Info("Stopped gRPC Agent service")
 }
+
+// CreateToken creates a new pre-shared token
+func (as *MeshAgentServer) CreateToken(ctx context.Context, tr *TokenRequest) (*TokenInfo, error) {
+	log.WithField("tr", tr).Trace("agent: CreateToken requested")
+
+	var expiresAt time.Time
+	if tr.ExpiresTS > 0 {
+		expiresAt = time.Unix(tr.ExpiresTS, 0)
+	}
+
+	var meshIP net.IP
+	if tr.MeshIP != "" {
+		meshIP = net.ParseIP(tr.MeshIP)
+		if meshIP == nil {
+			return nil, fmt.Errorf("%s is not a valid ip", tr.MeshIP)
+		}
+	}
+
+	tags := make(map[string]string)
+	for _, tag := range tr.Tags {
+		if strings.HasPrefix(tag.Key, "_") {
+			return nil, errors.New("tag keys may not start with underscore _")
+		}
+		tags[tag.Key] = tag.Value
+	}
+
+	pt, err := as.meshService().presharedTokens.create(expiresAt, int(tr.MaxUses), tr.NodeName, meshIP, tags)
+	if err != nil {
+		log.WithError(err).Error("unable to create token")
+		return nil, err
+	}
+
+	ti := newTokenInfo(pt)
+	ti.Token = pt.token
+
+	return ti, nil
+}
+
+// Tokens streams all pre-shared tokens, without their secrets
+func (as *MeshAgentServer) Tokens(cte *AgentEmpty, server Agent_TokensServer) error {
+	log.Trace("agent: Tokens requested")
+
+	for _, pt := range as.meshService().presharedTokens.list() {
+		if err := server.Send(newTokenInfo(pt)); err != nil {
+			log.WithError(err).Error("unable to stream send token info")
+		}
+	}
+	return nil
+}
+
+// RevokeToken removes a pre-shared token
+func (as *MeshAgentServer) RevokeToken(ctx context.Context, tr *TokenRevokeRequest) (*TokenResult, error) {
+	log.WithField("id", tr.Id).Trace("agent: RevokeToken requested")
+
+	return &TokenResult{
+		Ok: as.meshService().presharedTokens.revoke(tr.Id),
+	}, nil
+}
+
+func newTokenInfo(pt presharedToken) *TokenInfo {
+	ti := &TokenInfo{
+		Id:       pt.id,
+		MaxUses:  int32(pt.maxUses),
+		Uses:     int32(pt.uses),
+		NodeName: pt.nodeName,
+		Tags:     make([]*NodeTag, 0, len(pt.tags)),
+	}
+	if !pt.expiresAt.IsZero() {
+		ti.ExpiresTS = pt.expiresAt.Unix()
+	}
+	if pt.meshIP != nil {
+		ti.MeshIP = pt.meshIP.String()
+	}
+	for key, value := range pt.tags {
+		ti.Tags = append(ti.Tags, &NodeTag{
+			Key:   key,
+			Value: value,
+		})
+	}
+	return ti
+}
+
+// PendingJoins streams all join requests waiting for approval
+func (as *MeshAgentServer) PendingJoins(cte *AgentEmpty, server Agent_PendingJoinsServer) error {
+	log.Trace("agent: PendingJoins requested")
+
+	for _, pj := range as.meshService().pendingJoins.list() {
+		err := server.Send(&PendingJoinInfo{
+			Id:           pj.id,
+			Pubkey:       pj.pubkey,
+			EndpointIP:   pj.endpointIP,
+			EndpointPort: int32(pj.endpointPort),
+			NodeName:     pj.nodeName,
+			CertSubject:  pj.certSubject,
+			RequestTS:    pj.requestedAt.Unix(),
+		})
+		if err != nil {
+			log.WithError(err).Error("unable to stream send pending join info")
+		}
+	}
+	return nil
+}
+
+// ApproveJoin approves or rejects a pending join request
+func (as *MeshAgentServer) ApproveJoin(ctx context.Context, d *PendingJoinDecision) (*PendingJoinResult, error) {
+	log.WithFields(log.Fields{
+		"id":      d.Id,
+		"approve": d.Approve,
+	}).Trace("agent: ApproveJoin requested")
+
+	return &PendingJoinResult{
+		Ok: as.meshService().pendingJoins.decide(d.Id, d.Approve),
+	}, nil
+}
+
+// Leases streams all ipam leases of this node
+func (as *MeshAgentServer) Leases(cte *AgentEmpty, server Agent_LeasesServer) error {
+	log.Trace("agent: Leases requested")
+
+	for _, l := range as.meshService().leases.list() {
+		err := server.Send(&LeaseInfo{
+			MeshIP:     l.IP,
+			MeshIP6:    l.IP6,
+			Pubkey:     l.Pubkey,
+			NodeName:   l.NodeName,
+			Reserved:   l.Reserved,
+			UpdatedTS:  l.UpdatedTS,
+			ReleasedTS: l.ReleasedTS,
+		})
+		if err != nil {
+			log.WithError(err).Error("unable to stream send lease info")
+		}
+	}
+	return nil
+}
+
+// ReserveLease reserves a mesh ip for a node, identified by public key or node name
+func (as *MeshAgentServer) ReserveLease(ctx context.Context, li *LeaseInfo) (*LeaseResult, error) {
+	log.WithField("li", li).Trace("agent: ReserveLease requested")
+
+	ms := as.meshService()
+
+	ip := net.ParseIP(li.MeshIP)
+	if ip == nil || !ms.CIDRRange.Contains(ip) {
+		return &LeaseResult{
+			Ok:           false,
+			ErrorMessage: "ip is not within the mesh cidr range",
+		}, nil
+	}
+	if li.Pubkey == "" && li.NodeName == "" {
+		return &LeaseResult{
+			Ok:           false,
+			ErrorMessage: "need a public key or node name",
+		}, nil
+	}
+
+	ms.leases.allocation.Lock()
+	err := ms.leases.reserve(ip, li.Pubkey, li.NodeName)
+	ms.leases.allocation.Unlock()
+	if err != nil {
+		return &LeaseResult{
+			Ok:           false,
+			ErrorMessage: err.Error(),
+		}, nil
+	}
+
+	return &LeaseResult{
+		Ok: true,
+	}, nil
+}
+
+// ReleaseLease removes the lease of a mesh ip
+func (as *MeshAgentServer) ReleaseLease(ctx context.Context, li *LeaseInfo) (*LeaseResult, error) {
+	log.WithField("li", li).Trace("agent: ReleaseLease requested")
+
+	ip := net.ParseIP(li.MeshIP)
+	if ip == nil {
+		return &LeaseResult{
+			Ok:           false,
+			ErrorMessage: "not a valid ip",
+		}, nil
+	}
+
+	ok, err := as.meshService().leases.release(ip)
+	if err != nil {
+		return &LeaseResult{
+			Ok:           false,
+			ErrorMessage: err.Error(),
+		}, nil
+	}
+	if !ok {
+		return &LeaseResult{
+			Ok:           false,
+			ErrorMessage: "no lease found",
+		}, nil
+	}
+
+	return &LeaseResult{
+		Ok: true,
+	}, nil
+}
+
+// InstallKey installs a serf encryption key on all nodes
+func (as *MeshAgentServer) InstallKey(ctx context.Context, kr *KeyringRequest) (*KeyringResponse, error) {
+	log.Trace("agent: InstallKey requested")
+
+	if err := validateEncryptionKey(kr.Key); err != nil {
+		return &KeyringResponse{Ok: false, ErrorMessage: err.Error()}, nil
+	}
+	return as.keyringResponse(as.meshService().Serf().KeyManager().InstallKey(kr.Key))
+}
+
+// UseKey makes an installed key the primary serf encryption key on all nodes
+func (as *MeshAgentServer) UseKey(ctx context.Context, kr *KeyringRequest) (*KeyringResponse, error) {
+	log.Trace("agent: UseKey requested")
+
+	if err := validateEncryptionKey(kr.Key); err != nil {
+		return &KeyringResponse{Ok: false, ErrorMessage: err.Error()}, nil
+	}
+	return as.keyringResponse(as.meshService().Serf().KeyManager().UseKey(kr.Key))
+}
+
+// RemoveKey removes a serf encryption key from all nodes
+func (as *MeshAgentServer) RemoveKey(ctx context.Context, kr *KeyringRequest) (*KeyringResponse, error) {
+	log.Trace("agent: RemoveKey requested")
+
+	if err := validateEncryptionKey(kr.Key); err != nil {
+		return &KeyringResponse{Ok: false, ErrorMessage: err.Error()}, nil
+	}
+	return as.keyringResponse(as.meshService().Serf().KeyManager().RemoveKey(kr.Key))
+}
+
+// ListKeys lists the serf encryption keys installed on all nodes
+func (as *MeshAgentServer) ListKeys(ctx context.Context, ae *AgentEmpty) (*KeyringResponse, error) {
+	log.Trace("agent: ListKeys requested")
+
+	return as.keyringResponse(as.meshService().Serf().KeyManager().ListKeys())
+}
+
+// keyringResponse converts the result of a serf keyring operation,
+// reporting all nodes which failed to apply it
+func (as *MeshAgentServer) keyringResponse(kr *serf.KeyResponse, err error) (*KeyringResponse, error) {
+	res := &KeyringResponse{
+		Ok:          err == nil,
+		FailedNodes: make([]*KeyringNodeResult, 0),
+		Keys:        make(map[string]int32),
+		PrimaryKey:  as.meshService().GetEncryptionKey(),
+	}
+	if err != nil {
+		log.WithError(err).Warn("keyring operation failed")
+		res.ErrorMessage = err.Error()
+	}
+	if kr == nil {
+		return res, nil
+	}
+
+	res.NumNodes = int32(kr.NumNodes)
+	res.NumResp = int32(kr.NumResp)
+	res.NumErr = int32(kr.NumErr)
+	for nodeName, msg := range kr.Messages {
+		res.FailedNodes = append(res.FailedNodes, &KeyringNodeResult{
+			NodeName: nodeName,
+			Message:  msg,
+		})
+	}
+	for key, num := range kr.Keys {
+		res.Keys[key] = int32(num)
+	}
+
+	return res, nil
+}
+
+// RotateKey replaces the wireguard key of the local node
+func (as *MeshAgentServer) RotateKey(ctx context.Context, rr *RotateKeyRequest) (*RotateKeyResult, error) {
+	log.WithField("grace", rr.GraceSecs).Trace("agent: RotateKey requested")
+
+	prevPubkey := as.meshService().WireguardPubKey
+	pubkey, err := as.meshService().RotateWireguardKey(time.Duration(rr.GraceSecs) * time.Second)
+	if err != nil {
+		return &RotateKeyResult{
+			Ok:           false,
+			ErrorMessage: err.Error(),
+		}, nil
+	}
+
+	return &RotateKeyResult{
+		Ok:             true,
+		Pubkey:         pubkey,
+		PreviousPubkey: prevPubkey,
+	}, nil
+}
+
+// RotatePresharedKeys replaces the mesh secret for preshared keys on all nodes
+func (as *MeshAgentServer) RotatePresharedKeys(ctx context.Context, ae *AgentEmpty) (*RotateKeyResult, error) {
+	log.Trace("agent: RotatePresharedKeys requested")
+
+	activation, err := as.meshService().RotatePresharedKeys()
+	if err != nil {
+		return &RotateKeyResult{
+			Ok:           false,
+			ErrorMessage: err.Error(),
+		}, nil
+	}
+
+	return &RotateKeyResult{
+		Ok:           true,
+		ActivationTS: activation.Unix(),
+	}, nil
+}
+
+// routesResult returns a successful result listing all routes of the mesh
+func (as *MeshAgentServer) routesResult() *RoutesResult {
+	res := &RoutesResult{
+		Ok:     true,
+		Routes: make([]*RouteInfo, 0),
+	}
+	for _, r := range as.meshService().MeshRoutes() {
+		res.Routes = append(res.Routes, &RouteInfo{
+			NodeName: r.NodeName,
+			Cidr:     r.CIDR,
+			Approved: r.Approved,
+		})
+	}
+	return res
+}
+
+// Routes lists the approved and pending routes of all nodes
+func (as *MeshAgentServer) Routes(ctx context.Context, ae *AgentEmpty) (*RoutesResult, error) {
+	log.Trace("agent: Routes requested")
+
+	return as.routesResult(), nil
+}
+
+// AdvertiseRoutes announces subnets behind the local node
+func (as *MeshAgentServer) AdvertiseRoutes(ctx context.Context, rr *RoutesRequest) (*RoutesResult, error) {
+	log.WithField("routes", rr.Routes).Trace("agent: AdvertiseRoutes requested")
+
+	if err := as.meshService().AdvertiseRoutes(rr.Routes); err != nil {
+		return &RoutesResult{
+			Ok:           false,
+			ErrorMessage: err.Error(),
+		}, nil
+	}
+	return as.routesResult(), nil
+}
+
+// WithdrawRoutes removes subnets advertised by the local node
+func (as *MeshAgentServer) WithdrawRoutes(ctx context.Context, rr *RoutesRequest) (*RoutesResult, error) {
+	log.WithField("routes", rr.Routes).Trace("agent: WithdrawRoutes requested")
+
+	if err := as.meshService().WithdrawRoutes(rr.Routes); err != nil {
+		return &RoutesResult{
+			Ok:           false,
+			ErrorMessage: err.Error(),
+		}, nil
+	}
+	return as.routesResult(), nil
+}
+
+// ApproveRoutes approves pending routes of a node
+func (as *MeshAgentServer) ApproveRoutes(ctx context.Context, rr *RoutesRequest) (*RoutesResult, error) {
+	log.WithFields(log.Fields{
+		"node":   rr.NodeName,
+		"routes": rr.Routes,
+	}).Trace("agent: ApproveRoutes requested")
+
+	approved, err := as.meshService().ApproveRoutes(rr.NodeName, rr.Routes)
+	if err != nil {
+		return &RoutesResult{
+			Ok:           false,
+			ErrorMessage: err.Error(),
+		}, nil
+	}
+
+	// the node announces approved routes by itself, so
+	// they are returned here as they have been sent
+	res := &RoutesResult{
+		Ok:     true,
+		Routes: make([]*RouteInfo, 0, len(approved)),
+	}
+	for _, cidr := range approved {
+		res.Routes = append(res.Routes, &RouteInfo{
+			NodeName: rr.NodeName,
+			Cidr:     cidr,
+			Approved: true,
+		})
+	}
+	return res, nil
+}
+
+// exitNodeResult returns a successful result listing all exit nodes
+func (as *MeshAgentServer) exitNodeResult() *ExitNodeResult {
+	return &ExitNodeResult{
+		Ok:        true,
+		ExitNodes: as.meshService().ExitNodes(),
+		Current:   as.meshService().ExitNode(),
+	}
+}
+
+// ExitNodes lists all nodes offering themselves as exit node
+func (as *MeshAgentServer) ExitNodes(ctx context.Context, ae *AgentEmpty) (*ExitNodeResult, error) {
+	log.Trace("agent: ExitNodes requested")
+
+	return as.exitNodeResult(), nil
+}
+
+// UseExitNode routes default traffic of the local node through an exit node
+func (as *MeshAgentServer) UseExitNode(ctx context.Context, er *ExitNodeRequest) (*ExitNodeResult, error) {
+	log.WithField("node", er.NodeName).Trace("agent: UseExitNode requested")
+
+	if err := as.meshService().UseExitNode(er.NodeName); err != nil {
+		return &ExitNodeResult{
+			Ok:           false,
+			ErrorMessage: err.Error(),
+		}, nil
+	}
+	return as.exitNodeResult(), nil
+}
+
+// StopExitNode stops routing default traffic through an exit node
+func (as *MeshAgentServer) StopExitNode(ctx context.Context, ae *AgentEmpty) (*ExitNodeResult, error) {
+	log.Trace("agent: StopExitNode requested")
+
+	as.meshService().StopUsingExitNode()
+	return as.exitNodeResult(), nil
+}
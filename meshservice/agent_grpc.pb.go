@@ -33,6 +33,48 @@ type AgentClient interface {
 	Tags(ctx context.Context, in *AgentEmpty, opts ...grpc.CallOption) (Agent_TagsClient, error)
 	// RTT yields the complete rtt timings for all nodes
 	RTT(ctx context.Context, in *AgentEmpty, opts ...grpc.CallOption) (Agent_RTTClient, error)
+	// CreateToken creates a pre-shared token for joining nodes
+	CreateToken(ctx context.Context, in *TokenRequest, opts ...grpc.CallOption) (*TokenInfo, error)
+	// Tokens streams all pre-shared tokens of this node
+	Tokens(ctx context.Context, in *AgentEmpty, opts ...grpc.CallOption) (Agent_TokensClient, error)
+	// RevokeToken removes a pre-shared token
+	RevokeToken(ctx context.Context, in *TokenRevokeRequest, opts ...grpc.CallOption) (*TokenResult, error)
+	// PendingJoins streams all join requests waiting for approval
+	PendingJoins(ctx context.Context, in *AgentEmpty, opts ...grpc.CallOption) (Agent_PendingJoinsClient, error)
+	// ApproveJoin approves or rejects a pending join request
+	ApproveJoin(ctx context.Context, in *PendingJoinDecision, opts ...grpc.CallOption) (*PendingJoinResult, error)
+	// Leases streams all ipam leases of this node
+	Leases(ctx context.Context, in *AgentEmpty, opts ...grpc.CallOption) (Agent_LeasesClient, error)
+	// ReserveLease reserves a mesh ip for a node
+	ReserveLease(ctx context.Context, in *LeaseInfo, opts ...grpc.CallOption) (*LeaseResult, error)
+	// ReleaseLease removes the lease of a mesh ip
+	ReleaseLease(ctx context.Context, in *LeaseInfo, opts ...grpc.CallOption) (*LeaseResult, error)
+	// InstallKey installs a serf encryption key on all nodes
+	InstallKey(ctx context.Context, in *KeyringRequest, opts ...grpc.CallOption) (*KeyringResponse, error)
+	// UseKey makes an installed key the primary serf encryption key on all nodes
+	UseKey(ctx context.Context, in *KeyringRequest, opts ...grpc.CallOption) (*KeyringResponse, error)
+	// RemoveKey removes a serf encryption key from all nodes
+	RemoveKey(ctx context.Context, in *KeyringRequest, opts ...grpc.CallOption) (*KeyringResponse, error)
+	// ListKeys lists the serf encryption keys installed on all nodes
+	ListKeys(ctx context.Context, in *AgentEmpty, opts ...grpc.CallOption) (*KeyringResponse, error)
+	// RotateKey replaces the wireguard key of the local node
+	RotateKey(ctx context.Context, in *RotateKeyRequest, opts ...grpc.CallOption) (*RotateKeyResult, error)
+	// RotatePresharedKeys replaces the mesh secret for preshared keys on all nodes
+	RotatePresharedKeys(ctx context.Context, in *AgentEmpty, opts ...grpc.CallOption) (*RotateKeyResult, error)
+	// Routes lists the approved and pending routes of all nodes
+	Routes(ctx context.Context, in *AgentEmpty, opts ...grpc.CallOption) (*RoutesResult, error)
+	// AdvertiseRoutes announces subnets behind the local node
+	AdvertiseRoutes(ctx context.Context, in *RoutesRequest, opts ...grpc.CallOption) (*RoutesResult, error)
+	// WithdrawRoutes removes subnets advertised by the local node
+	WithdrawRoutes(ctx context.Context, in *RoutesRequest, opts ...grpc.CallOption) (*RoutesResult, error)
+	// ApproveRoutes approves pending routes of a node (bootstrap only)
+	ApproveRoutes(ctx context.Context, in *RoutesRequest, opts ...grpc.CallOption) (*RoutesResult, error)
+	// ExitNodes lists all nodes offering themselves as exit node
+	ExitNodes(ctx context.Context, in *AgentEmpty, opts ...grpc.CallOption) (*ExitNodeResult, error)
+	// UseExitNode routes default traffic of the local node through an exit node
+	UseExitNode(ctx context.Context, in *ExitNodeRequest, opts ...grpc.CallOption) (*ExitNodeResult, error)
+	// StopExitNode stops routing default traffic through an exit node
+	StopExitNode(ctx context.Context, in *AgentEmpty, opts ...grpc.CallOption) (*ExitNodeResult, error)
 }
 
 type agentClient struct {
@@ -198,6 +240,264 @@ func (x *agentRTTClient) Recv() (*RTTInfo, error) {
 	return m, nil
 }
 
+func (c *agentClient) CreateToken(ctx context.Context, in *TokenRequest, opts ...grpc.CallOption) (*TokenInfo, error) {
+	out := new(TokenInfo)
+	err := c.cc.Invoke(ctx, "/meshservice.Agent/CreateToken", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *agentClient) Tokens(ctx context.Context, in *AgentEmpty, opts ...grpc.CallOption) (Agent_TokensClient, error) {
+	stream, err := c.cc.NewStream(ctx, &Agent_ServiceDesc.Streams[4], "/meshservice.Agent/Tokens", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &agentTokensClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type Agent_TokensClient interface {
+	Recv() (*TokenInfo, error)
+	grpc.ClientStream
+}
+
+type agentTokensClient struct {
+	grpc.ClientStream
+}
+
+func (x *agentTokensClient) Recv() (*TokenInfo, error) {
+	m := new(TokenInfo)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *agentClient) RevokeToken(ctx context.Context, in *TokenRevokeRequest, opts ...grpc.CallOption) (*TokenResult, error) {
+	out := new(TokenResult)
+	err := c.cc.Invoke(ctx, "/meshservice.Agent/RevokeToken", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *agentClient) PendingJoins(ctx context.Context, in *AgentEmpty, opts ...grpc.CallOption) (Agent_PendingJoinsClient, error) {
+	stream, err := c.cc.NewStream(ctx, &Agent_ServiceDesc.Streams[5], "/meshservice.Agent/PendingJoins", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &agentPendingJoinsClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type Agent_PendingJoinsClient interface {
+	Recv() (*PendingJoinInfo, error)
+	grpc.ClientStream
+}
+
+type agentPendingJoinsClient struct {
+	grpc.ClientStream
+}
+
+func (x *agentPendingJoinsClient) Recv() (*PendingJoinInfo, error) {
+	m := new(PendingJoinInfo)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *agentClient) ApproveJoin(ctx context.Context, in *PendingJoinDecision, opts ...grpc.CallOption) (*PendingJoinResult, error) {
+	out := new(PendingJoinResult)
+	err := c.cc.Invoke(ctx, "/meshservice.Agent/ApproveJoin", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *agentClient) Leases(ctx context.Context, in *AgentEmpty, opts ...grpc.CallOption) (Agent_LeasesClient, error) {
+	stream, err := c.cc.NewStream(ctx, &Agent_ServiceDesc.Streams[6], "/meshservice.Agent/Leases", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &agentLeasesClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type Agent_LeasesClient interface {
+	Recv() (*LeaseInfo, error)
+	grpc.ClientStream
+}
+
+type agentLeasesClient struct {
+	grpc.ClientStream
+}
+
+func (x *agentLeasesClient) Recv() (*LeaseInfo, error) {
+	m := new(LeaseInfo)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *agentClient) ReserveLease(ctx context.Context, in *LeaseInfo, opts ...grpc.CallOption) (*LeaseResult, error) {
+	out := new(LeaseResult)
+	err := c.cc.Invoke(ctx, "/meshservice.Agent/ReserveLease", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *agentClient) ReleaseLease(ctx context.Context, in *LeaseInfo, opts ...grpc.CallOption) (*LeaseResult, error) {
+	out := new(LeaseResult)
+	err := c.cc.Invoke(ctx, "/meshservice.Agent/ReleaseLease", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *agentClient) InstallKey(ctx context.Context, in *KeyringRequest, opts ...grpc.CallOption) (*KeyringResponse, error) {
+	out := new(KeyringResponse)
+	err := c.cc.Invoke(ctx, "/meshservice.Agent/InstallKey", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *agentClient) UseKey(ctx context.Context, in *KeyringRequest, opts ...grpc.CallOption) (*KeyringResponse, error) {
+	out := new(KeyringResponse)
+	err := c.cc.Invoke(ctx, "/meshservice.Agent/UseKey", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *agentClient) RemoveKey(ctx context.Context, in *KeyringRequest, opts ...grpc.CallOption) (*KeyringResponse, error) {
+	out := new(KeyringResponse)
+	err := c.cc.Invoke(ctx, "/meshservice.Agent/RemoveKey", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *agentClient) ListKeys(ctx context.Context, in *AgentEmpty, opts ...grpc.CallOption) (*KeyringResponse, error) {
+	out := new(KeyringResponse)
+	err := c.cc.Invoke(ctx, "/meshservice.Agent/ListKeys", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *agentClient) RotateKey(ctx context.Context, in *RotateKeyRequest, opts ...grpc.CallOption) (*RotateKeyResult, error) {
+	out := new(RotateKeyResult)
+	err := c.cc.Invoke(ctx, "/meshservice.Agent/RotateKey", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *agentClient) RotatePresharedKeys(ctx context.Context, in *AgentEmpty, opts ...grpc.CallOption) (*RotateKeyResult, error) {
+	out := new(RotateKeyResult)
+	err := c.cc.Invoke(ctx, "/meshservice.Agent/RotatePresharedKeys", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *agentClient) Routes(ctx context.Context, in *AgentEmpty, opts ...grpc.CallOption) (*RoutesResult, error) {
+	out := new(RoutesResult)
+	err := c.cc.Invoke(ctx, "/meshservice.Agent/Routes", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *agentClient) AdvertiseRoutes(ctx context.Context, in *RoutesRequest, opts ...grpc.CallOption) (*RoutesResult, error) {
+	out := new(RoutesResult)
+	err := c.cc.Invoke(ctx, "/meshservice.Agent/AdvertiseRoutes", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *agentClient) WithdrawRoutes(ctx context.Context, in *RoutesRequest, opts ...grpc.CallOption) (*RoutesResult, error) {
+	out := new(RoutesResult)
+	err := c.cc.Invoke(ctx, "/meshservice.Agent/WithdrawRoutes", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *agentClient) ApproveRoutes(ctx context.Context, in *RoutesRequest, opts ...grpc.CallOption) (*RoutesResult, error) {
+	out := new(RoutesResult)
+	err := c.cc.Invoke(ctx, "/meshservice.Agent/ApproveRoutes", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *agentClient) ExitNodes(ctx context.Context, in *AgentEmpty, opts ...grpc.CallOption) (*ExitNodeResult, error) {
+	out := new(ExitNodeResult)
+	err := c.cc.Invoke(ctx, "/meshservice.Agent/ExitNodes", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *agentClient) UseExitNode(ctx context.Context, in *ExitNodeRequest, opts ...grpc.CallOption) (*ExitNodeResult, error) {
+	out := new(ExitNodeResult)
+	err := c.cc.Invoke(ctx, "/meshservice.Agent/UseExitNode", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *agentClient) StopExitNode(ctx context.Context, in *AgentEmpty, opts ...grpc.CallOption) (*ExitNodeResult, error) {
+	out := new(ExitNodeResult)
+	err := c.cc.Invoke(ctx, "/meshservice.Agent/StopExitNode", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // AgentServer is the server API for Agent service.
 // All implementations must embed UnimplementedAgentServer
 // for forward compatibility
@@ -217,6 +517,48 @@ type AgentServer interface {
 	Tags(*AgentEmpty, Agent_TagsServer) error
 	// RTT yields the complete rtt timings for all nodes
 	RTT(*AgentEmpty, Agent_RTTServer) error
+	// CreateToken creates a pre-shared token for joining nodes
+	CreateToken(context.Context, *TokenRequest) (*TokenInfo, error)
+	// Tokens streams all pre-shared tokens of this node
+	Tokens(*AgentEmpty, Agent_TokensServer) error
+	// RevokeToken removes a pre-shared token
+	RevokeToken(context.Context, *TokenRevokeRequest) (*TokenResult, error)
+	// PendingJoins streams all join requests waiting for approval
+	PendingJoins(*AgentEmpty, Agent_PendingJoinsServer) error
+	// ApproveJoin approves or rejects a pending join request
+	ApproveJoin(context.Context, *PendingJoinDecision) (*PendingJoinResult, error)
+	// Leases streams all ipam leases of this node
+	Leases(*AgentEmpty, Agent_LeasesServer) error
+	// ReserveLease reserves a mesh ip for a node
+	ReserveLease(context.Context, *LeaseInfo) (*LeaseResult, error)
+	// ReleaseLease removes the lease of a mesh ip
+	ReleaseLease(context.Context, *LeaseInfo) (*LeaseResult, error)
+	// InstallKey installs a serf encryption key on all nodes
+	InstallKey(context.Context, *KeyringRequest) (*KeyringResponse, error)
+	// UseKey makes an installed key the primary serf encryption key on all nodes
+	UseKey(context.Context, *KeyringRequest) (*KeyringResponse, error)
+	// RemoveKey removes a serf encryption key from all nodes
+	RemoveKey(context.Context, *KeyringRequest) (*KeyringResponse, error)
+	// ListKeys lists the serf encryption keys installed on all nodes
+	ListKeys(context.Context, *AgentEmpty) (*KeyringResponse, error)
+	// RotateKey replaces the wireguard key of the local node
+	RotateKey(context.Context, *RotateKeyRequest) (*RotateKeyResult, error)
+	// RotatePresharedKeys replaces the mesh secret for preshared keys on all nodes
+	RotatePresharedKeys(context.Context, *AgentEmpty) (*RotateKeyResult, error)
+	// Routes lists the approved and pending routes of all nodes
+	Routes(context.Context, *AgentEmpty) (*RoutesResult, error)
+	// AdvertiseRoutes announces subnets behind the local node
+	AdvertiseRoutes(context.Context, *RoutesRequest) (*RoutesResult, error)
+	// WithdrawRoutes removes subnets advertised by the local node
+	WithdrawRoutes(context.Context, *RoutesRequest) (*RoutesResult, error)
+	// ApproveRoutes approves pending routes of a node (bootstrap only)
+	ApproveRoutes(context.Context, *RoutesRequest) (*RoutesResult, error)
+	// ExitNodes lists all nodes offering themselves as exit node
+	ExitNodes(context.Context, *AgentEmpty) (*ExitNodeResult, error)
+	// UseExitNode routes default traffic of the local node through an exit node
+	UseExitNode(context.Context, *ExitNodeRequest) (*ExitNodeResult, error)
+	// StopExitNode stops routing default traffic through an exit node
+	StopExitNode(context.Context, *AgentEmpty) (*ExitNodeResult, error)
 	mustEmbedUnimplementedAgentServer()
 }
 
@@ -245,6 +587,69 @@ func (UnimplementedAgentServer) Tags(*AgentEmpty, Agent_TagsServer) error {
 func (UnimplementedAgentServer) RTT(*AgentEmpty, Agent_RTTServer) error {
 	return status.Errorf(codes.Unimplemented, "method RTT not implemented")
 }
+func (UnimplementedAgentServer) CreateToken(context.Context, *TokenRequest) (*TokenInfo, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CreateToken not implemented")
+}
+func (UnimplementedAgentServer) Tokens(*AgentEmpty, Agent_TokensServer) error {
+	return status.Errorf(codes.Unimplemented, "method Tokens not implemented")
+}
+func (UnimplementedAgentServer) RevokeToken(context.Context, *TokenRevokeRequest) (*TokenResult, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RevokeToken not implemented")
+}
+func (UnimplementedAgentServer) PendingJoins(*AgentEmpty, Agent_PendingJoinsServer) error {
+	return status.Errorf(codes.Unimplemented, "method PendingJoins not implemented")
+}
+func (UnimplementedAgentServer) ApproveJoin(context.Context, *PendingJoinDecision) (*PendingJoinResult, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ApproveJoin not implemented")
+}
+func (UnimplementedAgentServer) Leases(*AgentEmpty, Agent_LeasesServer) error {
+	return status.Errorf(codes.Unimplemented, "method Leases not implemented")
+}
+func (UnimplementedAgentServer) ReserveLease(context.Context, *LeaseInfo) (*LeaseResult, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ReserveLease not implemented")
+}
+func (UnimplementedAgentServer) ReleaseLease(context.Context, *LeaseInfo) (*LeaseResult, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ReleaseLease not implemented")
+}
+func (UnimplementedAgentServer) InstallKey(context.Context, *KeyringRequest) (*KeyringResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method InstallKey not implemented")
+}
+func (UnimplementedAgentServer) UseKey(context.Context, *KeyringRequest) (*KeyringResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method UseKey not implemented")
+}
+func (UnimplementedAgentServer) RemoveKey(context.Context, *KeyringRequest) (*KeyringResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RemoveKey not implemented")
+}
+func (UnimplementedAgentServer) ListKeys(context.Context, *AgentEmpty) (*KeyringResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListKeys not implemented")
+}
+func (UnimplementedAgentServer) RotateKey(context.Context, *RotateKeyRequest) (*RotateKeyResult, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RotateKey not implemented")
+}
+func (UnimplementedAgentServer) RotatePresharedKeys(context.Context, *AgentEmpty) (*RotateKeyResult, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RotatePresharedKeys not implemented")
+}
+func (UnimplementedAgentServer) Routes(context.Context, *AgentEmpty) (*RoutesResult, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Routes not implemented")
+}
+func (UnimplementedAgentServer) AdvertiseRoutes(context.Context, *RoutesRequest) (*RoutesResult, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method AdvertiseRoutes not implemented")
+}
+func (UnimplementedAgentServer) WithdrawRoutes(context.Context, *RoutesRequest) (*RoutesResult, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method WithdrawRoutes not implemented")
+}
+func (UnimplementedAgentServer) ApproveRoutes(context.Context, *RoutesRequest) (*RoutesResult, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ApproveRoutes not implemented")
+}
+func (UnimplementedAgentServer) ExitNodes(context.Context, *AgentEmpty) (*ExitNodeResult, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ExitNodes not implemented")
+}
+func (UnimplementedAgentServer) UseExitNode(context.Context, *ExitNodeRequest) (*ExitNodeResult, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method UseExitNode not implemented")
+}
+func (UnimplementedAgentServer) StopExitNode(context.Context, *AgentEmpty) (*ExitNodeResult, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method StopExitNode not implemented")
+}
 func (UnimplementedAgentServer) mustEmbedUnimplementedAgentServer() {}
 
 // UnsafeAgentServer may be embedded to opt out of forward compatibility for this service.
@@ -396,45 +801,519 @@ func (x *agentRTTServer) Send(m *RTTInfo) error {
 	return x.ServerStream.SendMsg(m)
 }
 
-// Agent_ServiceDesc is the grpc.ServiceDesc for Agent service.
-// It's only intended for direct use with grpc.RegisterService,
-// and not to be introspected or modified (even as a copy)
-var Agent_ServiceDesc = grpc.ServiceDesc{
-	ServiceName: "meshservice.Agent",
-	HandlerType: (*AgentServer)(nil),
-	Methods: []grpc.MethodDesc{
-		{
-			MethodName: "Info",
-			Handler:    _Agent_Info_Handler,
-		},
-		{
-			MethodName: "Tag",
-			Handler:    _Agent_Tag_Handler,
-		},
-		{
-			MethodName: "Untag",
-			Handler:    _Agent_Untag_Handler,
-		},
-	},
-	Streams: []grpc.StreamDesc{
-		{
-			StreamName:    "Nodes",
-			Handler:       _Agent_Nodes_Handler,
-			ServerStreams: true,
-		},
-		{
-			StreamName:    "WaitForChangeInMesh",
-			Handler:       _Agent_WaitForChangeInMesh_Handler,
-			ServerStreams: true,
-		},
-		{
-			StreamName:    "Tags",
-			Handler:       _Agent_Tags_Handler,
-			ServerStreams: true,
-		},
-		{
-			StreamName:    "RTT",
-			Handler:       _Agent_RTT_Handler,
+func _Agent_CreateToken_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(TokenRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AgentServer).CreateToken(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/meshservice.Agent/CreateToken",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AgentServer).CreateToken(ctx, req.(*TokenRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Agent_Tokens_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(AgentEmpty)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(AgentServer).Tokens(m, &agentTokensServer{stream})
+}
+
+type Agent_TokensServer interface {
+	Send(*TokenInfo) error
+	grpc.ServerStream
+}
+
+type agentTokensServer struct {
+	grpc.ServerStream
+}
+
+func (x *agentTokensServer) Send(m *TokenInfo) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _Agent_RevokeToken_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(TokenRevokeRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AgentServer).RevokeToken(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/meshservice.Agent/RevokeToken",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AgentServer).RevokeToken(ctx, req.(*TokenRevokeRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Agent_PendingJoins_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(AgentEmpty)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(AgentServer).PendingJoins(m, &agentPendingJoinsServer{stream})
+}
+
+type Agent_PendingJoinsServer interface {
+	Send(*PendingJoinInfo) error
+	grpc.ServerStream
+}
+
+type agentPendingJoinsServer struct {
+	grpc.ServerStream
+}
+
+func (x *agentPendingJoinsServer) Send(m *PendingJoinInfo) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _Agent_ApproveJoin_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PendingJoinDecision)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AgentServer).ApproveJoin(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/meshservice.Agent/ApproveJoin",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AgentServer).ApproveJoin(ctx, req.(*PendingJoinDecision))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Agent_Leases_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(AgentEmpty)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(AgentServer).Leases(m, &agentLeasesServer{stream})
+}
+
+type Agent_LeasesServer interface {
+	Send(*LeaseInfo) error
+	grpc.ServerStream
+}
+
+type agentLeasesServer struct {
+	grpc.ServerStream
+}
+
+func (x *agentLeasesServer) Send(m *LeaseInfo) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _Agent_ReserveLease_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(LeaseInfo)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AgentServer).ReserveLease(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/meshservice.Agent/ReserveLease",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AgentServer).ReserveLease(ctx, req.(*LeaseInfo))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Agent_ReleaseLease_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(LeaseInfo)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AgentServer).ReleaseLease(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/meshservice.Agent/ReleaseLease",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AgentServer).ReleaseLease(ctx, req.(*LeaseInfo))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Agent_InstallKey_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(KeyringRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AgentServer).InstallKey(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/meshservice.Agent/InstallKey",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AgentServer).InstallKey(ctx, req.(*KeyringRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Agent_UseKey_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(KeyringRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AgentServer).UseKey(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/meshservice.Agent/UseKey",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AgentServer).UseKey(ctx, req.(*KeyringRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Agent_RemoveKey_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(KeyringRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AgentServer).RemoveKey(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/meshservice.Agent/RemoveKey",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AgentServer).RemoveKey(ctx, req.(*KeyringRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Agent_ListKeys_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AgentEmpty)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AgentServer).ListKeys(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/meshservice.Agent/ListKeys",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AgentServer).ListKeys(ctx, req.(*AgentEmpty))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Agent_RotateKey_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RotateKeyRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AgentServer).RotateKey(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/meshservice.Agent/RotateKey",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AgentServer).RotateKey(ctx, req.(*RotateKeyRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Agent_RotatePresharedKeys_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AgentEmpty)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AgentServer).RotatePresharedKeys(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/meshservice.Agent/RotatePresharedKeys",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AgentServer).RotatePresharedKeys(ctx, req.(*AgentEmpty))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Agent_Routes_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AgentEmpty)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AgentServer).Routes(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/meshservice.Agent/Routes",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AgentServer).Routes(ctx, req.(*AgentEmpty))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Agent_AdvertiseRoutes_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RoutesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AgentServer).AdvertiseRoutes(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/meshservice.Agent/AdvertiseRoutes",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AgentServer).AdvertiseRoutes(ctx, req.(*RoutesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Agent_WithdrawRoutes_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RoutesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AgentServer).WithdrawRoutes(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/meshservice.Agent/WithdrawRoutes",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AgentServer).WithdrawRoutes(ctx, req.(*RoutesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Agent_ApproveRoutes_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RoutesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AgentServer).ApproveRoutes(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/meshservice.Agent/ApproveRoutes",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AgentServer).ApproveRoutes(ctx, req.(*RoutesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Agent_ExitNodes_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AgentEmpty)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AgentServer).ExitNodes(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/meshservice.Agent/ExitNodes",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AgentServer).ExitNodes(ctx, req.(*AgentEmpty))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Agent_UseExitNode_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ExitNodeRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AgentServer).UseExitNode(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/meshservice.Agent/UseExitNode",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AgentServer).UseExitNode(ctx, req.(*ExitNodeRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Agent_StopExitNode_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AgentEmpty)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AgentServer).StopExitNode(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/meshservice.Agent/StopExitNode",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AgentServer).StopExitNode(ctx, req.(*AgentEmpty))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// Agent_ServiceDesc is the grpc.ServiceDesc for Agent service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var Agent_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "meshservice.Agent",
+	HandlerType: (*AgentServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Info",
+			Handler:    _Agent_Info_Handler,
+		},
+		{
+			MethodName: "Tag",
+			Handler:    _Agent_Tag_Handler,
+		},
+		{
+			MethodName: "Untag",
+			Handler:    _Agent_Untag_Handler,
+		},
+		{
+			MethodName: "CreateToken",
+			Handler:    _Agent_CreateToken_Handler,
+		},
+		{
+			MethodName: "RevokeToken",
+			Handler:    _Agent_RevokeToken_Handler,
+		},
+		{
+			MethodName: "ApproveJoin",
+			Handler:    _Agent_ApproveJoin_Handler,
+		},
+		{
+			MethodName: "ReserveLease",
+			Handler:    _Agent_ReserveLease_Handler,
+		},
+		{
+			MethodName: "ReleaseLease",
+			Handler:    _Agent_ReleaseLease_Handler,
+		},
+		{
+			MethodName: "InstallKey",
+			Handler:    _Agent_InstallKey_Handler,
+		},
+		{
+			MethodName: "UseKey",
+			Handler:    _Agent_UseKey_Handler,
+		},
+		{
+			MethodName: "RemoveKey",
+			Handler:    _Agent_RemoveKey_Handler,
+		},
+		{
+			MethodName: "ListKeys",
+			Handler:    _Agent_ListKeys_Handler,
+		},
+		{
+			MethodName: "RotateKey",
+			Handler:    _Agent_RotateKey_Handler,
+		},
+		{
+			MethodName: "RotatePresharedKeys",
+			Handler:    _Agent_RotatePresharedKeys_Handler,
+		},
+		{
+			MethodName: "Routes",
+			Handler:    _Agent_Routes_Handler,
+		},
+		{
+			MethodName: "AdvertiseRoutes",
+			Handler:    _Agent_AdvertiseRoutes_Handler,
+		},
+		{
+			MethodName: "WithdrawRoutes",
+			Handler:    _Agent_WithdrawRoutes_Handler,
+		},
+		{
+			MethodName: "ApproveRoutes",
+			Handler:    _Agent_ApproveRoutes_Handler,
+		},
+		{
+			MethodName: "ExitNodes",
+			Handler:    _Agent_ExitNodes_Handler,
+		},
+		{
+			MethodName: "UseExitNode",
+			Handler:    _Agent_UseExitNode_Handler,
+		},
+		{
+			MethodName: "StopExitNode",
+			Handler:    _Agent_StopExitNode_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "Nodes",
+			Handler:       _Agent_Nodes_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "WaitForChangeInMesh",
+			Handler:       _Agent_WaitForChangeInMesh_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "Tags",
+			Handler:       _Agent_Tags_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "RTT",
+			Handler:       _Agent_RTT_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "Tokens",
+			Handler:       _Agent_Tokens_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "PendingJoins",
+			Handler:       _Agent_PendingJoins_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "Leases",
+			Handler:       _Agent_Leases_Handler,
 			ServerStreams: true,
 		},
 	},
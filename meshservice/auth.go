@@ -0,0 +1,333 @@
+package meshservice
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/base32"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"golang.org/x/crypto/ssh"
+)
+
+// Authenticator is implemented by authentication methods a bootstrap
+// node may require from joining nodes. The challenge is sent as an
+// auth requirement within the HandshakeResponse, joining nodes answer
+// it in the subject of their join token.
+type Authenticator interface {
+	// Name is the key of the auth requirement
+	Name() string
+
+	// Challenge returns the value of the auth requirement
+	Challenge() (string, error)
+
+	// Verify checks the response of a joining node to given challenge
+	Verify(challenge, response string) error
+}
+
+// AuthResponder is the joining node's counterpart of an Authenticator
+type AuthResponder interface {
+	// Name is the key of the auth requirement this responder answers
+	Name() string
+
+	// Respond computes the response to given challenge
+	Respond(challenge string) (string, error)
+}
+
+const (
+	authNameHMAC   = "hmac"
+	authNameTOTP   = "totp"
+	authNameSSHKey = "sshkey"
+)
+
+func randomNonce() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(b), nil
+}
+
+// parseAuthResponses splits the subject of a join token into
+// a map of auth requirement name -> response
+func parseAuthResponses(subject string) (map[string]string, error) {
+	res := make(map[string]string)
+	if subject == "" {
+		return res, nil
+	}
+	for _, elem := range strings.Split(subject, "::") {
+		arr := strings.SplitN(elem, "=", 2)
+		if len(arr) != 2 {
+			return nil, errors.New("malformed auth response")
+		}
+		res[arr[0]] = arr[1]
+	}
+	return res, nil
+}
+
+// FormatAuthResponse formats a response to be put into the subject of a join token
+func FormatAuthResponse(name, response string) string {
+	return fmt.Sprintf("%s=%s", name, response)
+}
+
+// hmacAuth requires joining nodes to know a pre-shared secret, by
+// sending the HMAC-SHA256 of a random nonce.
+type hmacAuth struct {
+	secret []byte
+}
+
+// NewHMACAuthenticator creates an Authenticator for a pre-shared secret
+func NewHMACAuthenticator(secret string) Authenticator {
+	return &hmacAuth{secret: []byte(secret)}
+}
+
+// NewHMACAuthResponder creates an AuthResponder for a pre-shared secret
+func NewHMACAuthResponder(secret string) AuthResponder {
+	return &hmacAuth{secret: []byte(secret)}
+}
+
+func (a *hmacAuth) Name() string {
+	return authNameHMAC
+}
+
+func (a *hmacAuth) Challenge() (string, error) {
+	return randomNonce()
+}
+
+func (a *hmacAuth) Respond(challenge string) (string, error) {
+	mac := hmac.New(sha256.New, a.secret)
+	mac.Write([]byte(challenge))
+	return base64.StdEncoding.EncodeToString(mac.Sum(nil)), nil
+}
+
+func (a *hmacAuth) Verify(challenge, response string) error {
+	expected, _ := a.Respond(challenge)
+	if !hmac.Equal([]byte(expected), []byte(response)) {
+		return errors.New("hmac of pre-shared secret does not match")
+	}
+	return nil
+}
+
+// totpAuth requires joining nodes to send a time-based one-time
+// password (RFC 6238, 30 second steps, 6 digits) for a shared secret.
+// Each code is accepted once only.
+type totpAuth struct {
+	sync.Mutex
+
+	secret []byte
+	code   string
+
+	// time steps whose code has been used
+	usedSteps map[int64]bool
+}
+
+// NewTOTPAuthenticator creates an Authenticator for a base32-encoded TOTP secret
+func NewTOTPAuthenticator(secretB32 string) (Authenticator, error) {
+	secret, err := decodeTOTPSecret(secretB32)
+	if err != nil {
+		return nil, err
+	}
+	return &totpAuth{secret: secret, usedSteps: make(map[int64]bool)}, nil
+}
+
+// NewTOTPAuthResponder creates an AuthResponder which either computes the
+// code from a base32-encoded secret, or responds with a fixed code (e.g. as
+// entered by a user)
+func NewTOTPAuthResponder(secretB32, code string) (AuthResponder, error) {
+	if code != "" {
+		return &totpAuth{code: code}, nil
+	}
+	secret, err := decodeTOTPSecret(secretB32)
+	if err != nil {
+		return nil, err
+	}
+	return &totpAuth{secret: secret}, nil
+}
+
+func decodeTOTPSecret(secretB32 string) ([]byte, error) {
+	s := strings.TrimRight(strings.ToUpper(strings.ReplaceAll(secretB32, " ", "")), "=")
+	secret, err := base32.StdEncoding.WithPadding(base32.NoPadding).DecodeString(s)
+	if err != nil {
+		return nil, fmt.Errorf("invalid totp secret: %s", err)
+	}
+	return secret, nil
+}
+
+func totpStep(t time.Time) int64 {
+	return t.Unix() / 30
+}
+
+func totpCode(secret []byte, t time.Time) string {
+	return totpCodeOfStep(secret, totpStep(t))
+}
+
+func totpCodeOfStep(secret []byte, step int64) string {
+	buf := make([]byte, 8)
+	binary.BigEndian.PutUint64(buf, uint64(step))
+
+	mac := hmac.New(sha1.New, secret)
+	mac.Write(buf)
+	sum := mac.Sum(nil)
+
+	offset := sum[len(sum)-1] & 0x0f
+	code := (binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff) % 1000000
+
+	return fmt.Sprintf("%06d", code)
+}
+
+func (a *totpAuth) Name() string {
+	return authNameTOTP
+}
+
+func (a *totpAuth) Challenge() (string, error) {
+	return "", nil
+}
+
+func (a *totpAuth) Respond(challenge string) (string, error) {
+	if a.code != "" {
+		return a.code, nil
+	}
+	return totpCode(a.secret, time.Now()), nil
+}
+
+func (a *totpAuth) Verify(challenge, response string) error {
+	return a.verifyAt(time.Now(), response)
+}
+
+// verifyAt checks the code for given time. Codes from the previous and
+// next time step are accepted as well, but none of them twice.
+func (a *totpAuth) verifyAt(t time.Time, response string) error {
+	a.Lock()
+	defer a.Unlock()
+
+	now := totpStep(t)
+	for step := range a.usedSteps {
+		if step < now-1 {
+			delete(a.usedSteps, step)
+		}
+	}
+
+	for _, step := range []int64{now, now - 1, now + 1} {
+		if !hmac.Equal([]byte(totpCodeOfStep(a.secret, step)), []byte(response)) {
+			continue
+		}
+		if a.usedSteps[step] {
+			return errors.New("totp code has already been used")
+		}
+		a.usedSteps[step] = true
+		return nil
+	}
+	return errors.New("invalid totp code")
+}
+
+// sshKeyAuth requires joining nodes to sign a random nonce with
+// a private key whose public key is in an authorized_keys file.
+// All key types supported by ssh are accepted, e.g. ed25519.
+type sshKeyAuth struct {
+	authorizedKeys []ssh.PublicKey
+	signer         ssh.Signer
+}
+
+// NewSSHKeyAuthenticator creates an Authenticator from an authorized_keys
+// file. Lines which cannot be parsed are skipped with a warning.
+func NewSSHKeyAuthenticator(authorizedKeysFile string) (Authenticator, error) {
+	b, err := ioutil.ReadFile(authorizedKeysFile)
+	if err != nil {
+		return nil, err
+	}
+
+	a := &sshKeyAuth{
+		authorizedKeys: make([]ssh.PublicKey, 0),
+	}
+	for idx, line := range strings.Split(string(b), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		pubKey, _, _, _, err := ssh.ParseAuthorizedKey([]byte(line))
+		if err != nil {
+			log.WithError(err).WithFields(log.Fields{
+				"file": authorizedKeysFile,
+				"line": idx + 1,
+			}).Warn("Skipping invalid authorized key")
+			continue
+		}
+		a.authorizedKeys = append(a.authorizedKeys, pubKey)
+	}
+	if len(a.authorizedKeys) == 0 {
+		return nil, fmt.Errorf("no keys found in %s", authorizedKeysFile)
+	}
+
+	return a, nil
+}
+
+// NewSSHKeyAuthResponder creates an AuthResponder from an (unencrypted) private key file
+func NewSSHKeyAuthResponder(privateKeyFile string) (AuthResponder, error) {
+	b, err := ioutil.ReadFile(privateKeyFile)
+	if err != nil {
+		return nil, err
+	}
+	signer, err := ssh.ParsePrivateKey(b)
+	if err != nil {
+		return nil, err
+	}
+	return &sshKeyAuth{signer: signer}, nil
+}
+
+func (a *sshKeyAuth) Name() string {
+	return authNameSSHKey
+}
+
+func (a *sshKeyAuth) Challenge() (string, error) {
+	return randomNonce()
+}
+
+// Respond signs the challenge. The response consists of the public
+// key and the signature, so the bootstrap node does not need to try
+// all authorized keys.
+func (a *sshKeyAuth) Respond(challenge string) (string, error) {
+	sig, err := a.signer.Sign(rand.Reader, []byte(challenge))
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s.%s",
+		base64.StdEncoding.EncodeToString(a.signer.PublicKey().Marshal()),
+		base64.StdEncoding.EncodeToString(ssh.Marshal(sig))), nil
+}
+
+func (a *sshKeyAuth) Verify(challenge, response string) error {
+	arr := strings.Split(response, ".")
+	if len(arr) != 2 {
+		return errors.New("malformed signature response")
+	}
+	pubKeyBytes, err := base64.StdEncoding.DecodeString(arr[0])
+	if err != nil {
+		return err
+	}
+	sigBytes, err := base64.StdEncoding.DecodeString(arr[1])
+	if err != nil {
+		return err
+	}
+
+	for _, authorizedKey := range a.authorizedKeys {
+		if !hmac.Equal(authorizedKey.Marshal(), pubKeyBytes) {
+			continue
+		}
+		sig := &ssh.Signature{}
+		if err := ssh.Unmarshal(sigBytes, sig); err != nil {
+			return err
+		}
+		return authorizedKey.Verify([]byte(challenge), sig)
+	}
+
+	return errors.New("key is not authorized")
+}
@@ -0,0 +1,180 @@
+package meshservice
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base32"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+)
+
+func TestHMACAuth(t *testing.T) {
+	a := NewHMACAuthenticator("secret")
+	challenge, err := a.Challenge()
+	if err != nil {
+		t.Fatalf("unable to create challenge: %s", err)
+	}
+
+	tests := []struct {
+		secret string
+		ok     bool
+	}{
+		{"secret", true},
+		{"other", false},
+		{"", false},
+	}
+
+	for _, tt := range tests {
+		response, _ := NewHMACAuthResponder(tt.secret).Respond(challenge)
+		if err := a.Verify(challenge, response); (err == nil) != tt.ok {
+			t.Errorf("secret %q: verified is %t, want %t", tt.secret, err == nil, tt.ok)
+		}
+	}
+
+	// responses are bound to the challenge
+	response, _ := NewHMACAuthResponder("secret").Respond(challenge)
+	if err := a.Verify(challenge+"x", response); err == nil {
+		t.Errorf("response verified for another challenge")
+	}
+}
+
+func TestTOTPCode(t *testing.T) {
+	// test vectors of RFC 6238 (SHA1), truncated to 6 digits
+	secret := []byte("12345678901234567890")
+	tests := []struct {
+		ts   int64
+		code string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+	}
+
+	for _, tt := range tests {
+		if code := totpCode(secret, time.Unix(tt.ts, 0)); code != tt.code {
+			t.Errorf("%d: code is %s, want %s", tt.ts, code, tt.code)
+		}
+	}
+}
+
+func TestTOTPAuth(t *testing.T) {
+	secret := []byte("12345678901234567890")
+	a, err := NewTOTPAuthenticator(base32.StdEncoding.EncodeToString(secret))
+	if err != nil {
+		t.Fatalf("unable to create authenticator: %s", err)
+	}
+	totp := a.(*totpAuth)
+	now := time.Unix(1234567890, 0)
+
+	tests := []struct {
+		name string
+		code string
+		ok   bool
+	}{
+		{"current", totpCode(secret, now), true},
+		{"replayed", totpCode(secret, now), false},
+		{"previous", totpCode(secret, now.Add(-30*time.Second)), true},
+		{"next", totpCode(secret, now.Add(30*time.Second)), true},
+		{"replayed next", totpCode(secret, now.Add(30*time.Second)), false},
+		{"too old", totpCode(secret, now.Add(-60*time.Second)), false},
+		{"too new", totpCode(secret, now.Add(60*time.Second)), false},
+		{"invalid", "abcdef", false},
+	}
+
+	for _, tt := range tests {
+		if err := totp.verifyAt(now, tt.code); (err == nil) != tt.ok {
+			t.Errorf("%s: verified is %t, want %t (%v)", tt.name, err == nil, tt.ok, err)
+		}
+	}
+
+	// a fixed code is sent as is
+	r, err := NewTOTPAuthResponder("", "123456")
+	if err != nil {
+		t.Fatalf("unable to create responder: %s", err)
+	}
+	if code, _ := r.Respond(""); code != "123456" {
+		t.Errorf("fixed code responder sent %s", code)
+	}
+}
+
+func newTestSSHSigner(t *testing.T) ssh.Signer {
+	_, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("unable to generate key: %s", err)
+	}
+	signer, err := ssh.NewSignerFromKey(priv)
+	if err != nil {
+		t.Fatalf("unable to create signer: %s", err)
+	}
+	return signer
+}
+
+func TestSSHKeyAuth(t *testing.T) {
+	authorized := newTestSSHSigner(t)
+	other := newTestSSHSigner(t)
+	afterInvalid := newTestSSHSigner(t)
+
+	// invalid lines are skipped, keys after them still count
+	path := filepath.Join(t.TempDir(), "authorized_keys")
+	lines := []string{
+		"# comment",
+		"",
+		strings.TrimSpace(string(ssh.MarshalAuthorizedKey(authorized.PublicKey()))) + " user@host",
+		"ssh-ed25519 invalid",
+		strings.TrimSpace(string(ssh.MarshalAuthorizedKey(afterInvalid.PublicKey()))),
+	}
+	if err := ioutil.WriteFile(path, []byte(strings.Join(lines, "\n")), 0600); err != nil {
+		t.Fatalf("unable to write authorized keys: %s", err)
+	}
+	a, err := NewSSHKeyAuthenticator(path)
+	if err != nil {
+		t.Fatalf("unable to create authenticator: %s", err)
+	}
+	challenge, err := a.Challenge()
+	if err != nil {
+		t.Fatalf("unable to create challenge: %s", err)
+	}
+
+	tests := []struct {
+		name   string
+		signer ssh.Signer
+		ok     bool
+	}{
+		{"authorized", authorized, true},
+		{"after invalid line", afterInvalid, true},
+		{"unauthorized", other, false},
+	}
+
+	for _, tt := range tests {
+		response, err := (&sshKeyAuth{signer: tt.signer}).Respond(challenge)
+		if err != nil {
+			t.Fatalf("%s: unable to respond: %s", tt.name, err)
+		}
+		if err := a.Verify(challenge, response); (err == nil) != tt.ok {
+			t.Errorf("%s: verified is %t, want %t (%v)", tt.name, err == nil, tt.ok, err)
+		}
+	}
+
+	// signatures are bound to the challenge
+	response, _ := (&sshKeyAuth{signer: authorized}).Respond(challenge)
+	if err := a.Verify(challenge+"x", response); err == nil {
+		t.Errorf("signature verified for another challenge")
+	}
+	if err := a.Verify(challenge, "malformed"); err == nil {
+		t.Errorf("malformed response verified")
+	}
+
+	// files without any valid key are refused
+	if err := ioutil.WriteFile(path, []byte("ssh-ed25519 invalid\n"), 0600); err != nil {
+		t.Fatalf("unable to write authorized keys: %s", err)
+	}
+	if _, err := NewSSHKeyAuthenticator(path); err == nil {
+		t.Errorf("authorized keys without valid keys accepted")
+	}
+}
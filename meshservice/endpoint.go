@@ -0,0 +1,70 @@
+package meshservice
+
+import (
+	"net"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// EndpointDetector determines the current external wireguard ip of this
+// node, and whether it is behind NAT
+type EndpointDetector func() (net.IP, bool, error)
+
+// StartEndpointUpdater periodically re-detects the external wireguard ip
+// of this node. When it changed, e.g. after a DHCP renewal or when roaming
+// to another network, the _addr and _nat tags are updated, so that all
+// other nodes reconfigure their peer entry for this node.
+func (ms *MeshService) StartEndpointUpdater(interval time.Duration, detect EndpointDetector) {
+	ticker := time.NewTicker(interval)
+
+	go func() {
+		for range ticker.C {
+			ip, nat, err := detect()
+			if err != nil {
+				log.WithError(err).Warn("Unable to detect external ip")
+				continue
+			}
+			if ip.Equal(ms.WireguardListenIP) && nat == ms.BehindNAT {
+				continue
+			}
+
+			if err = ms.updateEndpointIP(ip, nat); err != nil {
+				log.WithError(err).Error("Unable to announce new external ip")
+				continue
+			}
+			log.WithFields(log.Fields{
+				"ip":  ip,
+				"nat": nat,
+			}).Info("External ip changed, announced new endpoint")
+		}
+	}()
+}
+
+// updateEndpointIP sets the _addr tag of this node to ip, and the
+// _nat tag if it is behind NAT. Keepalives to peers are adjusted
+// when this node went behind NAT or left it.
+func (ms *MeshService) updateEndpointIP(ip net.IP, nat bool) error {
+	err := ms.updateTags(func(tags map[string]string) error {
+		tags[nodeTagAddr] = ip.String()
+		if nat {
+			tags[nodeTagNAT] = "1"
+		} else {
+			delete(tags, nodeTagNAT)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	ms.WireguardListenIP = ip
+	if nat != ms.BehindNAT {
+		ms.BehindNAT = nat
+		if ms.Relay && nat {
+			log.Warn("This node is a relay but behind NAT, other nodes behind NAT may not be able to reach it")
+		}
+		ms.reconcilePeers()
+	}
+	return nil
+}
@@ -0,0 +1,248 @@
+package meshservice
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net"
+	"os/exec"
+	"sort"
+	"strconv"
+	"sync"
+
+	wgwrapper "github.com/aschmidt75/go-wg-wrapper/pkg/wgwrapper"
+	serf "github.com/hashicorp/serf/serf"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// routing table and firewall mark for default traffic through an exit
+	// node. Wireguard marks its own (encrypted) packets, so that they do not
+	// match the rule and still go out the underlay.
+	exitNodeRouteTable = 51830
+
+	// priority of the ip rules sending default traffic to the exit node.
+	// The rule before makes more specific routes of the main table win.
+	exitNodeRulePriority = 31830
+)
+
+// exitNodeState keeps the exit node in use by this node, and
+// whether this node offers itself as exit node
+type exitNodeState struct {
+	sync.Mutex
+
+	// name of the node default traffic is routed through
+	nodeName string
+
+	// underlay interface masquerading traffic of the mesh, if offered
+	masqueradeIntf string
+}
+
+// exitNodeDefaultRoute is added to the allowed ips of the exit node in use
+var exitNodeDefaultRoute = net.IPNet{
+	IP:   net.IPv4zero,
+	Mask: net.CIDRMask(0, 32),
+}
+
+// ExitNode returns the name of the exit node in use, or an empty string
+func (ms *MeshService) ExitNode() string {
+	ms.exitNode.Lock()
+	defer ms.exitNode.Unlock()
+
+	return ms.exitNode.nodeName
+}
+
+// ExitNodes returns the names of all alive nodes offering themselves as exit node
+func (ms *MeshService) ExitNodes() []string {
+	res := make([]string, 0)
+	for _, member := range ms.Serf().Members() {
+		if member.Status == serf.StatusAlive && member.Tags[nodeTagExitNode] == "1" {
+			res = append(res, member.Name)
+		}
+	}
+	sort.Strings(res)
+	return res
+}
+
+// OfferExitNode makes this node an exit node: it forwards traffic of
+// the mesh, masquerading it on the interface of the default route.
+// This node is announced in the _exit tag by StartSerfCluster.
+func (ms *MeshService) OfferExitNode() error {
+	wg := wgwrapper.New()
+	intf, err := wg.DefaultRouteInterface()
+	if err != nil {
+		return err
+	}
+
+	if err = ms.EnableForwarding(); err != nil {
+		return err
+	}
+	if err = execCommand("iptables", "-t", "nat", "-A", "POSTROUTING", "-s", ms.CIDRRange.String(), "-o", intf, "-j", "MASQUERADE"); err != nil {
+		return err
+	}
+
+	ms.exitNode.Lock()
+	ms.exitNode.masqueradeIntf = intf
+	ms.exitNode.Unlock()
+
+	log.WithField("intf", intf).Info("Offering this node as exit node")
+	return nil
+}
+
+// StopOfferingExitNode removes the masquerading rule of an exit node
+func (ms *MeshService) StopOfferingExitNode() {
+	ms.exitNode.Lock()
+	defer ms.exitNode.Unlock()
+
+	if ms.exitNode.masqueradeIntf == "" {
+		return
+	}
+	if err := execCommand("iptables", "-t", "nat", "-D", "POSTROUTING", "-s", ms.CIDRRange.String(), "-o", ms.exitNode.masqueradeIntf, "-j", "MASQUERADE"); err != nil {
+		log.WithError(err).Error("Unable to remove masquerading rule of exit node")
+	}
+	ms.exitNode.masqueradeIntf = ""
+}
+
+// UseExitNode routes all default (ipv4) traffic of this node through
+// the given exit node. The exit node gets 0.0.0.0/0 as allowed ips,
+// and policy routing sends all traffic not marked by wireguard itself
+// to the wireguard interface, so that the encrypted packets still go
+// out the underlay. A previously used exit node is replaced.
+func (ms *MeshService) UseExitNode(nodeName string) error {
+	if nodeName == ms.NodeName {
+		return errors.New("this node cannot be its own exit node")
+	}
+
+	var member *serf.Member
+	for _, m := range ms.Serf().Members() {
+		if m.Name == nodeName && m.Status == serf.StatusAlive {
+			member = &m
+			break
+		}
+	}
+	if member == nil {
+		return fmt.Errorf("no alive node %s", nodeName)
+	}
+	if member.Tags[nodeTagExitNode] != "1" {
+		return fmt.Errorf("node %s does not offer itself as exit node", nodeName)
+	}
+
+	ms.exitNode.Lock()
+	prev := ms.exitNode.nodeName
+	ms.exitNode.nodeName = nodeName
+	ms.exitNode.Unlock()
+
+	// move the default route to the new exit node. If
+	// there was none before, set up policy routing.
+	if err := ms.updatePeerAllowedIPs(memberPeer(*member).Pubkey); err != nil {
+		ms.StopUsingExitNode()
+		return err
+	}
+	if prev != "" {
+		log.WithFields(log.Fields{
+			"prev": prev,
+			"node": nodeName,
+		}).Info("Switched exit node")
+		return nil
+	}
+
+	if err := ms.setExitNodeRouting(); err != nil {
+		ms.StopUsingExitNode()
+		return err
+	}
+
+	log.WithField("node", nodeName).Info("Routing default traffic through exit node")
+	return nil
+}
+
+// setExitNodeRouting installs the policy routing for an exit node
+func (ms *MeshService) setExitNodeRouting() error {
+	intf := ms.WireguardInterface.InterfaceName
+	table := strconv.Itoa(exitNodeRouteTable)
+
+	cmds := [][]string{
+		{"wg", "set", intf, "fwmark", table},
+		{"ip", "route", "replace", "default", "dev", intf, "table", table},
+		{"ip", "rule", "add", "table", "main", "suppress_prefixlength", "0", "priority", strconv.Itoa(exitNodeRulePriority - 1)},
+		{"ip", "rule", "add", "not", "fwmark", table, "table", table, "priority", strconv.Itoa(exitNodeRulePriority)},
+	}
+	for _, cmd := range cmds {
+		if err := execCommand(cmd[0], cmd[1:]...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// StopUsingExitNode removes the policy routing and the default route from
+// the allowed ips of the exit node, so that default traffic goes out the
+// underlay again. Does nothing if no exit node is in use.
+func (ms *MeshService) StopUsingExitNode() {
+	ms.exitNode.Lock()
+	nodeName := ms.exitNode.nodeName
+	ms.exitNode.nodeName = ""
+	ms.exitNode.Unlock()
+
+	if nodeName == "" {
+		return
+	}
+
+	intf := ms.WireguardInterface.InterfaceName
+	table := strconv.Itoa(exitNodeRouteTable)
+
+	// all steps are tried, so that as much as possible is restored
+	cmds := [][]string{
+		{"ip", "rule", "del", "not", "fwmark", table, "table", table, "priority", strconv.Itoa(exitNodeRulePriority)},
+		{"ip", "rule", "del", "table", "main", "suppress_prefixlength", "0", "priority", strconv.Itoa(exitNodeRulePriority - 1)},
+		{"ip", "route", "flush", "table", table},
+		{"wg", "set", intf, "fwmark", "off"},
+	}
+	for _, cmd := range cmds {
+		if err := execCommand(cmd[0], cmd[1:]...); err != nil {
+			log.WithError(err).WithField("cmd", cmd).Warn("Unable to restore routing after using exit node")
+		}
+	}
+
+	if ms.Serf() != nil {
+		for _, member := range ms.Serf().Members() {
+			if member.Name != nodeName {
+				continue
+			}
+			if err := ms.updatePeerAllowedIPs(memberPeer(member).Pubkey); err != nil {
+				log.WithError(err).Warn("Unable to remove default route from exit node")
+			}
+		}
+	}
+
+	log.WithField("node", nodeName).Info("Stopped routing default traffic through exit node")
+}
+
+// checkExitNode stops using the exit node if it is no
+// longer alive or does not offer itself as exit node anymore
+func (ms *MeshService) checkExitNode(members []serf.Member) {
+	nodeName := ms.ExitNode()
+	if nodeName == "" {
+		return
+	}
+	for _, member := range members {
+		if member.Name != nodeName {
+			continue
+		}
+		if member.Status != serf.StatusAlive || member.Tags[nodeTagExitNode] != "1" {
+			log.WithField("node", nodeName).Warn("Exit node is gone")
+			ms.StopUsingExitNode()
+		}
+	}
+}
+
+// execCommand runs a command, logging its error output
+func execCommand(name string, args ...string) error {
+	cmd := exec.Command(name, args...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		log.WithField("stderr", stderr.String()).Errorf("%s reported an error", name)
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,130 @@
+package meshservice
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// GroupPolicy tells which groups the nodes of a group peer with, by group.
+// Groups not in the policy peer with all groups. Nodes without a group,
+// e.g. bootstrap nodes, peer with all nodes, regardless of the policy.
+type GroupPolicy map[string][]string
+
+// ParseGroupPolicy parses a policy of the form "<group>:<group>[+<group>...],...",
+// e.g. "edge:core" for edge nodes peering only with core nodes. * allows all groups.
+func ParseGroupPolicy(s string) (GroupPolicy, error) {
+	res := make(GroupPolicy)
+	if strings.TrimSpace(s) == "" {
+		return res, nil
+	}
+
+	for _, entry := range strings.Split(s, ",") {
+		arr := strings.SplitN(strings.TrimSpace(entry), ":", 2)
+		if len(arr) != 2 || ValidateGroupName(arr[0]) != nil {
+			return nil, fmt.Errorf("invalid group policy entry '%s', use <group>:<group>[+<group>...]", entry)
+		}
+		if _, ok := res[arr[0]]; ok {
+			return nil, fmt.Errorf("duplicate group policy entry for group %s", arr[0])
+		}
+
+		allowed := make([]string, 0)
+		for _, g := range strings.Split(arr[1], "+") {
+			if g != "*" && ValidateGroupName(g) != nil {
+				return nil, fmt.Errorf("invalid group '%s' in group policy entry '%s'", g, entry)
+			}
+			allowed = append(allowed, g)
+		}
+		res[arr[0]] = allowed
+	}
+	return res, nil
+}
+
+// ValidateGroupName checks that a group name can be used in a group policy
+func ValidateGroupName(group string) error {
+	if group == "" || strings.ContainsAny(group, ",:+* ") {
+		return fmt.Errorf("invalid group name '%s'", group)
+	}
+	return nil
+}
+
+// String formats the policy, so that it can be parsed again
+func (p GroupPolicy) String() string {
+	entries := make([]string, 0, len(p))
+	for group, allowed := range p {
+		entries = append(entries, fmt.Sprintf("%s:%s", group, strings.Join(allowed, "+")))
+	}
+	sort.Strings(entries)
+	return strings.Join(entries, ",")
+}
+
+// allows tells whether nodes of group a may peer with nodes of group b
+func (p GroupPolicy) allows(a, b string) bool {
+	if a == "" || b == "" {
+		return true
+	}
+	allowed, ok := p[a]
+	if !ok {
+		return true
+	}
+	for _, g := range allowed {
+		if g == "*" || g == b {
+			return true
+		}
+	}
+	return false
+}
+
+// PeeringAllowed tells whether nodes of both groups peer with each other.
+// Both groups must allow the other one, so that peerings are symmetric.
+func (p GroupPolicy) PeeringAllowed(a, b string) bool {
+	return p.allows(a, b) && p.allows(b, a)
+}
+
+// isolates tells whether the policy keeps some groups from peering with
+// each other. Groups which allow all others do not isolate anything.
+func (p GroupPolicy) isolates() bool {
+	for _, allowed := range p {
+		if !containsString(allowed, "*") {
+			return true
+		}
+	}
+	return false
+}
+
+// SetGroupPolicy sets the group policy of the mesh
+func (ms *MeshService) SetGroupPolicy(s string) error {
+	p, err := ParseGroupPolicy(s)
+	if err != nil {
+		return err
+	}
+	ms.groupPolicy = p
+	return nil
+}
+
+// GroupPolicy returns the group policy of the mesh
+func (ms *MeshService) GroupPolicy() string {
+	return ms.groupPolicy.String()
+}
+
+// isolatedPruneDelay is the time failed members stay in the mesh before
+// they are pruned, when the group policy isolates some groups
+const isolatedPruneDelay = 5 * time.Minute
+
+// failedPruneDelay returns the time failed members keep their place in
+// the mesh before being pruned. Serf probes all members, also those of
+// isolated groups which cannot reach each other directly. Such members
+// may be reported as failed until serf refutes it through other nodes,
+// so with an isolating policy pruning is put off by isolatedPruneDelay.
+func (ms *MeshService) failedPruneDelay() time.Duration {
+	if ms.groupPolicy.isolates() {
+		return isolatedPruneDelay
+	}
+	return 0
+}
+
+// peersWith tells whether this node peers with nodes of given group
+func (ms *MeshService) peersWith(group string) bool {
+	return ms.groupPolicy.PeeringAllowed(ms.Group, group)
+}
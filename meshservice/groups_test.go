@@ -0,0 +1,85 @@
+package meshservice
+
+import (
+	"testing"
+)
+
+func TestParseGroupPolicy(t *testing.T) {
+	tests := []struct {
+		policy string
+		valid  bool
+		want   string
+	}{
+		{"", true, ""},
+		{"edge:core", true, "edge:core"},
+		{"edge:core, core:*", true, "core:*,edge:core"},
+		{"edge:core+db", true, "edge:core+db"},
+		{"edge", false, ""},
+		{":core", false, ""},
+		{"edge:", false, ""},
+		{"edge:core,edge:db", false, ""},
+		{"ed ge:core", false, ""},
+		{"*:core", false, ""},
+	}
+
+	for _, tt := range tests {
+		p, err := ParseGroupPolicy(tt.policy)
+		if (err == nil) != tt.valid {
+			t.Errorf("%q: valid is %t, want %t (%v)", tt.policy, err == nil, tt.valid, err)
+			continue
+		}
+		if err == nil && p.String() != tt.want {
+			t.Errorf("%q: parsed as %q, want %q", tt.policy, p.String(), tt.want)
+		}
+	}
+}
+
+func TestPeeringAllowed(t *testing.T) {
+	tests := []struct {
+		policy   string
+		a, b     string
+		allowed  bool
+		isolates bool
+	}{
+		// without a policy, all nodes peer
+		{"", "edge", "edge", true, false},
+		{"", "", "edge", true, false},
+
+		// hub and spoke
+		{"edge:core,core:*", "edge", "core", true, true},
+		{"edge:core,core:*", "core", "edge", true, true},
+		{"edge:core,core:*", "edge", "edge", false, true},
+		{"edge:core,core:*", "core", "core", true, true},
+
+		// groups not in the policy peer with all groups, unless
+		// the other group restricts them
+		{"edge:core,core:*", "db", "core", true, true},
+		{"edge:core,core:*", "db", "edge", false, true},
+		{"edge:core,core:*", "db", "db", true, true},
+
+		// nodes without a group peer with all nodes
+		{"edge:core,core:*", "", "edge", true, true},
+		{"edge:core,core:*", "edge", "", true, true},
+		{"edge:core,core:*", "", "", true, true},
+
+		// peerings must be allowed in both directions
+		{"edge:core,core:db", "edge", "core", false, true},
+		{"edge:core+db,db:edge", "edge", "db", true, true},
+
+		// allowing all groups does not isolate anything
+		{"core:*,edge:*", "edge", "core", true, false},
+	}
+
+	for _, tt := range tests {
+		p, err := ParseGroupPolicy(tt.policy)
+		if err != nil {
+			t.Fatalf("%q: unexpected error %s", tt.policy, err)
+		}
+		if got := p.PeeringAllowed(tt.a, tt.b); got != tt.allowed {
+			t.Errorf("%q: peering of %q and %q is %t, want %t", tt.policy, tt.a, tt.b, got, tt.allowed)
+		}
+		if got := p.isolates(); got != tt.isolates {
+			t.Errorf("%q: isolates is %t, want %t", tt.policy, got, tt.isolates)
+		}
+	}
+}
@@ -5,10 +5,11 @@ import (
 	"crypto/tls"
 	"encoding/json"
 	"errors"
-	"math/rand"
+	"fmt"
 	"net"
 	"strconv"
 	"strings"
+	"time"
 
 	wgwrapper "github.com/aschmidt75/go-wg-wrapper/pkg/wgwrapper"
 	"github.com/cristalhq/jwt/v3"
@@ -16,6 +17,7 @@ import (
 	grpc "google.golang.org/grpc"
 	"google.golang.org/grpc/credentials"
 	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
 	"google.golang.org/protobuf/proto"
 )
 
@@ -30,48 +32,193 @@ func (ms *MeshService) Begin(ctx context.Context, req *HandshakeRequest) (*Hands
 		}, nil
 	}
 
+	// check the pre-shared token if one is presented
+	var presharedTokenID string
+	if req.Token != "" {
+		var err error
+		presharedTokenID, err = ms.presharedTokens.lookup(req.Token)
+		if err != nil {
+			log.WithError(err).Warn("Rejecting handshake with invalid token")
+			return &HandshakeResponse{
+				Result:       HandshakeResponse_ERROR,
+				ErrorMessage: "Invalid token",
+			}, nil
+		}
+	} else if ms.RequireToken {
+		return &HandshakeResponse{
+			Result:       HandshakeResponse_ERROR,
+			ErrorMessage: "A token is required to join this mesh",
+		}, nil
+	}
+
+	// every configured authenticator publishes its challenge
+	authReqs := make(map[string]string)
+	for _, a := range ms.Authenticators {
+		challenge, err := a.Challenge()
+		if err != nil {
+			log.WithError(err).WithField("auth", a.Name()).Error("unable to create auth challenge")
+			return &HandshakeResponse{
+				Result:       HandshakeResponse_ERROR,
+				ErrorMessage: "internal error while creating auth requirements",
+			}, nil
+		}
+		authReqs[a.Name()] = challenge
+	}
+
+	token, err := ms.joinTokens.issue(authReqs, presharedTokenID)
+	if err != nil {
+		log.WithError(err).Error("unable to issue join token")
+		return &HandshakeResponse{
+			Result:       HandshakeResponse_ERROR,
+			ErrorMessage: "internal error while issuing join token",
+		}, nil
+	}
+
 	return &HandshakeResponse{
 		Result:    HandshakeResponse_OK,
-		JoinToken: randomTokenAsString(),
+		JoinToken: token,
+		AuthReqs:  authReqs,
 	}, nil
 }
 
-func (ms *MeshService) parseJWT(md metadata.MD) error {
+func (ms *MeshService) parseJWT(md metadata.MD) (joinToken, error) {
 	log.WithField("md", md).Trace("parseJWT")
 	if t, ok := md["authorization"]; ok {
 		for _, value := range t {
 			if strings.HasPrefix(value, "Bearer: ") {
 				arr := strings.Split(value, " ")
 				if len(arr) > 1 {
-					tokenStr := arr[1]
-
-					key := []byte(`secret`)
-					verifier, err := jwt.NewVerifierHS(jwt.HS256, key)
-					if err != nil {
-						return err
-					}
-					token, err := jwt.ParseAndVerifyString(tokenStr, verifier)
-					if err != nil {
-						log.WithError(err).Error("Unable to parse/verify join token")
-						return err
-					}
-					var claims jwt.StandardClaims
-					err = json.Unmarshal(token.RawClaims(), &claims)
-					if err != nil {
-						log.WithError(err).Error("Unable to parse/verify join token claims")
-						return err
-					}
-
-					// TODO check claims, check authn/authz requirements
-					// log.WithField("claims", claims).Debug("Verified handshake token claims")
-
-					return nil
+					return ms.verifyJWT(arr[1])
 				}
 			}
 		}
 	}
 
-	return errors.New("authorization header not found or not valid")
+	return joinToken{}, errors.New("authorization header not found or not valid")
+}
+
+// verifyJWT checks that the token has been signed with a join token
+// previously issued by Begin, and validates its claims. Returns the
+// join token.
+func (ms *MeshService) verifyJWT(tokenStr string) (joinToken, error) {
+	// the jti claim tells us which join token has been used for signing.
+	token, err := jwt.ParseString(tokenStr)
+	if err != nil {
+		log.WithError(err).Error("Unable to parse join token")
+		return joinToken{}, err
+	}
+	var claims jwt.StandardClaims
+	err = json.Unmarshal(token.RawClaims(), &claims)
+	if err != nil {
+		log.WithError(err).Error("Unable to parse join token claims")
+		return joinToken{}, err
+	}
+
+	jt, err := ms.joinTokens.take(claims.ID)
+	if err != nil {
+		log.WithError(err).Error("Unable to verify join token")
+		return joinToken{}, err
+	}
+
+	verifier, err := jwt.NewVerifierHS(jwt.HS256, []byte(jt.token))
+	if err != nil {
+		return joinToken{}, err
+	}
+	_, err = jwt.ParseAndVerifyString(tokenStr, verifier)
+	if err != nil {
+		log.WithError(err).Error("Unable to parse/verify join token")
+		return joinToken{}, err
+	}
+
+	if claims.IssuedAt == nil || claims.NotBefore == nil || claims.ExpiresAt == nil {
+		return joinToken{}, errors.New("join token is missing iat, nbf or exp claims")
+	}
+	now := time.Now()
+	if !claims.IsValidIssuedAt(now.Add(jwtClockSkew)) || !claims.IsValidNotBefore(now.Add(jwtClockSkew)) {
+		return joinToken{}, errors.New("join token is not yet valid")
+	}
+	if !claims.IsValidExpiresAt(now.Add(-jwtClockSkew)) {
+		return joinToken{}, errors.New("join token has expired")
+	}
+	if claims.ExpiresAt.Sub(claims.IssuedAt.Time) > joinTokenTTL {
+		return joinToken{}, errors.New("join token has a lifetime that is too long")
+	}
+
+	// the subject carries one response for each of the auth
+	// requirements sent along with the join token
+	authResponses, err := parseAuthResponses(claims.Subject)
+	if err != nil {
+		return joinToken{}, err
+	}
+	if len(authResponses) != len(jt.authReqs) {
+		return joinToken{}, errors.New("join token does not answer all auth requirements")
+	}
+	for _, a := range ms.Authenticators {
+		challenge, ok := jt.authReqs[a.Name()]
+		if !ok {
+			continue
+		}
+		response, ok := authResponses[a.Name()]
+		if !ok {
+			return joinToken{}, fmt.Errorf("join token does not answer auth requirement %s", a.Name())
+		}
+		if err := a.Verify(challenge, response); err != nil {
+			log.WithError(err).WithField("auth", a.Name()).Warn("Auth requirement not met")
+			return joinToken{}, fmt.Errorf("auth requirement %s not met", a.Name())
+		}
+	}
+	log.WithField("claims", claims).Debug("Verified handshake token claims")
+
+	return jt, nil
+}
+
+// waitForApproval blocks until the join request has been approved
+// or rejected by an operator, or the joining node gave up. Returns
+// nil if the request has been approved, an error response otherwise.
+func (ms *MeshService) waitForApproval(ctx context.Context, req *JoinRequest, nodeName string) *JoinResponse {
+	certSubject := ""
+	if p, ok := peer.FromContext(ctx); ok {
+		if tlsInfo, ok := p.AuthInfo.(credentials.TLSInfo); ok && len(tlsInfo.State.PeerCertificates) > 0 {
+			certSubject = tlsInfo.State.PeerCertificates[0].Subject.String()
+		}
+	}
+
+	pj, err := ms.pendingJoins.add(req.Pubkey, req.EndpointIP, int(req.EndpointPort), nodeName, certSubject)
+	if err != nil {
+		log.WithError(err).Error("unable to park join request")
+		return &JoinResponse{
+			Result:            JoinResponse_ERROR,
+			ErrorMessage:      "internal error while waiting for approval",
+			JoiningNodeMeshIP: "",
+		}
+	}
+	log.WithFields(log.Fields{
+		"id":       pj.id,
+		"endpoint": req.EndpointIP,
+		"nodeName": nodeName,
+	}).Info("Join request is pending approval, use wgmesh pending")
+
+	select {
+	case approved := <-pj.decision:
+		if approved {
+			log.WithField("id", pj.id).Info("Join request approved")
+			return nil
+		}
+		log.WithField("id", pj.id).Info("Join request rejected")
+		return &JoinResponse{
+			Result:            JoinResponse_ERROR,
+			ErrorMessage:      "Join request rejected",
+			JoiningNodeMeshIP: "",
+		}
+	case <-ctx.Done():
+		ms.pendingJoins.remove(pj.id)
+		log.WithField("id", pj.id).Warn("Joining node gave up waiting for approval")
+		return &JoinResponse{
+			Result:            JoinResponse_ERROR,
+			ErrorMessage:      "Timeout while waiting for approval",
+			JoiningNodeMeshIP: "",
+		}
+	}
 }
 
 // Join allows other nodes to join by sending a JoinRequest
@@ -87,7 +234,8 @@ func (ms *MeshService) Join(ctx context.Context, req *JoinRequest) (*JoinRespons
 			JoiningNodeMeshIP: "",
 		}, nil
 	}
-	if err := ms.parseJWT(md); err != nil {
+	jt, err := ms.parseJWT(md)
+	if err != nil {
 		log.Error(err)
 		return &JoinResponse{
 			Result:            JoinResponse_ERROR,
@@ -104,83 +252,150 @@ func (ms *MeshService) Join(ctx context.Context, req *JoinRequest) (*JoinRespons
 		}, nil
 	}
 
-	// choose a random ip address from the address pool of this node
-	// which has not been used before. Choose from cidr range or
-	// if specified from the IPAM cidr range
-	var mip net.IP
-	for {
-		var _net *net.IPNet = &ms.CIDRRange
-		if ms.CIDRRangeIPAM != nil {
-			_net = ms.CIDRRangeIPAM
+	// apply the pre-shared token presented in the handshake
+	nodeName := req.NodeName
+	var pt presharedToken
+	joined := false
+	if jt.presharedTokenID != "" {
+		pt, err = ms.presharedTokens.use(jt.presharedTokenID)
+		if err != nil {
+			log.WithError(err).Warn("Rejecting join with invalid token")
+			return &JoinResponse{
+				Result:            JoinResponse_ERROR,
+				ErrorMessage:      "Invalid token",
+				JoiningNodeMeshIP: "",
+			}, nil
 		}
 
-		mip, _ = newIPInNet(*_net)
-
-		if ms.isIPAvailable(mip) {
-			break
+		// the use only counts if the node joins
+		defer func() {
+			if !joined {
+				ms.presharedTokens.release(jt.presharedTokenID)
+			}
+		}()
+		if pt.nodeName != "" {
+			if nodeName != "" && nodeName != pt.nodeName {
+				return &JoinResponse{
+					Result:            JoinResponse_ERROR,
+					ErrorMessage:      "Requested node name is not allowed by token",
+					JoiningNodeMeshIP: "",
+				}, nil
+			}
+			nodeName = pt.nodeName
 		}
 	}
 
-	// TODO: check if joining node wishes to have a explicit node name
-	// if so, check if this name is already in use.
-	if ms.isNodeNameInUse(req.NodeName) {
+	// check if the requested node name is already in use, so that
+	// we do not wire up a node which serf will reject later on.
+	nodeName, err = ms.uniqueNodeName(nodeName, req.Pubkey, pt.nodeName == "")
+	if err != nil {
+		log.WithError(err).Warn("Rejecting join")
 		return &JoinResponse{
 			Result:            JoinResponse_ERROR,
-			ErrorMessage:      "Request node name is already in use",
+			ErrorMessage:      err.Error(),
 			JoiningNodeMeshIP: "",
 		}, nil
 	}
 
-	//
-	targetWGIP := net.IPNet{
-		mip,
-		net.CIDRMask(32, 32),
+	// park the join request until an operator approved it
+	if ms.RequireApproval {
+		if resp := ms.waitForApproval(ctx, req, nodeName); resp != nil {
+			return resp, nil
+		}
+
+		// the name may have been taken while waiting
+		if ms.isNodeNameInUse(nodeName, req.Pubkey) {
+			return &JoinResponse{
+				Result:            JoinResponse_ERROR,
+				ErrorMessage:      fmt.Sprintf("Requested node name %s is already in use", nodeName),
+				JoiningNodeMeshIP: "",
+			}, nil
+		}
 	}
 
-	/*
-		keepAliveSeconds := 0
-		if req.Nat {
-			keepAliveSeconds = 20
+	// bootstrap nodes joining the mesh bring their own ipam
+	// range, which must not overlap with others
+	var ipamRange *net.IPNet
+	if req.IpamRange != "" {
+		ipamRange, err = ms.checkIPAMRange(req.IpamRange, req.Pubkey, nodeName)
+		if err != nil {
+			log.WithError(err).Warn("Rejecting join")
+			return &JoinResponse{
+				Result:            JoinResponse_ERROR,
+				ErrorMessage:      err.Error(),
+				JoiningNodeMeshIP: "",
+			}, nil
 		}
-	*/
-	// take public key and endpoint, add as peer to own wireguard interface
-	p := wgwrapper.WireguardPeer{
-		RemoteEndpointIP: req.EndpointIP,
-		ListenPort:       int(req.EndpointPort),
-		Pubkey:           req.Pubkey,
-		AllowedIPs: []net.IPNet{
-			targetWGIP,
-		},
-		Psk: nil,
-		//PersistentKeepaliveInterval: time.Duration(keepAliveSeconds) * time.Second,
 	}
-	log.WithField("peer", p).Trace("Adding peer")
 
-	wg := wgwrapper.New()
+	// in dual-stack meshes they also bring an ipv6 ipam range
+	var ipamRange6 *net.IPNet
+	if req.IpamRange6 != "" || (req.IpamRange != "" && ms.CIDRRange6 != nil) {
+		if req.IpamRange6 == "" {
+			err = errors.New("mesh is dual-stack, joining bootstrap nodes need an ipv6 ipam range")
+		} else {
+			ipamRange6, err = ms.checkIPAMRange6(req.IpamRange6, req.Pubkey, nodeName)
+		}
+		if err != nil {
+			log.WithError(err).Warn("Rejecting join")
+			return &JoinResponse{
+				Result:            JoinResponse_ERROR,
+				ErrorMessage:      err.Error(),
+				JoiningNodeMeshIP: "",
+			}, nil
+		}
+	}
 
-	ok, err := wg.AddPeer(ms.WireguardInterface, p)
-	if err != nil {
-		log.Error(err)
+	// a mesh ip fixed by a token takes precedence over a requested one
+	fixedIP := pt.meshIP
+	if req.RequestedMeshIP != "" {
+		requestedIP, err := ms.checkRequestedMeshIP(req.RequestedMeshIP, fixedIP, ipamRange)
+		if err != nil {
+			log.WithError(err).Warn("Rejecting join")
+			return &JoinResponse{
+				Result:            JoinResponse_ERROR,
+				ErrorMessage:      err.Error(),
+				JoiningNodeMeshIP: "",
+			}, nil
+		}
+		fixedIP = requestedIP
+	}
+
+	// the joining node peers with this node first, so
+	// the group policy must allow this
+	if !ms.peersWith(req.Group) {
 		return &JoinResponse{
 			Result:            JoinResponse_ERROR,
-			ErrorMessage:      "Unable to add peer",
+			ErrorMessage:      fmt.Sprintf("group policy does not allow group %s to peer with this bootstrap node", req.Group),
 			JoiningNodeMeshIP: "",
 		}, nil
 	}
-	if !ok && err == nil {
+
+	// allocations are serialized until the lease is assigned,
+	// so that concurrent joins do not get the same mesh ip
+	ms.leases.allocation.Lock()
+	mip, mip6, err := ms.addJoiningPeer(req, nodeName, fixedIP, ipamRange, ipamRange6)
+	ms.leases.allocation.Unlock()
+	if err != nil {
 		return &JoinResponse{
 			Result:            JoinResponse_ERROR,
-			ErrorMessage:      "Peer already present",
+			ErrorMessage:      err.Error(),
 			JoiningNodeMeshIP: "",
 		}, nil
 	}
+	mip6Str := ""
+	if mip6 != nil {
+		mip6Str = mip6.String()
+	}
 
 	log.WithFields(log.Fields{
-		"ip": mip.String(),
+		"ip":  mip.String(),
+		"ip6": mip6Str,
 	}).Info("node joined mesh")
 	log.WithFields(log.Fields{
-		"ip": mip.String(),
-		"pk": req.Pubkey,
+		"ip":  mip.String(),
+		"ip6": mip6Str,
+		"pk":  req.Pubkey,
 	}).Debug("node joined mesh")
 
 	// send out a Peer Update as message to all serf nodes
@@ -189,22 +404,88 @@ func (ms *MeshService) Join(ctx context.Context, req *JoinRequest) (*JoinRespons
 		Pubkey:       req.Pubkey,
 		EndpointIP:   req.EndpointIP,
 		EndpointPort: int32(req.EndpointPort),
-		MeshIP:       targetWGIP.IP.String(),
+		MeshIP:       mip.String(),
+		MeshIP6:      mip6Str,
+		Nat:          req.Nat,
+		Group:        req.Group,
 	})
 	// send out a join request event
 	ms.Serf().UserEvent(serfEventMarkerJoin, []byte(peerAnnouncementBuf), true)
 
 	// return successful join response to client
+	joined = true
+	pskMode, pskSecret := ms.PresharedKeys()
 	return &JoinResponse{
-		Result:            JoinResponse_OK,
-		ErrorMessage:      "",
-		JoiningNodeMeshIP: mip.String(),
-		MeshCidr:          ms.CIDRRange.String(),
-		CreationTS:        int64(ms.creationTS.Unix()),
-		SerfEncryptionKey: ms.GetEncryptionKey(),
+		Result:             JoinResponse_OK,
+		ErrorMessage:       "",
+		JoiningNodeMeshIP:  mip.String(),
+		MeshCidr:           ms.CIDRRange.String(),
+		CreationTS:         int64(ms.creationTS.Unix()),
+		SerfEncryptionKey:  ms.GetEncryptionKey(),
+		SerfModeLAN:        ms.serfModeLAN,
+		SerfBindPort:       int32(ms.SerfBindPort()),
+		NodeName:           nodeName,
+		Tags:               pt.tags,
+		JoiningNodeMeshIP6: mip6Str,
+		MeshCidr6:          ms.meshCidr6(),
+		PskMode:            pskMode,
+		PskSecret:          pskSecret,
+		PskEpoch:           ms.PresharedKeyEpoch(),
+		GroupPolicy:        ms.GroupPolicy(),
 	}, nil
 }
 
+// addJoiningPeer allocates the mesh ip(s) of a joining node, adds it as
+// wireguard peer and records its lease, so that the ips are taken. Unless
+// fixed, the mesh ips of a joining bootstrap node lie outside of its ipam
+// ranges ipamRange and ipamRange6.
+func (ms *MeshService) addJoiningPeer(req *JoinRequest, nodeName string, fixedIP net.IP, ipamRange, ipamRange6 *net.IPNet) (net.IP, net.IP, error) {
+	// choose the mesh ip, preferring a lease of a rejoining node
+	mip, err := ms.allocateMeshIP(req.Pubkey, nodeName, fixedIP, ipamRange)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// in dual-stack meshes, also choose an ipv6 mesh ip
+	mip6, err := ms.allocateMeshIP6(req.Pubkey, nodeName, ipamRange6)
+	if err != nil {
+		return nil, nil, err
+	}
+	mip6Str := ""
+	if mip6 != nil {
+		mip6Str = mip6.String()
+	}
+
+	// take public key and endpoint, add as peer to own wireguard interface
+	p := ms.wireguardPeer(KnownPeer{
+		Pubkey:       req.Pubkey,
+		EndpointIP:   req.EndpointIP,
+		EndpointPort: int(req.EndpointPort),
+		MeshIP:       mip.String(),
+		MeshIP6:      mip6Str,
+		NAT:          req.Nat,
+	})
+	log.WithField("peer", p).Trace("Adding peer")
+
+	wg := wgwrapper.New()
+
+	ok, err := wg.AddPeer(ms.WireguardInterface, p)
+	if err != nil {
+		log.Error(err)
+		return nil, nil, errors.New("Unable to add peer")
+	}
+	if !ok {
+		return nil, nil, errors.New("Peer already present")
+	}
+
+	// remember the mesh ip of this node for rejoins
+	if err = ms.leases.assign(mip, mip6, req.Pubkey, nodeName); err != nil {
+		log.WithError(err).Error("Unable to persist ipam lease")
+	}
+
+	return mip, mip6, nil
+}
+
 // Peers serves a list of all current peers, starting with this node.
 // All data is derived from serf's memberlist
 func (ms *MeshService) Peers(e *Empty, stream Mesh_PeersServer) error {
@@ -214,11 +495,15 @@ func (ms *MeshService) Peers(e *Empty, stream Mesh_PeersServer) error {
 		//log.WithField("t", t).Trace("Peers: sending member tags")
 
 		port, _ := strconv.Atoi(t[nodeTagPort])
+		pubkey, _ := memberPubkeys(t)
 		err := stream.Send(&Peer{
-			Pubkey:       t[nodeTagPubKey],
+			Pubkey:       pubkey,
 			EndpointIP:   t[nodeTagAddr],
 			EndpointPort: int32(port),
 			MeshIP:       t[nodeTagMeshIP],
+			MeshIP6:      t[nodeTagMeshIP6],
+			Nat:          t[nodeTagNAT] == "1",
+			Group:        t[nodeTagGroup],
 			Type:         Peer_JOIN,
 		})
 		if err != nil {
@@ -229,32 +514,6 @@ func (ms *MeshService) Peers(e *Empty, stream Mesh_PeersServer) error {
 	return nil
 }
 
-func newIPInNet(ipnet net.IPNet) (net.IP, error) {
-
-	ipmask := ipnet.Mask
-
-	var newIP [4]byte
-	if len(ipnet.IP) == 4 {
-		newIP = [4]byte{
-			(byte(rand.Intn(250)+2) & ^ipmask[0]) + ipnet.IP[0],
-			(byte(rand.Intn(250)) & ^ipmask[1]) + ipnet.IP[1],
-			(byte(rand.Intn(250)) & ^ipmask[2]) + ipnet.IP[2],
-			(byte(rand.Intn(250)+1) & ^ipmask[3]) + ipnet.IP[3],
-		}
-	}
-	if len(ipnet.IP) == 16 {
-		newIP = [4]byte{
-			(byte(rand.Intn(250)+2) & ^ipmask[0]) + ipnet.IP[12],
-			(byte(rand.Intn(250)) & ^ipmask[1]) + ipnet.IP[13],
-			(byte(rand.Intn(250)) & ^ipmask[2]) + ipnet.IP[14],
-			(byte(rand.Intn(250)+1) & ^ipmask[3]) + ipnet.IP[15],
-		}
-	}
-	log.WithField("newIP", newIP).Trace("newIPInNet.dump")
-
-	return net.IPv4(newIP[0], newIP[1], newIP[2], newIP[3]), nil
-}
-
 func (ms *MeshService) newTLSCredentials() credentials.TransportCredentials {
 	return credentials.NewTLS(&tls.Config{
 		//ServerName: serverNameOverride,
@@ -296,26 +555,3 @@ func (ms *MeshService) StopGrpcService() {
 	ms.grpcServer.GracefulStop()
 	log.Info("Stopped gRPC mesh service")
 }
-
-func (ms *MeshService) isIPAvailable(ip net.IP) bool {
-	s := ip.String()
-
-	for _, member := range ms.Serf().Members() {
-		wgIP := member.Tags[nodeTagAddr]
-		if wgIP == s {
-			return false
-		}
-	}
-
-	return true
-}
-
-func randomTokenAsString() string {
-	chars := "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
-	b := make([]byte, 32)
-	for i := range b {
-		b[i] = chars[rand.Intn(len(chars))]
-	}
-	//	return string(b)
-	return "secret"
-}
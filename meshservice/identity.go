@@ -0,0 +1,312 @@
+package meshservice
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	serf "github.com/hashicorp/serf/serf"
+	log "github.com/sirupsen/logrus"
+	"golang.org/x/crypto/curve25519"
+)
+
+// NodeIdentity is the state of a joined node, persisted in its state
+// directory so that the node keeps its wireguard key, mesh ip and name
+// across restarts and is able to rejoin through known peers.
+type NodeIdentity struct {
+	MeshName          string            `json:"meshName"`
+	NodeName          string            `json:"nodeName"`
+	PrivateKey        string            `json:"privateKey"`
+	PendingPrivateKey string            `json:"pendingPrivateKey,omitempty"`
+	PendingKeyTS      int64             `json:"pendingKeyTS,omitempty"`
+	MeshIP            string            `json:"meshIP"`
+	MeshIP6           string            `json:"meshIP6,omitempty"`
+	MeshCidr          string            `json:"meshCidr"`
+	MeshCidr6         string            `json:"meshCidr6,omitempty"`
+	SerfEncryptionKey string            `json:"serfEncryptionKey,omitempty"`
+	SerfModeLAN       bool              `json:"serfModeLAN"`
+	SerfBindPort      int               `json:"serfBindPort,omitempty"`
+	PSKMode           string            `json:"pskMode,omitempty"`
+	PSKSecret         string            `json:"pskSecret,omitempty"`
+	PSKEpoch          int64             `json:"pskEpoch,omitempty"`
+	GroupPolicy       string            `json:"groupPolicy,omitempty"`
+	CreationTS        int64             `json:"creationTS"`
+	Tags              map[string]string `json:"tags,omitempty"`
+	Peers             []KnownPeer       `json:"peers"`
+	UpdatedTS         int64             `json:"updatedTS"`
+}
+
+// KnownPeer holds the wireguard details of a mesh member
+type KnownPeer struct {
+	NodeName     string   `json:"nodeName"`
+	Pubkey       string   `json:"pubkey"`
+	EndpointIP   string   `json:"endpointIP"`
+	EndpointPort int      `json:"endpointPort"`
+	MeshIP       string   `json:"meshIP"`
+	MeshIP6      string   `json:"meshIP6,omitempty"`
+	NAT          bool     `json:"nat,omitempty"`
+	Routes       []string `json:"routes,omitempty"`
+	Group        string   `json:"group,omitempty"`
+
+	// new public key of a pending key rotation, taken from the tags
+	PendingPubkey string `json:"-"`
+}
+
+// LoadNodeIdentity reads a persisted node identity. Returns
+// nil if the file does not exist.
+func LoadNodeIdentity(path string) (*NodeIdentity, error) {
+	b, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	id := &NodeIdentity{}
+	if err = json.Unmarshal(b, id); err != nil {
+		return nil, err
+	}
+	return id, nil
+}
+
+// Save writes the node identity to given file. As it
+// contains the private key, it is only readable by owner.
+func (id *NodeIdentity) Save(path string) error {
+	b, err := json.MarshalIndent(id, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmpPath := path + ".tmp"
+	if err = ioutil.WriteFile(tmpPath, b, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmpPath, path)
+}
+
+// SetIdentityFile makes this node persist its identity in given
+// file, every time the member state changes.
+func (ms *MeshService) SetIdentityFile(f string) {
+	ms.identityFile = f
+}
+
+// NodeIdentity returns the current identity of this node,
+// including all known peers
+func (ms *MeshService) NodeIdentity() (*NodeIdentity, error) {
+	privateKey, err := ms.WireguardPrivateKey()
+	if err != nil {
+		return nil, err
+	}
+
+	pskMode, pskSecret := ms.PresharedKeys()
+
+	id := &NodeIdentity{
+		MeshName:          ms.MeshName,
+		NodeName:          ms.NodeName,
+		PrivateKey:        privateKey,
+		MeshIP:            ms.MeshIP.IP.String(),
+		MeshCidr:          ms.CIDRRange.String(),
+		SerfEncryptionKey: ms.GetEncryptionKey(),
+		SerfModeLAN:       ms.serfModeLAN,
+		SerfBindPort:      ms.SerfBindPort(),
+		PSKMode:           pskMode,
+		PSKSecret:         pskSecret,
+		PSKEpoch:          ms.PresharedKeyEpoch(),
+		GroupPolicy:       ms.GroupPolicy(),
+		CreationTS:        ms.creationTS.Unix(),
+		Tags:              make(map[string]string),
+		Peers:             make([]KnownPeer, 0),
+		UpdatedTS:         time.Now().Unix(),
+	}
+	if ms.MeshIP6.IP != nil {
+		id.MeshIP6 = ms.MeshIP6.IP.String()
+		id.MeshCidr6 = ms.meshCidr6()
+	}
+	ms.pendingKey.Lock()
+	if ms.pendingKey.privateKey != "" {
+		id.PendingPrivateKey = ms.pendingKey.privateKey
+		id.PendingKeyTS = ms.pendingKey.activation.Unix()
+	}
+	ms.pendingKey.Unlock()
+
+	if ms.Serf() == nil {
+		return id, nil
+	}
+
+	// keep all non-internal tags, e.g. from a token or set by `wgmesh tags`
+	for key, value := range ms.Serf().LocalMember().Tags {
+		if !strings.HasPrefix(key, "_") {
+			id.Tags[key] = value
+		}
+	}
+	id.Peers = ms.knownPeers()
+
+	return id, nil
+}
+
+// knownPeers returns the wireguard details of all alive members
+// except this node, taken from their tags
+func (ms *MeshService) knownPeers() []KnownPeer {
+	res := make([]KnownPeer, 0)
+	for _, member := range ms.Serf().Members() {
+		if member.Name == ms.NodeName || member.Status != serf.StatusAlive {
+			continue
+		}
+		res = append(res, memberPeer(member))
+	}
+	return res
+}
+
+// updateIdentity persists the identity if the member state changed
+func (ms *MeshService) updateIdentity() {
+	if ms.lastUpdatedTS.Unix() <= ms.lastIdentitySavedTS.Unix() {
+		return
+	}
+	log.Debug("updateIdentity")
+
+	ms.SaveIdentity()
+	ms.lastIdentitySavedTS = ms.lastUpdatedTS
+}
+
+// SaveIdentity persists the current identity of this node, if
+// an identity file has been set
+func (ms *MeshService) SaveIdentity() {
+	if ms.identityFile == "" {
+		return
+	}
+
+	id, err := ms.NodeIdentity()
+	if err != nil {
+		log.WithError(err).Error("Unable to determine node identity")
+		return
+	}
+	if err = id.Save(ms.identityFile); err != nil {
+		log.WithError(err).Error("Unable to persist node identity")
+	}
+}
+
+// RestoreNodeIdentity applies a persisted identity to this node: the
+// wireguard interface is given the private key and the mesh ip(s), and
+// mesh settings are taken over. The interface must have been created before.
+func (ms *MeshService) RestoreNodeIdentity(id *NodeIdentity) error {
+	if id.MeshName != ms.MeshName {
+		return fmt.Errorf("persisted identity belongs to mesh %s", id.MeshName)
+	}
+
+	if err := ms.RestoreWireguardKey(id); err != nil {
+		return err
+	}
+
+	_, meshCidr, err := net.ParseCIDR(id.MeshCidr)
+	if err != nil {
+		return fmt.Errorf("invalid mesh cidr range %s in persisted identity", id.MeshCidr)
+	}
+	ms.CIDRRange = *meshCidr
+	ms.MeshIP = net.IPNet{
+		IP:   net.ParseIP(id.MeshIP),
+		Mask: meshCidr.Mask,
+	}
+	if err = ms.AssignJoiningNodeIP(id.MeshIP); err != nil {
+		return err
+	}
+
+	if id.MeshIP6 != "" {
+		_, meshCidr6, err := net.ParseCIDR(id.MeshCidr6)
+		if err != nil {
+			return fmt.Errorf("invalid ipv6 mesh cidr range %s in persisted identity", id.MeshCidr6)
+		}
+		ms.CIDRRange6 = meshCidr6
+		ms.MeshIP6 = net.IPNet{
+			IP:   net.ParseIP(id.MeshIP6),
+			Mask: meshCidr6.Mask,
+		}
+		if err = ms.AssignMeshIP6(id.MeshIP6); err != nil {
+			return err
+		}
+	}
+
+	if id.SerfEncryptionKey != "" {
+		if err = ms.SetEncryptionKey(id.SerfEncryptionKey); err != nil {
+			return err
+		}
+	}
+	if id.PSKMode != "" {
+		if err = ms.SetPresharedKeys(id.PSKMode, id.PSKSecret); err != nil {
+			return err
+		}
+		ms.SetPresharedKeyEpoch(id.PSKEpoch)
+	}
+	if err = ms.SetGroupPolicy(id.GroupPolicy); err != nil {
+		return err
+	}
+	ms.SetNodeName(id.NodeName)
+	ms.SetTimestamps(id.CreationTS, time.Now().Unix())
+
+	return nil
+}
+
+// WireguardPrivateKey returns the (base64-encoded) private key
+// of the wireguard interface
+func (ms *MeshService) WireguardPrivateKey() (string, error) {
+	if ms.wireguardPrivateKey != "" {
+		return ms.wireguardPrivateKey, nil
+	}
+
+	cmd := exec.Command("wg", "show", ms.WireguardInterface.InterfaceName, "private-key")
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		log.WithField("stderr", stderr.String()).Error("wg show reported an error")
+		return "", err
+	}
+
+	ms.wireguardPrivateKey = strings.TrimSpace(stdout.String())
+	return ms.wireguardPrivateKey, nil
+}
+
+// SetWireguardPrivateKey replaces the private key of the wireguard
+// interface, and updates the public key accordingly
+func (ms *MeshService) SetWireguardPrivateKey(privateKey string) error {
+	pubkey, err := wireguardPublicKey(privateKey)
+	if err != nil {
+		return err
+	}
+
+	cmd := exec.Command("wg", "set", ms.WireguardInterface.InterfaceName, "private-key", "/dev/stdin")
+	cmd.Stdin = strings.NewReader(privateKey)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err = cmd.Run(); err != nil {
+		log.WithField("stderr", stderr.String()).Error("wg set reported an error")
+		return err
+	}
+
+	ms.wireguardPrivateKey = privateKey
+	ms.WireguardPubKey = pubkey
+	ms.WireguardInterface.PublicKey = pubkey
+
+	return nil
+}
+
+// wireguardPublicKey derives the public key from a base64-encoded private key
+func wireguardPublicKey(privateKey string) (string, error) {
+	b, err := base64.StdEncoding.DecodeString(privateKey)
+	if err != nil || len(b) != curve25519.ScalarSize {
+		return "", errors.New("invalid wireguard private key")
+	}
+	pub, err := curve25519.X25519(b, curve25519.Basepoint)
+	if err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(pub), nil
+}
@@ -112,6 +112,18 @@ func (ms *MeshService) CreateWireguardInterface(wgListenPort int) (string, error
 
 // AssignJoiningNodeIP sets the ip address of the wireguard interface
 func (ms *MeshService) AssignJoiningNodeIP(ip string) error {
+	return ms.addInterfaceIP(ip)
+}
+
+// AssignMeshIP6 adds the ipv6 mesh ip of a dual-stack mesh
+// to the wireguard interface
+func (ms *MeshService) AssignMeshIP6(ip string) error {
+	return ms.addInterfaceIP(ip)
+}
+
+// addInterfaceIP adds an ip address to the wireguard interface,
+// unless it is already present
+func (ms *MeshService) addInterfaceIP(ip string) error {
 	intfName := intfNameForMesh(ms.MeshName)
 
 	intf, err := net.InterfaceByName(intfName)
@@ -120,35 +132,46 @@ func (ms *MeshService) AssignJoiningNodeIP(ip string) error {
 	}
 
 	// Assign IP if desired and not yet present
-	a, err := intf.Addrs()
+	if hasInterfaceIP(intf, ip) {
+		return nil
+	}
+
+	cmd := exec.Command("/sbin/ip", "address", "add", "dev", intfName, ip)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	err = cmd.Run()
 	if err != nil {
 		return err
 	}
-	if len(a) == 0 {
-		cmd := exec.Command("/sbin/ip", "address", "add", "dev", intfName, ip)
-		var stdout, stderr bytes.Buffer
-		cmd.Stdout = &stdout
-		cmd.Stderr = &stderr
-		err := cmd.Run()
-		if err != nil {
-			return err
-		}
-		_, errStr := string(stdout.Bytes()), string(stderr.Bytes())
-		if len(errStr) > 0 {
-			e := fmt.Sprintf("/sbin/ip reported: %s", errStr)
-			return errors.New(e)
-		}
+	_, errStr := string(stdout.Bytes()), string(stderr.Bytes())
+	if len(errStr) > 0 {
+		e := fmt.Sprintf("/sbin/ip reported: %s", errStr)
+		return errors.New(e)
 	}
 
-	a, err = intf.Addrs()
-	if len(a) == 0 {
-		e := fmt.Sprintf("unable to add ip address %s to interface %s: %s", ip, intfName, err)
+	if !hasInterfaceIP(intf, ip) {
+		e := fmt.Sprintf("unable to add ip address %s to interface %s", ip, intfName)
 		return errors.New(e)
 	}
 
 	return nil
 }
 
+func hasInterfaceIP(intf *net.Interface, ip string) bool {
+	a, err := intf.Addrs()
+	if err != nil {
+		return false
+	}
+	wantIP := net.ParseIP(ip)
+	for _, addr := range a {
+		if ipnet, ok := addr.(*net.IPNet); ok && ipnet.IP.Equal(wantIP) {
+			return true
+		}
+	}
+	return false
+}
+
 // SetRoute adds a route for the cidr range to the wireguard interface
 func (ms *MeshService) SetRoute() error {
 	wg := wgwrapper.New()
@@ -161,6 +184,16 @@ func (ms *MeshService) SetRoute() error {
 	}
 	log.WithField("target", ms.CIDRRange.String()).Debug("Added route")
 
+	// dual-stack meshes also route the ipv6 range
+	if ms.CIDRRange6 != nil {
+		err = wg.SetRoute(ms.WireguardInterface, ms.CIDRRange6.String())
+		if err != nil {
+			log.WithError(err).Error("unable to add ipv6 route to target")
+			return err
+		}
+		log.WithField("target", ms.CIDRRange6.String()).Debug("Added route")
+	}
+
 	return nil
 }
 
@@ -185,7 +218,7 @@ func (ms *MeshService) RemoveWireguardInterfaceForMesh() error {
 }
 
 // ApplyPeerUpdatesFromStream reads peer data from an incoming stream and apply
-// these to the interface.
+// these to the interface. Peers denied by the group policy are skipped.
 // Returns a list of MeshIPs from all peers, with the first entry being the bootstrap node
 // where we joined.
 func (ms *MeshService) ApplyPeerUpdatesFromStream(wg wgwrapper.WireguardWrapper, stream Mesh_PeersClient) []string {
@@ -203,6 +236,10 @@ func (ms *MeshService) ApplyPeerUpdatesFromStream(wg wgwrapper.WireguardWrapper,
 			log.WithError(err).Error("Error while receiving peers")
 			break
 		}
+		if !ms.peersWith(peer.Group) {
+			log.WithField("group", peer.Group).Debug("not peering with node, as denied by group policy")
+			continue
+		}
 
 		peerCh <- peer
 
@@ -215,6 +252,26 @@ func (ms *MeshService) ApplyPeerUpdatesFromStream(wg wgwrapper.WireguardWrapper,
 	return res
 }
 
+// AddKnownPeers adds persisted peers to the wireguard interface, except
+// for those denied by the group policy. Returns the mesh ips of all peers.
+func (ms *MeshService) AddKnownPeers(wg wgwrapper.WireguardWrapper, peers []KnownPeer) []string {
+	res := make([]string, 0, len(peers))
+
+	for _, peer := range peers {
+		if !ms.peersWith(peer.Group) {
+			continue
+		}
+		ok, err := wg.AddPeer(ms.WireguardInterface, ms.wireguardPeer(peer))
+		if err != nil || !ok {
+			log.WithError(err).Errorf("unable to add known peer %s", peer.Pubkey)
+			continue
+		}
+		res = append(res, peer.MeshIP)
+	}
+
+	return res
+}
+
 func peerAdder(ms *MeshService, wg wgwrapper.WireguardWrapper, peerCh <-chan *Peer) error {
 	for {
 		select {
@@ -227,17 +284,7 @@ func peerAdder(ms *MeshService, wg wgwrapper.WireguardWrapper, peerCh <-chan *Pe
 
 			// Add the peer to the interface, making the peer the
 			// only allowed ip
-			ok, err := wg.AddPeer(ms.WireguardInterface, wgwrapper.WireguardPeer{
-				RemoteEndpointIP: peer.EndpointIP,
-				ListenPort:       int(peer.EndpointPort),
-				Pubkey:           peer.Pubkey,
-				AllowedIPs: []net.IPNet{
-					{
-						IP:   net.ParseIP(peer.MeshIP),
-						Mask: net.CIDRMask(32, 32),
-					},
-				},
-			})
+			ok, err := wg.AddPeer(ms.WireguardInterface, ms.wireguardPeer(announcedPeer(peer)))
 
 			if err != nil {
 				log.WithError(err).Errorf("unable to add peer %s", peer.Pubkey)
@@ -0,0 +1,423 @@
+package meshservice
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"net"
+
+	log "github.com/sirupsen/logrus"
+)
+
+var (
+	// errAddressPoolExhausted is returned when all addresses
+	// of the ipam range are in use or leased
+	errAddressPoolExhausted = errors.New("address pool exhausted")
+)
+
+// allocateMeshIP chooses the mesh ip for a joining node. A fixed ip
+// (e.g. from a token) is used if it is available. Otherwise rejoining
+// nodes get their leased ip back, and new nodes get the first ip of
+// the ipam range which is neither in use nor leased to other nodes.
+// Leased and new ips skip the ipam ranges of other bootstrap nodes,
+// including the one a joining bootstrap node brings along (joiningRange,
+// may be nil). Callers must hold the allocation lock of the lease store
+// until the lease has been assigned.
+func (ms *MeshService) allocateMeshIP(pubkey, nodeName string, fixedIP net.IP, joiningRange *net.IPNet) (net.IP, error) {
+	used := ms.meshIPsInUse()
+	otherRanges := ms.otherIPAMRanges()
+
+	if fixedIP != nil {
+		if !ms.CIDRRange.Contains(fixedIP) || isNetworkOrBroadcast(ms.CIDRRange, fixedIP) ||
+			used[fixedIP.String()] || ms.leases.isLeasedToOther(fixedIP, pubkey, nodeName) ||
+			isInAnyNet(fixedIP, otherRanges) {
+			return nil, fmt.Errorf("Mesh ip %s is not available", fixedIP)
+		}
+		return fixedIP, nil
+	}
+
+	if joiningRange != nil {
+		otherRanges = append(otherRanges, joiningRange)
+	}
+
+	if ip, _ := ms.leases.lookup(pubkey, nodeName); ip != nil {
+		if ms.CIDRRange.Contains(ip) && !used[ip.String()] && !isInAnyNet(ip, otherRanges) {
+			log.WithField("ip", ip).Debug("Reusing leased mesh ip")
+			return ip, nil
+		}
+		log.WithField("ip", ip).Warn("Leased mesh ip is not available, assigning a new one")
+	}
+
+	// choose from cidr range or if specified from the IPAM cidr range,
+	// skipping the ipam ranges of other bootstrap nodes
+	_net := ms.ipamRange()
+
+	ip, err := nextFreeIPInNet(*_net, func(ip net.IP) bool {
+		return !ms.CIDRRange.Contains(ip) || isNetworkOrBroadcast(ms.CIDRRange, ip) ||
+			used[ip.String()] || ms.leases.isLeasedToOther(ip, pubkey, nodeName) ||
+			isInAnyNet(ip, otherRanges)
+	})
+	if err != nil {
+		log.WithField("range", _net.String()).Error("No more mesh ips available")
+		return nil, err
+	}
+	return ip, nil
+}
+
+// checkRequestedMeshIP validates a mesh ip requested by a joining node.
+// It must be within the ipam range of this node, or within ipamRange if
+// not nil, and may not conflict with a mesh ip fixed by a token.
+func (ms *MeshService) checkRequestedMeshIP(requested string, fixedIP net.IP, ipamRange *net.IPNet) (net.IP, error) {
+	ip := net.ParseIP(requested)
+	if ip == nil {
+		return nil, fmt.Errorf("Requested mesh ip %s is not a valid ip", requested)
+	}
+	if fixedIP != nil {
+		if !fixedIP.Equal(ip) {
+			return nil, fmt.Errorf("Requested mesh ip %s is not allowed by token", requested)
+		}
+		return ip, nil
+	}
+
+	_net := ipamRange
+	if _net == nil {
+		_net = ms.ipamRange()
+	}
+	if !_net.Contains(ip) || isNetworkOrBroadcast(ms.CIDRRange, ip) {
+		return nil, fmt.Errorf("Requested mesh ip %s is not within ipam range %s", requested, _net.String())
+	}
+
+	return ip, nil
+}
+
+// checkIPAMRange validates the ipam range of a bootstrap node joining
+// the mesh, identified by public key and node name. It must be within
+// the mesh cidr range, and may neither overlap with the ipam range of
+// this or any other bootstrap node nor contain mesh ips in use or leased
+// to other nodes. If this node has no ipam range of its own, it skips
+// the ranges of other nodes when allocating. Unless requested, the mesh
+// ip of the joining node is allocated outside of its ipam range.
+func (ms *MeshService) checkIPAMRange(r, pubkey, nodeName string) (*net.IPNet, error) {
+	_, ipamRange, err := net.ParseCIDR(r)
+	if err != nil {
+		return nil, fmt.Errorf("%s is not a valid ipam range", r)
+	}
+	ones, _ := ipamRange.Mask.Size()
+	meshOnes, _ := ms.CIDRRange.Mask.Size()
+	if !ms.CIDRRange.Contains(ipamRange.IP) || ones < meshOnes {
+		return nil, fmt.Errorf("ipam range %s is not within mesh cidr range %s", r, ms.CIDRRange.String())
+	}
+
+	ranges := ms.otherIPAMRanges()
+	if ms.CIDRRangeIPAM != nil {
+		ranges = append(ranges, ms.CIDRRangeIPAM)
+	}
+	for _, other := range ranges {
+		if netsOverlap(ipamRange, other) {
+			return nil, fmt.Errorf("ipam range %s overlaps with ipam range %s of another bootstrap node", r, other.String())
+		}
+	}
+	if ip := ms.takenIPInNet(ipamRange, pubkey, nodeName); ip != nil {
+		return nil, fmt.Errorf("ipam range %s contains mesh ip %s, which is in use or leased", r, ip)
+	}
+
+	return ipamRange, nil
+}
+
+// checkIPAMRange6 validates the ipv6 ipam range of a bootstrap node
+// joining a dual-stack mesh, like checkIPAMRange does for ipv4.
+func (ms *MeshService) checkIPAMRange6(r, pubkey, nodeName string) (*net.IPNet, error) {
+	if ms.CIDRRange6 == nil {
+		return nil, errors.New("mesh is not dual-stack, an ipv6 ipam range cannot be used")
+	}
+	_, ipamRange, err := net.ParseCIDR(r)
+	if err != nil || ipamRange.IP.To4() != nil {
+		return nil, fmt.Errorf("%s is not a valid ipv6 ipam range", r)
+	}
+	ones, _ := ipamRange.Mask.Size()
+	meshOnes, _ := ms.CIDRRange6.Mask.Size()
+	if !ms.CIDRRange6.Contains(ipamRange.IP) || ones < meshOnes {
+		return nil, fmt.Errorf("ipv6 ipam range %s is not within mesh ipv6 cidr range %s", r, ms.CIDRRange6.String())
+	}
+
+	ranges := ms.otherIPAMRanges6()
+	if ms.CIDRRangeIPAM6 != nil {
+		ranges = append(ranges, ms.CIDRRangeIPAM6)
+	}
+	for _, other := range ranges {
+		if netsOverlap(ipamRange, other) {
+			return nil, fmt.Errorf("ipv6 ipam range %s overlaps with ipv6 ipam range %s of another bootstrap node", r, other.String())
+		}
+	}
+	if ip := ms.takenIPInNet(ipamRange, pubkey, nodeName); ip != nil {
+		return nil, fmt.Errorf("ipv6 ipam range %s contains mesh ip %s, which is in use or leased", r, ip)
+	}
+
+	return ipamRange, nil
+}
+
+// takenIPInNet returns a mesh ip (v4 or v6) within ipnet which is in
+// use or leased to another node than the one identified by public key
+// or node name, nil if there is none
+func (ms *MeshService) takenIPInNet(ipnet *net.IPNet, pubkey, nodeName string) net.IP {
+	ownIP, ownIP6 := ms.leases.lookup(pubkey, nodeName)
+	for s := range ms.meshIPsInUse() {
+		ip := net.ParseIP(s)
+		if ip != nil && ipnet.Contains(ip) && !ip.Equal(ownIP) && !ip.Equal(ownIP6) {
+			return ip
+		}
+	}
+	for _, l := range ms.leases.list() {
+		if l.ownedBy(pubkey, nodeName) || l.reclaimable() {
+			continue
+		}
+		for _, s := range []string{l.IP, l.IP6} {
+			if ip := net.ParseIP(s); ip != nil && ipnet.Contains(ip) {
+				return ip
+			}
+		}
+	}
+	return nil
+}
+
+// ipamRange returns the range this node allocates mesh ips from
+func (ms *MeshService) ipamRange() *net.IPNet {
+	if ms.CIDRRangeIPAM != nil {
+		return ms.CIDRRangeIPAM
+	}
+	return &ms.CIDRRange
+}
+
+// ipamRange6 returns the range this node allocates ipv6 mesh ips from
+func (ms *MeshService) ipamRange6() *net.IPNet {
+	if ms.CIDRRangeIPAM6 != nil {
+		return ms.CIDRRangeIPAM6
+	}
+	return ms.CIDRRange6
+}
+
+// otherIPAMRanges collects the ipam ranges advertised by all other
+// bootstrap nodes in their tags. Failed nodes are included, so that
+// their ranges are not handed out until they have been reaped.
+func (ms *MeshService) otherIPAMRanges() []*net.IPNet {
+	return ms.otherRangesByTag(nodeTagIPAM)
+}
+
+// otherIPAMRanges6 collects the ipv6 ipam ranges of all other
+// bootstrap nodes, see otherIPAMRanges
+func (ms *MeshService) otherIPAMRanges6() []*net.IPNet {
+	return ms.otherRangesByTag(nodeTagIPAM6)
+}
+
+func (ms *MeshService) otherRangesByTag(tag string) []*net.IPNet {
+	res := make([]*net.IPNet, 0)
+	if ms.Serf() == nil {
+		return res
+	}
+	for _, member := range ms.Serf().Members() {
+		if member.Name == ms.NodeName {
+			continue
+		}
+		r, ok := member.Tags[tag]
+		if !ok {
+			continue
+		}
+		_, ipnet, err := net.ParseCIDR(r)
+		if err != nil {
+			log.WithField("node", member.Name).WithField("range", r).Warn("Ignoring invalid ipam range tag")
+			continue
+		}
+		res = append(res, ipnet)
+	}
+	return res
+}
+
+func netsOverlap(a, b *net.IPNet) bool {
+	return a.Contains(b.IP) || b.Contains(a.IP)
+}
+
+func isInAnyNet(ip net.IP, nets []*net.IPNet) bool {
+	for _, n := range nets {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// allocateMeshIP6 chooses the additional ipv6 mesh ip for a joining
+// node in a dual-stack mesh, preferring a lease of a rejoining node.
+// Like with ipv4, the ipv6 ipam ranges of other bootstrap nodes are
+// skipped, and so is the ipv6 ipam range a joining bootstrap node
+// brings along (joiningRange, may be nil). Returns nil if the mesh
+// is not dual-stack.
+func (ms *MeshService) allocateMeshIP6(pubkey, nodeName string, joiningRange *net.IPNet) (net.IP, error) {
+	if ms.CIDRRange6 == nil {
+		return nil, nil
+	}
+	used := ms.meshIPsInUse()
+	otherRanges := ms.otherIPAMRanges6()
+	if joiningRange != nil {
+		otherRanges = append(otherRanges, joiningRange)
+	}
+
+	if _, ip6 := ms.leases.lookup(pubkey, nodeName); ip6 != nil {
+		if ms.CIDRRange6.Contains(ip6) && !used[ip6.String()] && !isInAnyNet(ip6, otherRanges) {
+			log.WithField("ip6", ip6).Debug("Reusing leased mesh ipv6")
+			return ip6, nil
+		}
+	}
+
+	_net := ms.ipamRange6()
+	ip, err := nextFreeIPInNet(*_net, func(ip net.IP) bool {
+		return !ms.CIDRRange6.Contains(ip) || used[ip.String()] ||
+			ms.leases.isLeasedToOther(ip, pubkey, nodeName) || isInAnyNet(ip, otherRanges)
+	})
+	if err != nil {
+		log.WithField("range", _net.String()).Error("No more mesh ipv6 addresses available")
+		return nil, err
+	}
+	return ip, nil
+}
+
+// meshIPsInUse collects the mesh ips (v4 and v6) of all
+// serf members including this node
+func (ms *MeshService) meshIPsInUse() map[string]bool {
+	res := make(map[string]bool)
+	if ms.MeshIP.IP != nil {
+		res[ms.MeshIP.IP.String()] = true
+	}
+	if ms.MeshIP6.IP != nil {
+		res[ms.MeshIP6.IP.String()] = true
+	}
+	if ms.Serf() == nil {
+		return res
+	}
+	for _, member := range ms.Serf().Members() {
+		if meshIP, ok := member.Tags[nodeTagMeshIP]; ok {
+			res[meshIP] = true
+		}
+		if meshIP6, ok := member.Tags[nodeTagMeshIP6]; ok {
+			res[meshIP6] = true
+		}
+	}
+	return res
+}
+
+// nextFreeIPInNet walks all addresses of the network, skipping the
+// network and (ipv4) broadcast addresses, and returns the first one
+// for which isTaken returns false.
+func nextFreeIPInNet(ipnet net.IPNet, isTaken func(net.IP) bool) (net.IP, error) {
+	first, last, size := ipRange(ipnet)
+	if first == nil {
+		return nil, errors.New("invalid ip range")
+	}
+
+	first.Add(first, big.NewInt(1))
+	if size == net.IPv4len {
+		last.Sub(last, big.NewInt(1))
+	}
+
+	for i := first; i.Cmp(last) <= 0; i.Add(i, big.NewInt(1)) {
+		ip := bigIntToIP(i, size)
+		if !isTaken(ip) {
+			return ip, nil
+		}
+	}
+
+	return nil, errAddressPoolExhausted
+}
+
+// isNetworkOrBroadcast checks if ip is the network or (ipv4) broadcast address of ipnet
+func isNetworkOrBroadcast(ipnet net.IPNet, ip net.IP) bool {
+	first, last, size := ipRange(ipnet)
+	if first == nil {
+		return false
+	}
+	v := ipToBigInt(ip, size)
+	return v.Cmp(first) == 0 || (size == net.IPv4len && v.Cmp(last) == 0)
+}
+
+// ipRange returns the first and last address of ipnet as integers,
+// together with the address length. /31, /32 and /127, /128 ranges
+// are widened so that they contain no network or broadcast address.
+func ipRange(ipnet net.IPNet) (first, last *big.Int, size int) {
+	ones, bits := ipnet.Mask.Size()
+	switch bits {
+	case 32:
+		size = net.IPv4len
+	case 128:
+		size = net.IPv6len
+	default:
+		return nil, nil, 0
+	}
+
+	first = ipToBigInt(ipnet.IP.Mask(ipnet.Mask), size)
+	last = new(big.Int).Lsh(big.NewInt(1), uint(bits-ones))
+	last.Sub(last, big.NewInt(1))
+	last.Or(last, first)
+
+	if bits-ones < 2 {
+		first.Sub(first, big.NewInt(1))
+		if size == net.IPv4len {
+			last.Add(last, big.NewInt(1))
+		}
+	}
+
+	return first, last, size
+}
+
+func ipToBigInt(ip net.IP, size int) *big.Int {
+	if size == net.IPv4len {
+		ip = ip.To4()
+	} else {
+		ip = ip.To16()
+	}
+	return new(big.Int).SetBytes(ip)
+}
+
+func bigIntToIP(i *big.Int, size int) net.IP {
+	b := i.Bytes()
+	ip := make(net.IP, size)
+	copy(ip[size-len(b):], b)
+	return ip
+}
+
+// hostIPNet returns a single-host network (/32 or /128) for ip
+func hostIPNet(ip net.IP) net.IPNet {
+	if ip.To4() != nil {
+		return net.IPNet{IP: ip, Mask: net.CIDRMask(32, 32)}
+	}
+	return net.IPNet{IP: ip, Mask: net.CIDRMask(128, 128)}
+}
+
+// peerAllowedIPs returns the allowed ips of a peer, consisting
+// of its mesh ip and - if dual-stack - its ipv6 mesh ip
+func peerAllowedIPs(meshIP, meshIP6 string) []net.IPNet {
+	res := make([]net.IPNet, 0, 2)
+	for _, s := range []string{meshIP, meshIP6} {
+		if ip := net.ParseIP(s); ip != nil {
+			res = append(res, hostIPNet(ip))
+		}
+	}
+	return res
+}
+
+func (ms *MeshService) meshCidr6() string {
+	if ms.CIDRRange6 == nil {
+		return ""
+	}
+	return ms.CIDRRange6.String()
+}
+
+// SetLeaseFile makes ipam leases persistent in given file,
+// and loads all leases found there
+func (ms *MeshService) SetLeaseFile(path string) error {
+	return ms.leases.load(path)
+}
+
+// SetTokenFile makes pre-shared tokens persistent in given
+// file, and loads all tokens found there
+func (ms *MeshService) SetTokenFile(path string) error {
+	return ms.presharedTokens.load(path)
+}
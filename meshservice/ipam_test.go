@@ -0,0 +1,303 @@
+package meshservice
+
+import (
+	"net"
+	"testing"
+)
+
+func mustParseCIDR(t *testing.T, s string) *net.IPNet {
+	t.Helper()
+	_, ipnet, err := net.ParseCIDR(s)
+	if err != nil {
+		t.Fatalf("invalid cidr %s: %s", s, err)
+	}
+	return ipnet
+}
+
+func TestNextFreeIPInNet(t *testing.T) {
+	tests := []struct {
+		cidr      string
+		first     string
+		last      string
+		count     int
+		broadcast string
+	}{
+		{"10.0.0.0/30", "10.0.0.1", "10.0.0.2", 2, "10.0.0.3"},
+		{"10.0.0.0/31", "10.0.0.0", "10.0.0.1", 2, ""},
+		{"10.0.0.5/32", "10.0.0.5", "10.0.0.5", 1, ""},
+		{"10.232.1.0/24", "10.232.1.1", "10.232.1.254", 254, "10.232.1.255"},
+		{"10.232.0.0/16", "10.232.0.1", "10.232.255.254", 65534, "10.232.255.255"},
+		// walking all addresses of a /8 takes too long, so only
+		// the first address and the network/broadcast skip are checked
+		{"10.0.0.0/8", "10.0.0.1", "", 0, "10.255.255.255"},
+	}
+
+	for _, tt := range tests {
+		ipnet := mustParseCIDR(t, tt.cidr)
+
+		ip, err := nextFreeIPInNet(*ipnet, func(net.IP) bool { return false })
+		if err != nil {
+			t.Fatalf("%s: unexpected error %s", tt.cidr, err)
+		}
+		if ip.String() != tt.first {
+			t.Errorf("%s: first free ip is %s, want %s", tt.cidr, ip, tt.first)
+		}
+
+		if tt.broadcast != "" {
+			if !isNetworkOrBroadcast(*ipnet, ipnet.IP) {
+				t.Errorf("%s: network address %s not detected", tt.cidr, ipnet.IP)
+			}
+			if !isNetworkOrBroadcast(*ipnet, net.ParseIP(tt.broadcast)) {
+				t.Errorf("%s: broadcast address %s not detected", tt.cidr, tt.broadcast)
+			}
+		}
+		if isNetworkOrBroadcast(*ipnet, net.ParseIP(tt.first)) {
+			t.Errorf("%s: %s is not a network or broadcast address", tt.cidr, tt.first)
+		}
+
+		if tt.count == 0 {
+			continue
+		}
+
+		// take all addresses, counting and remembering the last one
+		count := 0
+		var last net.IP
+		_, err = nextFreeIPInNet(*ipnet, func(ip net.IP) bool {
+			count++
+			last = ip
+			if isNetworkOrBroadcast(*ipnet, ip) {
+				t.Errorf("%s: walked network or broadcast address %s", tt.cidr, ip)
+			}
+			return true
+		})
+		if err != errAddressPoolExhausted {
+			t.Errorf("%s: got error %v, want %v", tt.cidr, err, errAddressPoolExhausted)
+		}
+		if count != tt.count {
+			t.Errorf("%s: %d addresses, want %d", tt.cidr, count, tt.count)
+		}
+		if last.String() != tt.last {
+			t.Errorf("%s: last address is %s, want %s", tt.cidr, last, tt.last)
+		}
+	}
+}
+
+func TestIPRange(t *testing.T) {
+	tests := []struct {
+		cidr  string
+		first string
+		last  string
+		size  int
+	}{
+		{"10.0.0.0/30", "10.0.0.0", "10.0.0.3", net.IPv4len},
+		{"10.0.0.0/31", "9.255.255.255", "10.0.0.2", net.IPv4len},
+		{"10.0.0.5/32", "10.0.0.4", "10.0.0.6", net.IPv4len},
+		{"10.232.1.0/24", "10.232.1.0", "10.232.1.255", net.IPv4len},
+		{"10.232.0.0/16", "10.232.0.0", "10.232.255.255", net.IPv4len},
+		{"10.0.0.0/8", "10.0.0.0", "10.255.255.255", net.IPv4len},
+		{"fd00:232::/64", "fd00:232::", "fd00:232::ffff:ffff:ffff:ffff", net.IPv6len},
+	}
+
+	for _, tt := range tests {
+		first, last, size := ipRange(*mustParseCIDR(t, tt.cidr))
+		if size != tt.size {
+			t.Errorf("%s: size %d, want %d", tt.cidr, size, tt.size)
+			continue
+		}
+		if ip := bigIntToIP(first, size); ip.String() != tt.first {
+			t.Errorf("%s: first %s, want %s", tt.cidr, ip, tt.first)
+		}
+		if ip := bigIntToIP(last, size); ip.String() != tt.last {
+			t.Errorf("%s: last %s, want %s", tt.cidr, ip, tt.last)
+		}
+	}
+}
+
+func newTestIPAMService(t *testing.T, cidr, ipamCidr, meshIP string) *MeshService {
+	t.Helper()
+	ms := NewMeshService("test")
+	ms.CIDRRange = *mustParseCIDR(t, cidr)
+	if ipamCidr != "" {
+		ms.CIDRRangeIPAM = mustParseCIDR(t, ipamCidr)
+	}
+	ms.MeshIP = net.IPNet{
+		IP:   net.ParseIP(meshIP),
+		Mask: ms.CIDRRange.Mask,
+	}
+	return &ms
+}
+
+func TestAllocateMeshIP(t *testing.T) {
+	tests := []struct {
+		cidr     string
+		ipamCidr string
+		meshIP   string
+		want     []string
+	}{
+		{"10.232.0.0/16", "", "10.232.0.1", []string{"10.232.0.2", "10.232.0.3"}},
+		{"10.232.0.0/16", "10.232.5.0/24", "10.232.1.1", []string{"10.232.5.1", "10.232.5.2"}},
+		{"10.232.0.0/16", "10.232.5.0/30", "10.232.1.1", []string{"10.232.5.1", "10.232.5.2"}},
+		{"10.232.0.0/16", "10.232.5.4/31", "10.232.1.1", []string{"10.232.5.4", "10.232.5.5"}},
+		{"10.232.0.0/16", "10.232.5.7/32", "10.232.1.1", []string{"10.232.5.7"}},
+		{"10.0.0.0/8", "10.0.0.0/30", "10.0.0.1", []string{"10.0.0.2"}},
+		// the ipam range starts at the network address of the mesh
+		// range, which must be skipped nevertheless
+		{"10.232.0.0/16", "10.232.0.0/31", "10.232.1.1", []string{"10.232.0.1"}},
+	}
+
+	for _, tt := range tests {
+		ms := newTestIPAMService(t, tt.cidr, tt.ipamCidr, tt.meshIP)
+		ipamRange := ms.ipamRange()
+
+		for i, want := range tt.want {
+			pubkey := string(rune('a' + i))
+			ip, err := ms.allocateMeshIP(pubkey, "", nil, nil)
+			if err != nil {
+				t.Fatalf("%s/%s: unexpected error %s", tt.cidr, tt.ipamCidr, err)
+			}
+			if ip.String() != want {
+				t.Errorf("%s/%s: allocated %s, want %s", tt.cidr, tt.ipamCidr, ip, want)
+			}
+			if !ipamRange.Contains(ip) {
+				t.Errorf("%s/%s: %s is not within ipam range", tt.cidr, tt.ipamCidr, ip)
+			}
+			if err = ms.leases.assign(ip, nil, pubkey, ""); err != nil {
+				t.Fatalf("unable to assign lease: %s", err)
+			}
+
+			// a rejoining node gets its leased ip back
+			if ip2, err := ms.allocateMeshIP(pubkey, "", nil, nil); err != nil || !ip2.Equal(ip) {
+				t.Errorf("%s/%s: rejoining node got %s, want %s", tt.cidr, tt.ipamCidr, ip2, ip)
+			}
+		}
+
+		// only exhaust small ranges
+		if ones, _ := ipamRange.Mask.Size(); ones < 30 {
+			continue
+		}
+		if _, err := ms.allocateMeshIP("z", "", nil, nil); err != errAddressPoolExhausted {
+			t.Errorf("%s/%s: got error %v, want %v", tt.cidr, tt.ipamCidr, err, errAddressPoolExhausted)
+		}
+	}
+}
+
+func TestAllocateFixedMeshIP(t *testing.T) {
+	ms := newTestIPAMService(t, "10.232.0.0/16", "10.232.5.0/24", "10.232.1.1")
+	if err := ms.leases.assign(net.ParseIP("10.232.7.1"), nil, "other", ""); err != nil {
+		t.Fatalf("unable to assign lease: %s", err)
+	}
+
+	tests := []struct {
+		ip string
+		ok bool
+	}{
+		{"10.232.7.7", true},
+		{"10.232.0.0", false},
+		{"10.232.255.255", false},
+		{"10.233.0.1", false},
+		{"10.232.1.1", false},
+		{"10.232.7.1", false},
+	}
+
+	for _, tt := range tests {
+		ip, err := ms.allocateMeshIP("pk", "", net.ParseIP(tt.ip), nil)
+		if tt.ok && (err != nil || ip.String() != tt.ip) {
+			t.Errorf("fixed ip %s: got %s, %v", tt.ip, ip, err)
+		}
+		if !tt.ok && err == nil {
+			t.Errorf("fixed ip %s: allocated, but should be rejected", tt.ip)
+		}
+	}
+}
+
+func TestAllocateMeshIP6(t *testing.T) {
+	tests := []struct {
+		ipamCidr6    string
+		joiningCidr6 string
+		leased       string
+		want         string
+	}{
+		{"", "", "", "fd00:232::1"},
+		{"fd00:232::100/120", "", "", "fd00:232::101"},
+		{"", "fd00:232::/120", "", "fd00:232::101"},
+		{"", "", "fd00:232::2", "fd00:232::2"},
+		{"", "fd00:232::/120", "fd00:232::2", "fd00:232::101"},
+	}
+
+	for _, tt := range tests {
+		ms := newTestIPAMService(t, "10.232.0.0/16", "", "10.232.1.1")
+		ms.CIDRRange6 = mustParseCIDR(t, "fd00:232::/64")
+		ms.MeshIP6 = net.IPNet{
+			IP:   net.ParseIP("fd00:232::100"),
+			Mask: ms.CIDRRange6.Mask,
+		}
+		if tt.ipamCidr6 != "" {
+			ms.CIDRRangeIPAM6 = mustParseCIDR(t, tt.ipamCidr6)
+			ms.MeshIP6.IP = net.ParseIP("fd00:232::1")
+		}
+		var joiningRange *net.IPNet
+		if tt.joiningCidr6 != "" {
+			joiningRange = mustParseCIDR(t, tt.joiningCidr6)
+		}
+		if tt.leased != "" {
+			if err := ms.leases.assign(net.ParseIP("10.232.0.2"), net.ParseIP(tt.leased), "pk", ""); err != nil {
+				t.Fatalf("unable to assign lease: %s", err)
+			}
+		}
+
+		ip, err := ms.allocateMeshIP6("pk", "", joiningRange)
+		if err != nil || ip.String() != tt.want {
+			t.Errorf("%s/%s: got %s, %v, want %s", tt.ipamCidr6, tt.joiningCidr6, ip, err, tt.want)
+		}
+	}
+}
+
+func TestCheckIPAMRange(t *testing.T) {
+	ms := newTestIPAMService(t, "10.232.0.0/16", "10.232.0.0/20", "10.232.1.1")
+	if err := ms.leases.assign(net.ParseIP("10.232.32.7"), nil, "other", ""); err != nil {
+		t.Fatalf("unable to assign lease: %s", err)
+	}
+	if err := ms.leases.assign(net.ParseIP("10.232.48.7"), nil, "pk", ""); err != nil {
+		t.Fatalf("unable to assign lease: %s", err)
+	}
+
+	tests := []struct {
+		r  string
+		ok bool
+	}{
+		{"10.232.16.0/20", true},
+		{"10.232.48.0/20", true},
+		{"10.232.0.0/24", false},
+		{"10.232.0.0/16", false},
+		{"10.233.0.0/24", false},
+		{"10.232.32.0/20", false},
+		{"10.232.32.0/24", false},
+		{"invalid", false},
+	}
+
+	for _, tt := range tests {
+		_, err := ms.checkIPAMRange(tt.r, "pk", "")
+		if tt.ok && err != nil {
+			t.Errorf("%s: rejected: %s", tt.r, err)
+		}
+		if !tt.ok && err == nil {
+			t.Errorf("%s: accepted, but should be rejected", tt.r)
+		}
+	}
+
+	// the mesh ip of a joining bootstrap node lies outside of its ipam range
+	ipamRange := mustParseCIDR(t, "10.232.16.0/20")
+	ip, err := ms.allocateMeshIP("pk2", "", nil, ipamRange)
+	if err != nil || ipamRange.Contains(ip) {
+		t.Errorf("joining node got mesh ip %s, %v within its ipam range", ip, err)
+	}
+
+	// leases within the ipam ranges of other nodes are not reused
+	if err := ms.leases.assign(net.ParseIP("10.232.16.9"), nil, "pk3", ""); err != nil {
+		t.Fatalf("unable to assign lease: %s", err)
+	}
+	ip, err = ms.allocateMeshIP("pk3", "", nil, ipamRange)
+	if err != nil || ipamRange.Contains(ip) {
+		t.Errorf("leased mesh ip %s, %v within ipam range of joining node reused", ip, err)
+	}
+}
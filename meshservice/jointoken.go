@@ -0,0 +1,114 @@
+package meshservice
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"sync"
+	"time"
+)
+
+const (
+	// joinTokenTTL is the time a joining node has after Begin
+	// to send its Join request.
+	joinTokenTTL = 30 * time.Second
+
+	// jwtClockSkew is the tolerated difference between the clocks
+	// of joining and bootstrap node when checking jwt claims.
+	jwtClockSkew = 5 * time.Second
+)
+
+// joinToken is handed out by Begin. The joining node signs the jwt
+// of its Join request with it.
+type joinToken struct {
+	token     string
+	expiresAt time.Time
+
+	// authentication/authorization requirements sent along with this token
+	authReqs map[string]string
+
+	// ID of the pre-shared token presented in the handshake, if any
+	presharedTokenID string
+}
+
+// joinTokenStore keeps all join tokens which have been issued
+// but not yet used.
+type joinTokenStore struct {
+	sync.Mutex
+
+	tokens map[string]joinToken
+	ttl    time.Duration
+}
+
+func newJoinTokenStore(ttl time.Duration) *joinTokenStore {
+	return &joinTokenStore{
+		tokens: make(map[string]joinToken),
+		ttl:    ttl,
+	}
+}
+
+// JoinTokenID derives the ID of a join token. Joining nodes put it
+// in the jti claim, so the bootstrap node is able to look up the
+// token without it being sent in clear.
+func JoinTokenID(token string) string {
+	h := sha256.Sum256([]byte(token))
+	return base64.RawURLEncoding.EncodeToString(h[:])
+}
+
+// issue creates a new random token and stores it together
+// with the given auth requirements and pre-shared token ID.
+func (s *joinTokenStore) issue(authReqs map[string]string, presharedTokenID string) (string, error) {
+	token, err := randomTokenAsString()
+	if err != nil {
+		return "", err
+	}
+
+	s.Lock()
+	defer s.Unlock()
+
+	// drop all tokens that have not been used in time
+	now := time.Now()
+	for id, jt := range s.tokens {
+		if now.After(jt.expiresAt) {
+			delete(s.tokens, id)
+		}
+	}
+
+	s.tokens[JoinTokenID(token)] = joinToken{
+		token:            token,
+		expiresAt:        now.Add(s.ttl),
+		authReqs:         authReqs,
+		presharedTokenID: presharedTokenID,
+	}
+
+	return token, nil
+}
+
+// take removes the token with given ID from the store and returns it.
+// A token can only be taken once, so it is not possible to replay
+// a join request.
+func (s *joinTokenStore) take(id string) (joinToken, error) {
+	s.Lock()
+	defer s.Unlock()
+
+	jt, ok := s.tokens[id]
+	if !ok {
+		return joinToken{}, errors.New("unknown or already used join token")
+	}
+	delete(s.tokens, id)
+
+	if time.Now().After(jt.expiresAt) {
+		return joinToken{}, errors.New("join token expired")
+	}
+
+	return jt, nil
+}
+
+func randomTokenAsString() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
@@ -0,0 +1,267 @@
+package meshservice
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// leaseReclaimTimeout is the time after which the lease of a
+	// reaped node may be handed out to other nodes.
+	leaseReclaimTimeout = 24 * time.Hour
+)
+
+// lease maps the mesh ip of a node to its public key and node name,
+// so that rejoining nodes get the same mesh ip back.
+type lease struct {
+	IP       string `json:"ip"`
+	IP6      string `json:"ip6,omitempty"`
+	Pubkey   string `json:"pubkey,omitempty"`
+	NodeName string `json:"nodeName,omitempty"`
+
+	// reserved leases are never reclaimed
+	Reserved bool `json:"reserved"`
+
+	UpdatedTS int64 `json:"updatedTS"`
+
+	// when the node holding this lease has been reaped, 0 if active
+	ReleasedTS int64 `json:"releasedTS,omitempty"`
+}
+
+func (l *lease) ownedBy(pubkey, nodeName string) bool {
+	return (pubkey != "" && l.Pubkey == pubkey) || (nodeName != "" && l.NodeName == nodeName)
+}
+
+func (l *lease) reclaimable() bool {
+	return !l.Reserved && l.ReleasedTS > 0 && time.Since(time.Unix(l.ReleasedTS, 0)) > leaseReclaimTimeout
+}
+
+// leaseStore keeps all leases of a bootstrap node, by mesh ip. If
+// a path is set, leases are persisted as JSON.
+type leaseStore struct {
+	sync.Mutex
+
+	path   string
+	leases map[string]*lease
+
+	// serializes choosing a mesh ip and assigning its lease
+	allocation sync.Mutex
+}
+
+func newLeaseStore() *leaseStore {
+	return &leaseStore{
+		leases: make(map[string]*lease),
+	}
+}
+
+// load reads leases from given file and persists all
+// further changes there. A missing file is not an error.
+func (s *leaseStore) load(path string) error {
+	s.Lock()
+	defer s.Unlock()
+
+	s.path = path
+
+	b, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	leases := make([]*lease, 0)
+	if err = json.Unmarshal(b, &leases); err != nil {
+		return err
+	}
+	for _, l := range leases {
+		s.leases[l.IP] = l
+	}
+	log.WithField("num", len(leases)).Debug("Loaded ipam leases")
+
+	return nil
+}
+
+// save writes all leases to the file. Must be called with lock held.
+func (s *leaseStore) save() error {
+	if s.path == "" {
+		return nil
+	}
+
+	b, err := json.MarshalIndent(s.sorted(), "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmpPath := s.path + ".tmp"
+	if err = ioutil.WriteFile(tmpPath, b, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmpPath, s.path)
+}
+
+func (s *leaseStore) sorted() []lease {
+	res := make([]lease, 0, len(s.leases))
+	for _, l := range s.leases {
+		res = append(res, *l)
+	}
+	sort.Slice(res, func(i, j int) bool {
+		return bytes.Compare(net.ParseIP(res[i].IP), net.ParseIP(res[j].IP)) < 0
+	})
+	return res
+}
+
+// lookup returns the mesh ip (and ipv6 mesh ip in dual-stack meshes)
+// leased to a node, identified by its public key or node name. Returns
+// nil if there is none.
+func (s *leaseStore) lookup(pubkey, nodeName string) (net.IP, net.IP) {
+	s.Lock()
+	defer s.Unlock()
+
+	// prefer a match by public key
+	for _, l := range s.leases {
+		if pubkey != "" && l.Pubkey == pubkey {
+			return net.ParseIP(l.IP), net.ParseIP(l.IP6)
+		}
+	}
+	for _, l := range s.leases {
+		if l.ownedBy("", nodeName) {
+			return net.ParseIP(l.IP), net.ParseIP(l.IP6)
+		}
+	}
+	return nil, nil
+}
+
+// isLeasedToOther checks if ip (v4 or v6) is leased to another
+// node than the one identified by public key or node name
+func (s *leaseStore) isLeasedToOther(ip net.IP, pubkey, nodeName string) bool {
+	s.Lock()
+	defer s.Unlock()
+
+	key := ip.String()
+	for _, l := range s.leases {
+		if l.IP != key && l.IP6 != key {
+			continue
+		}
+		return !l.ownedBy(pubkey, nodeName) && !l.reclaimable()
+	}
+	return false
+}
+
+// assign leases ip (and ip6, if not nil) to a node. Other
+// non-reserved leases of this node are dropped.
+func (s *leaseStore) assign(ip, ip6 net.IP, pubkey, nodeName string) error {
+	s.Lock()
+	defer s.Unlock()
+
+	key := ip.String()
+	for k, l := range s.leases {
+		if k != key && !l.Reserved && l.ownedBy(pubkey, "") {
+			delete(s.leases, k)
+		}
+	}
+
+	l, ok := s.leases[key]
+	if !ok || l.reclaimable() {
+		l = &lease{IP: key}
+		s.leases[key] = l
+	}
+	if ip6 != nil {
+		l.IP6 = ip6.String()
+	}
+	l.Pubkey = pubkey
+	if nodeName != "" {
+		l.NodeName = nodeName
+	}
+	l.UpdatedTS = time.Now().Unix()
+	l.ReleasedTS = 0
+
+	return s.save()
+}
+
+// rekey moves the leases of a node which rotated its wireguard key to the new key
+func (s *leaseStore) rekey(prevPubkey, pubkey string) error {
+	s.Lock()
+	defer s.Unlock()
+
+	changed := false
+	for _, l := range s.leases {
+		if prevPubkey != "" && l.Pubkey == prevPubkey {
+			l.Pubkey = pubkey
+			l.UpdatedTS = time.Now().Unix()
+			changed = true
+		}
+	}
+	if !changed {
+		return nil
+	}
+
+	return s.save()
+}
+
+// reserve creates a lease which is never reclaimed
+func (s *leaseStore) reserve(ip net.IP, pubkey, nodeName string) error {
+	s.Lock()
+	defer s.Unlock()
+
+	key := ip.String()
+	if l, ok := s.leases[key]; ok && !l.reclaimable() && !l.ownedBy(pubkey, nodeName) {
+		return errors.New("ip is already leased to another node")
+	}
+
+	s.leases[key] = &lease{
+		IP:        key,
+		Pubkey:    pubkey,
+		NodeName:  nodeName,
+		Reserved:  true,
+		UpdatedTS: time.Now().Unix(),
+	}
+
+	return s.save()
+}
+
+// release removes the lease of ip. Returns false if there is none.
+func (s *leaseStore) release(ip net.IP) (bool, error) {
+	s.Lock()
+	defer s.Unlock()
+
+	key := ip.String()
+	if _, ok := s.leases[key]; !ok {
+		return false, nil
+	}
+	delete(s.leases, key)
+
+	return true, s.save()
+}
+
+// markReleased notes that the node holding the lease of ip has been
+// reaped, so the lease can be reclaimed after leaseReclaimTimeout.
+func (s *leaseStore) markReleased(ip net.IP) error {
+	s.Lock()
+	defer s.Unlock()
+
+	l, ok := s.leases[ip.String()]
+	if !ok || l.Reserved || l.ReleasedTS > 0 {
+		return nil
+	}
+	l.ReleasedTS = time.Now().Unix()
+
+	return s.save()
+}
+
+// list returns copies of all leases, ordered by ip
+func (s *leaseStore) list() []lease {
+	s.Lock()
+	defer s.Unlock()
+
+	return s.sorted()
+}
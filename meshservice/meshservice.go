@@ -2,8 +2,10 @@ package meshservice
 
 import (
 	"encoding/base64"
+	"errors"
 	"fmt"
 	"net"
+	"sync"
 	"time"
 
 	wgwrapper "github.com/aschmidt75/go-wg-wrapper/pkg/wgwrapper"
@@ -28,9 +30,20 @@ type MeshService struct {
 	// this range only.
 	CIDRRangeIPAM *net.IPNet
 
+	// If set, this bootstrap will assign ipv6 addresses
+	// from this range only, see CIDRRangeIPAM.
+	CIDRRangeIPAM6 *net.IPNet
+
+	// (optional) ipv6 range of a dual-stack mesh, in
+	// addition to CIDRRange. Must be an ULA range.
+	CIDRRange6 *net.IPNet
+
 	// Local mesh IP of this node
 	MeshIP net.IPNet
 
+	// (optional) local ipv6 mesh IP of this node in a dual-stack mesh
+	MeshIP6 net.IPNet
+
 	// Listen port for Wireguard
 	WireguardListenPort int
 
@@ -40,6 +53,35 @@ type MeshService struct {
 	// Own public key
 	WireguardPubKey string
 
+	// This node is behind NAT, as detected or configured. It keeps its
+	// NAT mapping open by persistent keepalives to all peers, and all
+	// peers send keepalives to it.
+	BehindNAT bool
+
+	// Keepalive interval for peers behind NAT
+	PersistentKeepalive time.Duration
+
+	// This node relays traffic between peers which are
+	// not able to complete a direct handshake
+	Relay bool
+
+	// This node offers itself as exit node, routing
+	// default traffic of other nodes to the underlay
+	IsExitNode bool
+
+	// (optional) group of this node. It only peers with nodes
+	// of groups allowed by the group policy of the mesh.
+	Group string
+
+	// Own private key, only known when read from or set on the interface
+	wireguardPrivateKey string
+
+	// new private key announced by a key rotation, not yet active
+	pendingKey *pendingKey
+
+	// scheduled switches to announced keys of members
+	keyActivations *memberKeyActivations
+
 	// The interface we're controlling
 	WireguardInterface wgwrapper.WireguardInterface
 
@@ -52,17 +94,42 @@ type MeshService struct {
 	// (optional) TLS config struct for gRPC Mesh service
 	TLSConfig *TLSConfig
 
+	// If set, joining nodes must present a pre-shared token
+	RequireToken bool
+
+	// Authenticators publish auth requirements to joining nodes
+	// and verify their responses
+	Authenticators []Authenticator
+
+	// If set, join requests wait for approval by an operator
+	RequireApproval bool
+
+	// NodeNamePolicy decides what happens when a joining node
+	// requests a name which is already in use
+	NodeNamePolicy string
+
+	// RoutePolicy decides whether routes advertised by nodes are
+	// approved automatically or wait for an operator (bootstrap only)
+	RoutePolicy string
+
 	// Serf
 	cfg               *serf.Config
 	s                 *serf.Serf
 	serfEncryptionKey []byte
+	serfModeLAN       bool
+	serfSnapshotPath  string
 
 	// if set, exports the serf member list to this file
 	memberExportFile string
 
+	// if set, persists the identity of this node to this file
+	identityFile string
+
 	// timestamp of latest update to the member state
-	lastUpdatedTS  time.Time
-	lastExportedTS time.Time
+	lastUpdatedTS       time.Time
+	lastExportedTS      time.Time
+	lastIdentitySavedTS time.Time
+	lastPeerCacheTS     time.Time
 
 	// gRPC
 	UnimplementedMeshServer
@@ -82,18 +149,95 @@ type MeshService struct {
 
 	//
 	serfEventNotifierMap map[string]SerfEventChan
+
+	// join tokens issued by Begin, waiting to be used by Join
+	joinTokens *joinTokenStore
+
+	// pre-shared tokens which joining nodes may present
+	presharedTokens *presharedTokenStore
+
+	// join requests waiting for approval
+	pendingJoins *pendingJoinStore
+
+	// ipam leases of nodes which joined via this node
+	leases *leaseStore
+
+	// wireguard details of all members as last seen in their tags
+	memberPeers *peerStore
+
+	// names and public keys of members which failed or left recently
+	leftMembers *leftMemberStore
+
+	// wireguard details of failed members, kept for rejoins
+	prunedPeers *prunedPeerStore
+
+	// leave, failed and reap events, handled one after the other
+	memberEvents *memberEventQueue
+
+	// periodic reconciliation of wireguard peers
+	reconciler *peerReconciler
+
+	// preshared key mode and mesh secret
+	psk *presharedKeys
+
+	// latest result of querying STUN servers
+	stun *stunState
+
+	// peers reached through relay nodes
+	relays *relayState
+
+	// kernel routes to subnets advertised by other nodes
+	routes *routeTable
+
+	// exit node in use, or offered by this node
+	exitNode *exitNodeState
+
+	// network policy enforced on the wireguard interface
+	acl *aclState
+
+	// which groups of nodes peer with each other
+	groupPolicy GroupPolicy
+
+	// serializes updates of the tags of this node
+	tagUpdates *sync.Mutex
 }
 
 const (
-	nodeTagPort     = "_port"
-	nodeTagAddr     = "_addr"
-	nodeTagPubKey   = "_pk"
-	nodeTagMeshIP   = "_i"
-	nodeTagNodeType = "_t"
+	nodeTagPort          = "_port"
+	nodeTagAddr          = "_addr"
+	nodeTagPubKey        = "_pk"
+	nodeTagPrevPubKey    = "_pkp"
+	nodeTagKeyActivation = "_pka"
+	nodeTagMeshIP        = "_i"
+	nodeTagMeshIP6       = "_i6"
+	nodeTagNodeType      = "_t"
+	nodeTagIPAM          = "_ipam"
+	nodeTagIPAM6         = "_ipam6"
+	nodeTagNAT           = "_nat"
+	nodeTagRelay         = "_relay"
+	nodeTagRoutes        = "_rt"
+	nodeTagRoutesPending = "_rtp"
+	nodeTagExitNode      = "_exit"
+	nodeTagGroup         = "_grp"
+	nodeTagPSKEpoch      = "_pske"
 
 	serfEventMarkerJoin   = "_j"
 	serfEventMarkerRTTReq = "_rtt0"
 	serfEventMarkerRTTRes = "_rtt1"
+	serfEventMarkerPSK    = "_psk"
+	serfEventMarkerRoutes = "_rtok"
+
+	serfQueryPSK = "_pskq"
+)
+
+const (
+	// NodeNamePolicyReject rejects joins with a node name already in use
+	NodeNamePolicyReject = "reject"
+
+	// NodeNamePolicyAutoSuffix assigns an alternative name by appending a suffix
+	NodeNamePolicyAutoSuffix = "auto-suffix"
+
+	maxNodeNameSuffix = 100
 )
 
 // SerfEventChan is a pointer to a channel of serf events,
@@ -117,6 +261,25 @@ func NewMeshService(meshName string) MeshService {
 		creationTS:           time.Now(),
 		serfEventNotifierMap: make(map[string]SerfEventChan),
 		serfEncryptionKey:    make([]byte, 0),
+		joinTokens:           newJoinTokenStore(joinTokenTTL),
+		presharedTokens:      newPresharedTokenStore(),
+		pendingJoins:         newPendingJoinStore(),
+		leases:               newLeaseStore(),
+		memberPeers:          newPeerStore(),
+		leftMembers:          newLeftMemberStore(),
+		prunedPeers:          newPrunedPeerStore(),
+		memberEvents:         newMemberEventQueue(),
+		pendingKey:           &pendingKey{},
+		keyActivations:       newMemberKeyActivations(),
+		reconciler:           newPeerReconciler(),
+		psk:                  &presharedKeys{mode: PSKModeOff},
+		stun:                 &stunState{},
+		relays:               newRelayState(),
+		routes:               newRouteTable(),
+		exitNode:             &exitNodeState{},
+		acl:                  &aclState{},
+		groupPolicy:          make(GroupPolicy),
+		tagUpdates:           &sync.Mutex{},
 	}
 }
 
@@ -127,20 +290,18 @@ func (ms *MeshService) SetNodeName(name string) {
 		return
 	}
 
-	// if no name is given, derive one from the
-	// IPv4 address
-	if len(ms.MeshIP.IP) == 16 {
-		i := int(ms.MeshIP.IP[12]) * 16777216
-		i += int(ms.MeshIP.IP[13]) * 65536
-		i += int(ms.MeshIP.IP[14]) * 256
-		i += int(ms.MeshIP.IP[15])
-		ms.NodeName = fmt.Sprintf("%s%X", ms.MeshName, i)
+	// if no name is given, derive one from the IPv4
+	// address, or the last 4 bytes of an IPv6 address
+	ip := ms.MeshIP.IP
+	if ip4 := ip.To4(); ip4 != nil {
+		ip = ip4
 	}
-	if len(ms.MeshIP.IP) == 4 {
-		i := int(ms.MeshIP.IP[0]) * 16777216
-		i += int(ms.MeshIP.IP[1]) * 65536
-		i += int(ms.MeshIP.IP[2]) * 256
-		i += int(ms.MeshIP.IP[3])
+	if len(ip) >= 4 {
+		b := ip[len(ip)-4:]
+		i := int(b[0]) * 16777216
+		i += int(b[1]) * 65536
+		i += int(b[2]) * 256
+		i += int(b[3])
 		ms.NodeName = fmt.Sprintf("%s%X", ms.MeshName, i)
 	}
 }
@@ -174,11 +335,28 @@ func (ms *MeshService) SetEncryptionKey(encKeyB64 string) error {
 	return nil
 }
 
-// GetEncryptionKey returns serf encryption key from a base64 string
+// GetEncryptionKey returns the serf encryption key as a base64 string. Once
+// serf is running, this is the primary key of its keyring, which may have
+// been changed by a key rotation.
 func (ms *MeshService) GetEncryptionKey() string {
+	if ms.cfg != nil && ms.cfg.MemberlistConfig.Keyring != nil {
+		if key := ms.cfg.MemberlistConfig.Keyring.GetPrimaryKey(); key != nil {
+			return base64.StdEncoding.EncodeToString(key)
+		}
+	}
 	return base64.StdEncoding.EncodeToString(ms.serfEncryptionKey)
 }
 
+// validateEncryptionKey checks that a serf encryption
+// key is base64-encoded and 32 bytes long
+func validateEncryptionKey(encKeyB64 string) error {
+	b, err := base64.StdEncoding.DecodeString(encKeyB64)
+	if err != nil || len(b) != 32 {
+		return errors.New("key must be 32 bytes, base64-encoded")
+	}
+	return nil
+}
+
 // Serf returns the serf instance
 func (ms *MeshService) Serf() *serf.Serf {
 	return ms.s
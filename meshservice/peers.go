@@ -0,0 +1,243 @@
+package meshservice
+
+import (
+	"bytes"
+	"fmt"
+	"net"
+	"os/exec"
+	"strconv"
+	"sync"
+	"time"
+
+	wgwrapper "github.com/aschmidt75/go-wg-wrapper/pkg/wgwrapper"
+	serf "github.com/hashicorp/serf/serf"
+	log "github.com/sirupsen/logrus"
+)
+
+// memberPeer returns the wireguard details of a member, taken from its tags
+func memberPeer(member serf.Member) KnownPeer {
+	port, _ := strconv.Atoi(member.Tags[nodeTagPort])
+	pubkey, pendingPubkey := memberPubkeys(member.Tags)
+	return KnownPeer{
+		NodeName:      member.Name,
+		Pubkey:        pubkey,
+		PendingPubkey: pendingPubkey,
+		EndpointIP:    member.Tags[nodeTagAddr],
+		EndpointPort:  port,
+		MeshIP:        member.Tags[nodeTagMeshIP],
+		MeshIP6:       member.Tags[nodeTagMeshIP6],
+		NAT:           member.Tags[nodeTagNAT] == "1",
+		Routes:        parseRoutesTag(member.Tags[nodeTagRoutes]),
+		Group:         member.Tags[nodeTagGroup],
+	}
+}
+
+// announcedPeer returns the wireguard details of a peer
+// announcement, e.g. received from a bootstrap node
+func announcedPeer(peer *Peer) KnownPeer {
+	return KnownPeer{
+		Pubkey:       peer.Pubkey,
+		EndpointIP:   peer.EndpointIP,
+		EndpointPort: int(peer.EndpointPort),
+		MeshIP:       peer.MeshIP,
+		MeshIP6:      peer.MeshIP6,
+		NAT:          peer.Nat,
+		Group:        peer.Group,
+	}
+}
+
+// wireguardPeer builds the wireguard peer configuration for a mesh node.
+// Allowed ips are its mesh ips and approved routes, and the default
+// route if it is the exit node in use. Peers behind NAT, or
+// all peers if this node is behind NAT, get a persistent keepalive, so
+// that NAT mappings do not time out.
+func (ms *MeshService) wireguardPeer(peer KnownPeer) wgwrapper.WireguardPeer {
+	p := wgwrapper.WireguardPeer{
+		RemoteEndpointIP: peer.EndpointIP,
+		ListenPort:       peer.EndpointPort,
+		Pubkey:           peer.Pubkey,
+		AllowedIPs:       append(peerAllowedIPs(peer.MeshIP, peer.MeshIP6), routeAllowedIPs(peer.Routes)...),
+		Psk:              ms.peerPSK(peer.Pubkey),
+	}
+	if peer.NodeName != "" && peer.NodeName == ms.ExitNode() {
+		p.AllowedIPs = append(p.AllowedIPs, exitNodeDefaultRoute)
+	}
+	if ms.BehindNAT || peer.NAT {
+		p.PersistentKeepaliveInterval = ms.PersistentKeepalive
+	}
+	return p
+}
+
+// peerStore keeps the wireguard details of all members as last
+// seen in their tags, by node name. Member updates are compared
+// against it to find out what has changed.
+type peerStore struct {
+	sync.Mutex
+
+	peers map[string]KnownPeer
+}
+
+func newPeerStore() *peerStore {
+	return &peerStore{
+		peers: make(map[string]KnownPeer),
+	}
+}
+
+func (s *peerStore) get(nodeName string) (KnownPeer, bool) {
+	s.Lock()
+	defer s.Unlock()
+
+	peer, ok := s.peers[nodeName]
+	return peer, ok
+}
+
+func (s *peerStore) set(peer KnownPeer) {
+	s.Lock()
+	defer s.Unlock()
+
+	s.peers[peer.NodeName] = peer
+}
+
+func (s *peerStore) remove(nodeName string) {
+	s.Lock()
+	defer s.Unlock()
+
+	delete(s.peers, nodeName)
+}
+
+const (
+	// prunedPeerTimeout is the time failed members keep their peers
+	prunedPeerTimeout = 24 * time.Hour
+)
+
+// prunedPeerStore keeps the wireguard details of failed members, by node
+// name. Their peer entries stay in place for prunedPeerTimeout, so that a
+// restarted node is able to rejoin through its known peers. Their ipam
+// leases are only released afterwards.
+type prunedPeerStore struct {
+	sync.Mutex
+
+	peers map[string]prunedPeer
+}
+
+type prunedPeer struct {
+	KnownPeer
+
+	prunedTS time.Time
+}
+
+func newPrunedPeerStore() *prunedPeerStore {
+	return &prunedPeerStore{
+		peers: make(map[string]prunedPeer),
+	}
+}
+
+func (s *prunedPeerStore) add(peer KnownPeer) {
+	s.Lock()
+	defer s.Unlock()
+
+	s.peers[peer.NodeName] = prunedPeer{
+		KnownPeer: peer,
+		prunedTS:  time.Now(),
+	}
+}
+
+func (s *prunedPeerStore) has(nodeName string) bool {
+	s.Lock()
+	defer s.Unlock()
+
+	_, ok := s.peers[nodeName]
+	return ok
+}
+
+func (s *prunedPeerStore) remove(nodeName string) {
+	s.Lock()
+	defer s.Unlock()
+
+	delete(s.peers, nodeName)
+}
+
+// list returns all pruned peers which have not expired yet
+func (s *prunedPeerStore) list() []KnownPeer {
+	s.Lock()
+	defer s.Unlock()
+
+	res := make([]KnownPeer, 0, len(s.peers))
+	for _, p := range s.peers {
+		if time.Since(p.prunedTS) <= prunedPeerTimeout {
+			res = append(res, p.KnownPeer)
+		}
+	}
+	return res
+}
+
+// expire drops and returns all pruned peers older than prunedPeerTimeout
+func (s *prunedPeerStore) expire() []KnownPeer {
+	s.Lock()
+	defer s.Unlock()
+
+	res := make([]KnownPeer, 0)
+	for name, p := range s.peers {
+		if time.Since(p.prunedTS) > prunedPeerTimeout {
+			res = append(res, p.KnownPeer)
+			delete(s.peers, name)
+		}
+	}
+	return res
+}
+
+// expirePrunedPeers removes the wireguard peers of expired pruned
+// members and releases their leases
+func (ms *MeshService) expirePrunedPeers() {
+	expired := ms.prunedPeers.expire()
+	if len(expired) == 0 {
+		return
+	}
+	desired := ms.desiredPeers()
+
+	wg := wgwrapper.New()
+	for _, peer := range expired {
+		ms.releaseLease(peer.MeshIP)
+
+		if _, ok := desired[peer.Pubkey]; ok {
+			continue
+		}
+		if err := wg.RemovePeerByPubkey(ms.WireguardInterface, peer.Pubkey); err != nil {
+			log.WithError(err).Error("unable to remove wireguard peer of pruned node")
+			continue
+		}
+		log.WithField("node", peer.NodeName).Info("Removed peer of pruned node")
+	}
+}
+
+// updatePeerAllowedIPs applies the desired allowed ips of a peer, or
+// of its relay node if the peer is relayed
+func (ms *MeshService) updatePeerAllowedIPs(pubkey string) error {
+	if relayPubkey, ok := ms.relayOf(pubkey); ok {
+		pubkey = relayPubkey
+	}
+	peer, ok := ms.desiredPeers()[pubkey]
+	if !ok {
+		return nil
+	}
+	return ms.applyDesiredPeer(peer)
+}
+
+// setPeerEndpoint changes the endpoint of an existing wireguard
+// peer, keeping its current session
+func (ms *MeshService) setPeerEndpoint(peer KnownPeer) error {
+	ip := net.ParseIP(peer.EndpointIP)
+	if ip == nil {
+		return fmt.Errorf("invalid endpoint ip %s", peer.EndpointIP)
+	}
+	endpoint := net.JoinHostPort(ip.String(), strconv.Itoa(peer.EndpointPort))
+
+	cmd := exec.Command("wg", "set", ms.WireguardInterface.InterfaceName, "peer", peer.Pubkey, "endpoint", endpoint)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		log.WithField("stderr", stderr.String()).Error("wg set reported an error")
+		return err
+	}
+	return nil
+}
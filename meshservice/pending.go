@@ -0,0 +1,101 @@
+package meshservice
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"sort"
+	"sync"
+	"time"
+)
+
+// pendingJoin is a join request which waits for an operator
+// to approve or reject it.
+type pendingJoin struct {
+	id           string
+	pubkey       string
+	endpointIP   string
+	endpointPort int
+	nodeName     string
+	certSubject  string
+	requestedAt  time.Time
+
+	// receives the decision of the operator
+	decision chan bool
+}
+
+// pendingJoinStore keeps all join requests waiting for approval
+type pendingJoinStore struct {
+	sync.Mutex
+
+	joins map[string]*pendingJoin
+}
+
+func newPendingJoinStore() *pendingJoinStore {
+	return &pendingJoinStore{
+		joins: make(map[string]*pendingJoin),
+	}
+}
+
+// add parks a join request
+func (s *pendingJoinStore) add(pubkey, endpointIP string, endpointPort int, nodeName, certSubject string) (*pendingJoin, error) {
+	b := make([]byte, 6)
+	if _, err := rand.Read(b); err != nil {
+		return nil, err
+	}
+
+	pj := &pendingJoin{
+		id:           hex.EncodeToString(b),
+		pubkey:       pubkey,
+		endpointIP:   endpointIP,
+		endpointPort: endpointPort,
+		nodeName:     nodeName,
+		certSubject:  certSubject,
+		requestedAt:  time.Now(),
+		decision:     make(chan bool, 1),
+	}
+
+	s.Lock()
+	defer s.Unlock()
+	s.joins[pj.id] = pj
+
+	return pj, nil
+}
+
+// remove drops a join request, e.g. when the joining node gave up waiting
+func (s *pendingJoinStore) remove(id string) {
+	s.Lock()
+	defer s.Unlock()
+	delete(s.joins, id)
+}
+
+// decide approves or rejects the join request with given id.
+// Returns false if there is no such request.
+func (s *pendingJoinStore) decide(id string, approve bool) bool {
+	s.Lock()
+	defer s.Unlock()
+
+	pj, ok := s.joins[id]
+	if !ok {
+		return false
+	}
+	delete(s.joins, id)
+	pj.decision <- approve
+
+	return true
+}
+
+// list returns copies of all pending join requests, oldest first
+func (s *pendingJoinStore) list() []pendingJoin {
+	s.Lock()
+	defer s.Unlock()
+
+	res := make([]pendingJoin, 0, len(s.joins))
+	for _, pj := range s.joins {
+		res = append(res, *pj)
+	}
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].requestedAt.Before(res[j].requestedAt)
+	})
+
+	return res
+}
@@ -0,0 +1,400 @@
+package meshservice
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	serf "github.com/hashicorp/serf/serf"
+	log "github.com/sirupsen/logrus"
+	"google.golang.org/protobuf/proto"
+)
+
+const (
+	// PSKModeOff creates wireguard peers without a preshared key
+	PSKModeOff = "off"
+
+	// PSKModeMesh uses the mesh secret as preshared key for all peers
+	PSKModeMesh = "mesh"
+
+	// PSKModePair derives a preshared key for each pair of nodes
+	// from the mesh secret and both public keys
+	PSKModePair = "pair"
+
+	// a rotated secret becomes active on all nodes at the same time,
+	// after it has been gossiped
+	pskActivationDelay = 10 * time.Second
+)
+
+// presharedKeys holds the mesh-wide preshared key settings
+type presharedKeys struct {
+	sync.Mutex
+
+	mode   string
+	secret []byte
+
+	// increased with every rotation of the secret
+	epoch int64
+
+	// received rotation waiting for its activation time
+	pending *PSKUpdate
+}
+
+// current returns the mode, secret and epoch in effect as update
+func (p *presharedKeys) current() *PSKUpdate {
+	return &PSKUpdate{
+		Mode:   p.mode,
+		Secret: base64.StdEncoding.EncodeToString(p.secret),
+		Epoch:  p.epoch,
+	}
+}
+
+// SetPresharedKeys sets the preshared key mode and the base64-encoded,
+// 32 bytes mesh secret. If the mode requires a secret and none is
+// given, a random secret is generated.
+func (ms *MeshService) SetPresharedKeys(mode, secretB64 string) error {
+	if mode == "" {
+		mode = PSKModeOff
+	}
+	if mode != PSKModeOff && mode != PSKModeMesh && mode != PSKModePair {
+		return fmt.Errorf("unknown preshared key mode %s", mode)
+	}
+
+	var secret []byte
+	if mode != PSKModeOff {
+		var err error
+		if secretB64 == "" {
+			secret, err = newPresharedKeySecret()
+		} else {
+			secret, err = decodePresharedKeySecret(secretB64)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	ms.psk.Lock()
+	defer ms.psk.Unlock()
+
+	ms.psk.mode = mode
+	ms.psk.secret = secret
+
+	return nil
+}
+
+// SetPresharedKeyEpoch sets the epoch of the current mesh secret
+func (ms *MeshService) SetPresharedKeyEpoch(epoch int64) {
+	ms.psk.Lock()
+	defer ms.psk.Unlock()
+
+	ms.psk.epoch = epoch
+}
+
+// PresharedKeyEpoch returns the epoch of the current mesh secret,
+// which is announced in the _pske tag
+func (ms *MeshService) PresharedKeyEpoch() int64 {
+	ms.psk.Lock()
+	defer ms.psk.Unlock()
+
+	return ms.psk.epoch
+}
+
+// PresharedKeys returns the preshared key mode and the
+// base64-encoded mesh secret, which is empty if the mode is off
+func (ms *MeshService) PresharedKeys() (string, string) {
+	ms.psk.Lock()
+	defer ms.psk.Unlock()
+
+	if ms.psk.mode == "" || ms.psk.mode == PSKModeOff {
+		return PSKModeOff, ""
+	}
+	return ms.psk.mode, base64.StdEncoding.EncodeToString(ms.psk.secret)
+}
+
+// peerPSK returns the base64-encoded preshared key for the
+// peer with given public key, or nil if the mode is off
+func (ms *MeshService) peerPSK(pubkey string) *string {
+	ms.psk.Lock()
+	defer ms.psk.Unlock()
+
+	var psk string
+	switch ms.psk.mode {
+	case PSKModeMesh:
+		psk = base64.StdEncoding.EncodeToString(ms.psk.secret)
+	case PSKModePair:
+		psk = derivePairPSK(ms.psk.secret, ms.WireguardPubKey, pubkey)
+	default:
+		return nil
+	}
+	return &psk
+}
+
+// derivePairPSK computes the preshared key of two nodes. Public keys are
+// ordered, so that both nodes derive the same preshared key.
+func derivePairPSK(secret []byte, pubkeyA, pubkeyB string) string {
+	if pubkeyA > pubkeyB {
+		pubkeyA, pubkeyB = pubkeyB, pubkeyA
+	}
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(pubkeyA + ":" + pubkeyB))
+
+	return base64.StdEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func newPresharedKeySecret() ([]byte, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+func decodePresharedKeySecret(secretB64 string) ([]byte, error) {
+	b, err := base64.StdEncoding.DecodeString(secretB64)
+	if err != nil || len(b) != 32 {
+		return nil, errors.New("preshared key secret must be 32 bytes, base64-encoded")
+	}
+	return b, nil
+}
+
+// RotatePresharedKeys generates a new mesh secret and gossips it to all
+// nodes. All nodes switch to the new secret at the same time, which is
+// returned. The secret is only protected by the serf encryption key, so
+// rotation is refused without one.
+func (ms *MeshService) RotatePresharedKeys() (time.Time, error) {
+	mode, _ := ms.PresharedKeys()
+	if mode == PSKModeOff {
+		return time.Time{}, errors.New("preshared keys are not enabled for this mesh")
+	}
+	if len(ms.serfEncryptionKey) != 32 {
+		return time.Time{}, errors.New("rotating preshared keys requires a serf encryption key")
+	}
+
+	secret, err := newPresharedKeySecret()
+	if err != nil {
+		return time.Time{}, err
+	}
+	activation := time.Now().Add(pskActivationDelay)
+
+	buf, err := proto.Marshal(&PSKUpdate{
+		Mode:         mode,
+		Secret:       base64.StdEncoding.EncodeToString(secret),
+		ActivationTS: activation.Unix(),
+		Epoch:        ms.PresharedKeyEpoch() + 1,
+	})
+	if err != nil {
+		return time.Time{}, err
+	}
+	if err = ms.Serf().UserEvent(serfEventMarkerPSK, buf, true); err != nil {
+		return time.Time{}, err
+	}
+
+	return activation, nil
+}
+
+// parses the user event as a preshared key update, and schedules
+// the new secret to be applied once it becomes active
+func (ms *MeshService) serfHandlePSKUpdateEvent(userEv serf.UserEvent) {
+	update := &PSKUpdate{}
+	err := proto.Unmarshal(userEv.Payload, update)
+	if err != nil {
+		log.WithError(err).Error("unable to unmarshal preshared key update event")
+		return
+	}
+
+	ms.psk.Lock()
+	if !newerPSKUpdate(update, ms.psk.current()) || !newerPSKUpdate(update, ms.psk.pending) {
+		ms.psk.Unlock()
+		return
+	}
+	ms.psk.pending = update
+	ms.psk.Unlock()
+
+	time.AfterFunc(time.Until(time.Unix(update.ActivationTS, 0)), func() {
+		// skip it if a concurrent rotation superseded it
+		ms.psk.Lock()
+		superseded := ms.psk.pending != update
+		ms.psk.Unlock()
+		if !superseded {
+			ms.activatePresharedKeys(update)
+		}
+	})
+}
+
+// newerPSKUpdate tells whether preshared key update a supersedes b, which
+// may be nil. Updates are ordered by epoch. Concurrent rotations end up
+// with the same epoch, these are ordered by the hash of their secret, so
+// that all nodes settle on the same secret.
+func newerPSKUpdate(a, b *PSKUpdate) bool {
+	if b == nil || a.Epoch != b.Epoch {
+		return b == nil || a.Epoch > b.Epoch
+	}
+	return bytes.Compare(pskSecretHash(a.Secret), pskSecretHash(b.Secret)) > 0
+}
+
+func pskSecretHash(secretB64 string) []byte {
+	secret, err := base64.StdEncoding.DecodeString(secretB64)
+	if err != nil {
+		return nil
+	}
+	h := sha256.Sum256(secret)
+	return h[:]
+}
+
+// activatePresharedKeys applies the secret of a preshared key update to
+// all peers and announces its epoch, unless it is outdated
+func (ms *MeshService) activatePresharedKeys(update *PSKUpdate) {
+	ms.psk.Lock()
+	outdated := !newerPSKUpdate(update, ms.psk.current())
+	ms.psk.Unlock()
+	if outdated {
+		return
+	}
+	if err := ms.SetPresharedKeys(update.Mode, update.Secret); err != nil {
+		log.WithError(err).Error("unable to apply preshared key update")
+
+		// let a later update or fetch of the secret take over
+		ms.psk.Lock()
+		if ms.psk.pending == update {
+			ms.psk.pending = nil
+		}
+		ms.psk.Unlock()
+		return
+	}
+	ms.SetPresharedKeyEpoch(update.Epoch)
+	ms.applyPresharedKeys()
+
+	err := ms.updateTags(func(tags map[string]string) error {
+		tags[nodeTagPSKEpoch] = strconv.FormatInt(update.Epoch, 10)
+		return nil
+	})
+	if err != nil {
+		log.WithError(err).Error("unable to announce preshared key epoch")
+	}
+	ms.SaveIdentity()
+
+	log.WithField("epoch", update.Epoch).Info("Rotated preshared keys")
+}
+
+// checkPresharedKeyEpoch compares the epoch of the mesh secret announced
+// by members against the own one. A node which missed a rotation, e.g.
+// as it was down or joined during the rotation, fetches the current
+// secret from a member announcing a newer epoch.
+func (ms *MeshService) checkPresharedKeyEpoch(members []serf.Member) {
+	ms.psk.Lock()
+	current := ms.psk.epoch
+	if ms.psk.pending != nil && ms.psk.pending.Epoch > current {
+		current = ms.psk.pending.Epoch
+	}
+	ms.psk.Unlock()
+
+	for _, member := range members {
+		epoch, err := strconv.ParseInt(member.Tags[nodeTagPSKEpoch], 10, 64)
+		if err != nil || epoch <= current || member.Status != serf.StatusAlive {
+			continue
+		}
+		if err = ms.fetchPresharedKeys(member.Name); err != nil {
+			log.WithError(err).WithField("node", member.Name).Warn("Unable to fetch current preshared key secret")
+			continue
+		}
+		return
+	}
+}
+
+// fetchPresharedKeys queries a member for the current mesh secret and
+// applies it right away
+func (ms *MeshService) fetchPresharedKeys(nodeName string) error {
+	if len(ms.serfEncryptionKey) != 32 {
+		return errors.New("fetching preshared keys requires a serf encryption key")
+	}
+
+	resp, err := ms.Serf().Query(serfQueryPSK, nil, &serf.QueryParam{
+		FilterNodes: []string{nodeName},
+	})
+	if err != nil {
+		return err
+	}
+	defer resp.Close()
+
+	for r := range resp.ResponseCh() {
+		update := &PSKUpdate{}
+		if err = proto.Unmarshal(r.Payload, update); err != nil {
+			return err
+		}
+		ms.activatePresharedKeys(update)
+		return nil
+	}
+	return fmt.Errorf("no response from %s", nodeName)
+}
+
+// answers a query for the current mesh secret. Queries and responses
+// are protected by the serf encryption key, nodes without one do not
+// give out the secret.
+func (ms *MeshService) serfHandlePSKQuery(q *serf.Query) {
+	if len(ms.serfEncryptionKey) != 32 {
+		log.Warn("Not answering preshared key query without a serf encryption key")
+		return
+	}
+	mode, secret := ms.PresharedKeys()
+	if mode == PSKModeOff {
+		return
+	}
+
+	buf, err := proto.Marshal(&PSKUpdate{
+		Mode:         mode,
+		Secret:       secret,
+		ActivationTS: time.Now().Unix(),
+		Epoch:        ms.PresharedKeyEpoch(),
+	})
+	if err != nil {
+		log.WithError(err).Error("unable to marshal preshared key query response")
+		return
+	}
+	if err = q.Respond(buf); err != nil {
+		log.WithError(err).Error("unable to respond to preshared key query")
+	}
+}
+
+// applyPresharedKeys sets the preshared key of all peers of the
+// wireguard interface, e.g. after the secret or own key changed
+func (ms *MeshService) applyPresharedKeys() {
+	peers, err := ms.interfacePeers()
+	if err != nil {
+		log.WithError(err).Error("Unable to read wireguard peers")
+		return
+	}
+	for pubkey := range peers {
+		if err := ms.setPeerPSK(pubkey, ms.peerPSK(pubkey)); err != nil {
+			log.WithError(err).WithField("pk", pubkey).Error("unable to set preshared key of peer")
+		}
+	}
+}
+
+// setPeerPSK sets the preshared key of an existing wireguard
+// peer. A nil psk removes the preshared key.
+func (ms *MeshService) setPeerPSK(pubkey string, psk *string) error {
+	keyFile := "/dev/null"
+	if psk != nil {
+		keyFile = "/dev/stdin"
+	}
+	cmd := exec.Command("wg", "set", ms.WireguardInterface.InterfaceName, "peer", pubkey, "preshared-key", keyFile)
+	if psk != nil {
+		cmd.Stdin = strings.NewReader(*psk)
+	}
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		log.WithField("stderr", stderr.String()).Error("wg set reported an error")
+		return err
+	}
+	return nil
+}
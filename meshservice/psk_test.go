@@ -0,0 +1,103 @@
+package meshservice
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	serf "github.com/hashicorp/serf/serf"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestDerivePairPSK(t *testing.T) {
+	secret := bytes.Repeat([]byte{1}, 32)
+	otherSecret := bytes.Repeat([]byte{2}, 32)
+
+	tests := []struct {
+		a, b string
+	}{
+		{"pkA", "pkB"},
+		{"pkB", "pkA"},
+		{"pkA", "pkC"},
+		{"", "pkA"},
+	}
+
+	seen := make(map[string]string)
+	for _, tt := range tests {
+		psk := derivePairPSK(secret, tt.a, tt.b)
+		if psk != derivePairPSK(secret, tt.b, tt.a) {
+			t.Errorf("%s/%s: preshared key is not symmetric", tt.a, tt.b)
+		}
+		if psk == derivePairPSK(otherSecret, tt.a, tt.b) {
+			t.Errorf("%s/%s: preshared key does not depend on the secret", tt.a, tt.b)
+		}
+		if len(psk) != 44 {
+			t.Errorf("%s/%s: preshared key %s is not 32 bytes, base64-encoded", tt.a, tt.b, psk)
+		}
+
+		pair := tt.a + ":" + tt.b
+		if tt.a > tt.b {
+			pair = tt.b + ":" + tt.a
+		}
+		if other, ok := seen[psk]; ok && other != pair {
+			t.Errorf("%s/%s: same preshared key as %s", tt.a, tt.b, other)
+		}
+		seen[psk] = pair
+	}
+}
+
+func TestNewerPSKUpdate(t *testing.T) {
+	secretA := "AQEBAQEBAQEBAQEBAQEBAQEBAQEBAQEBAQEBAQEBAQE="
+	secretB := "AgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgI="
+
+	tests := []struct {
+		a, b  *PSKUpdate
+		newer bool
+	}{
+		{&PSKUpdate{Epoch: 1, Secret: secretA}, nil, true},
+		{&PSKUpdate{Epoch: 2, Secret: secretA}, &PSKUpdate{Epoch: 1, Secret: secretB}, true},
+		{&PSKUpdate{Epoch: 1, Secret: secretB}, &PSKUpdate{Epoch: 2, Secret: secretA}, false},
+		{&PSKUpdate{Epoch: 1, Secret: secretA}, &PSKUpdate{Epoch: 1, Secret: secretA}, false},
+	}
+
+	for idx, tt := range tests {
+		if got := newerPSKUpdate(tt.a, tt.b); got != tt.newer {
+			t.Errorf("%d: newer is %t, want %t", idx, got, tt.newer)
+		}
+	}
+
+	// concurrent rotations of the same epoch are ordered the same way
+	// on all nodes, regardless of the order they arrive in
+	a := &PSKUpdate{Epoch: 3, Secret: secretA}
+	b := &PSKUpdate{Epoch: 3, Secret: secretB}
+	if newerPSKUpdate(a, b) == newerPSKUpdate(b, a) {
+		t.Errorf("concurrent rotations of the same epoch are not ordered")
+	}
+}
+
+func TestHandlePSKUpdateOrder(t *testing.T) {
+	activation := time.Now().Add(time.Hour).Unix()
+	a := &PSKUpdate{Mode: PSKModeMesh, Secret: "AQEBAQEBAQEBAQEBAQEBAQEBAQEBAQEBAQEBAQEBAQE=", Epoch: 1, ActivationTS: activation}
+	b := &PSKUpdate{Mode: PSKModeMesh, Secret: "AgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgI=", Epoch: 1, ActivationTS: activation}
+	winner := a
+	if newerPSKUpdate(b, a) {
+		winner = b
+	}
+
+	for _, order := range [][]*PSKUpdate{{a, b}, {b, a}} {
+		ms := NewMeshService("test")
+		if err := ms.SetPresharedKeys(PSKModeMesh, ""); err != nil {
+			t.Fatalf("unable to set preshared keys: %s", err)
+		}
+		for _, update := range order {
+			buf, err := proto.Marshal(update)
+			if err != nil {
+				t.Fatalf("unable to marshal update: %s", err)
+			}
+			ms.serfHandlePSKUpdateEvent(serf.UserEvent{Name: serfEventMarkerPSK, Payload: buf})
+		}
+		if ms.psk.pending == nil || ms.psk.pending.Secret != winner.Secret {
+			t.Errorf("rotations arriving in different order settle on different secrets")
+		}
+	}
+}
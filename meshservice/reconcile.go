@@ -0,0 +1,357 @@
+package meshservice
+
+import (
+	"bytes"
+	"net"
+	"os/exec"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	wgwrapper "github.com/aschmidt75/go-wg-wrapper/pkg/wgwrapper"
+	serf "github.com/hashicorp/serf/serf"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// peers which are not backed by an alive member are only removed after
+	// this period, as nodes are added as peers shortly before they join serf
+	reconcileStaleGrace = 1 * time.Minute
+
+	// endpoints of peers with a handshake within this period are not
+	// corrected, as wireguard may have learned a (NATed) endpoint by roaming
+	reconcileHandshakeTimeout = 3 * time.Minute
+)
+
+// ReconcileStats counts the drift between the serf members and the
+// wireguard peers found and fixed by the peer reconciler
+type ReconcileStats struct {
+	Runs            uint64
+	Added           uint64
+	Removed         uint64
+	AllowedIPsFixed uint64
+	EndpointsFixed  uint64
+	PSKsFixed       uint64
+	KeepalivesFixed uint64
+	Errors          uint64
+	LastRunTS       int64
+	LastDriftTS     int64
+}
+
+// peerReconciler keeps the state of the periodic peer reconciliation
+type peerReconciler struct {
+	sync.Mutex
+
+	stats ReconcileStats
+
+	// peers not backed by an alive member, by public key
+	staleSince map[string]time.Time
+}
+
+func newPeerReconciler() *peerReconciler {
+	return &peerReconciler{
+		staleSince: make(map[string]time.Time),
+	}
+}
+
+// interfacePeer is a wireguard peer as configured on the interface
+type interfacePeer struct {
+	pubkey          string
+	psk             string
+	endpoint        string
+	allowedIPs      []string
+	latestHandshake time.Time
+	transferTx      int64
+	keepalive       time.Duration
+}
+
+// ReconcileStats returns a copy of the current reconciler statistics
+func (ms *MeshService) ReconcileStats() ReconcileStats {
+	ms.reconciler.Lock()
+	defer ms.reconciler.Unlock()
+
+	return ms.reconciler.stats
+}
+
+// StartPeerReconciler periodically compares the alive members of the serf
+// cluster with the peers configured on the wireguard interface. Missing
+// peers are added, stale ones removed and wrong allowed ips, preshared keys,
+// keepalives or endpoints are corrected, so that lost events or failed operations do
+// not make the interface drift. Routes to subnets of other nodes are synced as well.
+func (ms *MeshService) StartPeerReconciler(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+
+	go func() {
+		for range ticker.C {
+			ms.reconcilePeers()
+			ms.syncRoutes()
+		}
+	}()
+}
+
+// desiredPeers returns the wireguard peers this node should have, by
+// public key. These are all alive members except this node which the
+// group policy allows to peer with, with mesh ips of relayed peers
+// moved to their relay node. Members with a pending key rotation
+// have an additional entry for their new key, without allowed ips.
+// Failed members keep their peers during the prune delay and for
+// prunedPeerTimeout after being pruned.
+func (ms *MeshService) desiredPeers() map[string]wgwrapper.WireguardPeer {
+	return ms.desiredPeersOf(ms.Serf().Members())
+}
+
+// desiredPeersOf returns the wireguard peers this node should have
+// given the members of the mesh, see desiredPeers
+func (ms *MeshService) desiredPeersOf(members []serf.Member) map[string]wgwrapper.WireguardPeer {
+	res := make(map[string]wgwrapper.WireguardPeer)
+	names := make(map[string]bool)
+	meshIPs := make(map[string]bool)
+	for _, member := range members {
+		if member.Name == ms.NodeName {
+			continue
+		}
+		if member.Status != serf.StatusAlive && !(member.Status == serf.StatusFailed && ms.failedPruneDelay() > 0) {
+			continue
+		}
+		peer := memberPeer(member)
+		names[member.Name] = true
+		meshIPs[peer.MeshIP] = true
+		if peer.Pubkey == "" || !ms.peersWith(peer.Group) {
+			continue
+		}
+		res[peer.Pubkey] = ms.wireguardPeer(peer)
+		if peer.PendingPubkey != "" {
+			res[peer.PendingPubkey] = ms.pendingWireguardPeer(peer)
+		}
+	}
+
+	// pruned members keep their peers, unless a member took over their
+	// name or mesh ip
+	for _, peer := range ms.prunedPeers.list() {
+		if _, ok := res[peer.Pubkey]; ok || names[peer.NodeName] || meshIPs[peer.MeshIP] {
+			continue
+		}
+		if !ms.peersWith(peer.Group) {
+			continue
+		}
+		res[peer.Pubkey] = ms.wireguardPeer(peer)
+	}
+	ms.applyRelays(res)
+	return res
+}
+
+func (ms *MeshService) reconcilePeers() {
+	if ms.Serf() == nil {
+		return
+	}
+
+	current, err := ms.interfacePeers()
+	if err != nil {
+		log.WithError(err).Error("Unable to read wireguard peers")
+		ms.reconciler.Lock()
+		ms.reconciler.stats.Errors++
+		ms.reconciler.Unlock()
+		return
+	}
+	desired := ms.desiredPeers()
+
+	ms.reconciler.Lock()
+	defer ms.reconciler.Unlock()
+
+	stats := &ms.reconciler.stats
+	stats.Runs++
+	stats.LastRunTS = time.Now().Unix()
+	drift := false
+
+	wg := wgwrapper.New()
+
+	for pubkey, peer := range desired {
+		cur, ok := current[pubkey]
+		if !ok {
+			drift = true
+			ok, err := wg.AddPeer(ms.WireguardInterface, peer)
+			if err != nil || !ok {
+				log.WithError(err).WithField("pk", pubkey).Error("reconcile: unable to add missing peer")
+				stats.Errors++
+				continue
+			}
+			log.WithField("pk", pubkey).Info("reconcile: added missing peer")
+			stats.Added++
+			continue
+		}
+
+		allowedIPs := make([]string, 0, len(peer.AllowedIPs))
+		for _, a := range peer.AllowedIPs {
+			allowedIPs = append(allowedIPs, a.String())
+		}
+		sort.Strings(allowedIPs)
+		if strings.Join(allowedIPs, ",") != strings.Join(cur.allowedIPs, ",") {
+			drift = true
+			if err := ms.setPeerAllowedIPs(pubkey, allowedIPs); err != nil {
+				log.WithError(err).WithField("pk", pubkey).Error("reconcile: unable to fix allowed ips of peer")
+				stats.Errors++
+			} else {
+				log.WithField("pk", pubkey).Info("reconcile: fixed allowed ips of peer")
+				stats.AllowedIPsFixed++
+			}
+		}
+
+		psk := "(none)"
+		if peer.Psk != nil {
+			psk = *peer.Psk
+		}
+		if psk != cur.psk {
+			drift = true
+			if err := ms.setPeerPSK(pubkey, peer.Psk); err != nil {
+				log.WithError(err).WithField("pk", pubkey).Error("reconcile: unable to fix preshared key of peer")
+				stats.Errors++
+			} else {
+				log.WithField("pk", pubkey).Info("reconcile: fixed preshared key of peer")
+				stats.PSKsFixed++
+			}
+		}
+
+		if peer.PersistentKeepaliveInterval != cur.keepalive {
+			drift = true
+			if err := ms.setPeerKeepalive(pubkey, peer.PersistentKeepaliveInterval); err != nil {
+				log.WithError(err).WithField("pk", pubkey).Error("reconcile: unable to fix persistent keepalive of peer")
+				stats.Errors++
+			} else {
+				log.WithField("pk", pubkey).Info("reconcile: fixed persistent keepalive of peer")
+				stats.KeepalivesFixed++
+			}
+		}
+
+		if peer.RemoteEndpointIP == "" || time.Since(cur.latestHandshake) < reconcileHandshakeTimeout {
+			continue
+		}
+		endpoint := net.JoinHostPort(peer.RemoteEndpointIP, strconv.Itoa(peer.ListenPort))
+		if endpoint != cur.endpoint {
+			drift = true
+			if err := ms.setPeerEndpoint(KnownPeer{
+				Pubkey:       pubkey,
+				EndpointIP:   peer.RemoteEndpointIP,
+				EndpointPort: peer.ListenPort,
+			}); err != nil {
+				log.WithError(err).WithField("pk", pubkey).Error("reconcile: unable to fix endpoint of peer")
+				stats.Errors++
+			} else {
+				log.WithField("pk", pubkey).Info("reconcile: fixed endpoint of peer")
+				stats.EndpointsFixed++
+			}
+		}
+	}
+
+	for pubkey := range current {
+		if _, ok := desired[pubkey]; ok {
+			delete(ms.reconciler.staleSince, pubkey)
+			continue
+		}
+		since, ok := ms.reconciler.staleSince[pubkey]
+		if !ok {
+			ms.reconciler.staleSince[pubkey] = time.Now()
+			continue
+		}
+		if time.Since(since) < reconcileStaleGrace {
+			continue
+		}
+
+		drift = true
+		if err := wg.RemovePeerByPubkey(ms.WireguardInterface, pubkey); err != nil {
+			log.WithError(err).WithField("pk", pubkey).Error("reconcile: unable to remove stale peer")
+			stats.Errors++
+			continue
+		}
+		log.WithField("pk", pubkey).Info("reconcile: removed stale peer")
+		delete(ms.reconciler.staleSince, pubkey)
+		stats.Removed++
+	}
+	for pubkey := range ms.reconciler.staleSince {
+		if _, ok := current[pubkey]; !ok {
+			delete(ms.reconciler.staleSince, pubkey)
+		}
+	}
+
+	if drift {
+		stats.LastDriftTS = stats.LastRunTS
+	}
+}
+
+// interfacePeers reads all peers of the wireguard interface, by public key
+func (ms *MeshService) interfacePeers() (map[string]interfacePeer, error) {
+	cmd := exec.Command("wg", "show", ms.WireguardInterface.InterfaceName, "dump")
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		log.WithField("stderr", stderr.String()).Error("wg show reported an error")
+		return nil, err
+	}
+
+	// the first line describes the interface, all following lines are peers:
+	// public-key, preshared-key, endpoint, allowed-ips, latest-handshake,
+	// transfer-rx, transfer-tx, persistent-keepalive
+	res := make(map[string]interfacePeer)
+	lines := strings.Split(strings.TrimSpace(stdout.String()), "\n")
+	for _, line := range lines[1:] {
+		fields := strings.Split(line, "\t")
+		if len(fields) < 5 {
+			continue
+		}
+		peer := interfacePeer{
+			pubkey:     fields[0],
+			psk:        fields[1],
+			endpoint:   fields[2],
+			allowedIPs: make([]string, 0),
+		}
+		if fields[3] != "(none)" {
+			peer.allowedIPs = strings.Split(fields[3], ",")
+			sort.Strings(peer.allowedIPs)
+		}
+		if ts, err := strconv.ParseInt(fields[4], 10, 64); err == nil && ts > 0 {
+			peer.latestHandshake = time.Unix(ts, 0)
+		}
+		if len(fields) >= 7 {
+			peer.transferTx, _ = strconv.ParseInt(fields[6], 10, 64)
+		}
+		if len(fields) >= 8 {
+			if secs, err := strconv.Atoi(fields[7]); err == nil {
+				peer.keepalive = time.Duration(secs) * time.Second
+			}
+		}
+		res[peer.pubkey] = peer
+	}
+	return res, nil
+}
+
+// setPeerAllowedIPs replaces the allowed ips of an existing wireguard peer
+func (ms *MeshService) setPeerAllowedIPs(pubkey string, allowedIPs []string) error {
+	cmd := exec.Command("wg", "set", ms.WireguardInterface.InterfaceName, "peer", pubkey, "allowed-ips", strings.Join(allowedIPs, ","))
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		log.WithField("stderr", stderr.String()).Error("wg set reported an error")
+		return err
+	}
+	return nil
+}
+
+// setPeerKeepalive sets the persistent keepalive interval of an
+// existing wireguard peer. An interval of 0 turns it off.
+func (ms *MeshService) setPeerKeepalive(pubkey string, interval time.Duration) error {
+	keepalive := "off"
+	if interval > 0 {
+		keepalive = strconv.Itoa(int(interval / time.Second))
+	}
+
+	cmd := exec.Command("wg", "set", ms.WireguardInterface.InterfaceName, "peer", pubkey, "persistent-keepalive", keepalive)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		log.WithField("stderr", stderr.String()).Error("wg set reported an error")
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,99 @@
+package meshservice
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	serf "github.com/hashicorp/serf/serf"
+)
+
+func TestDesiredPeers(t *testing.T) {
+	activation := strconv.FormatInt(time.Now().Add(1*time.Hour).Unix(), 10)
+	members := []serf.Member{
+		{Name: "a", Status: serf.StatusAlive, Tags: map[string]string{nodeTagPubKey: "pkA", nodeTagMeshIP: "10.232.0.1"}},
+		{Name: "b", Status: serf.StatusAlive, Tags: map[string]string{nodeTagPubKey: "pkB", nodeTagMeshIP: "10.232.0.2", nodeTagMeshIP6: "fd00:232::2", nodeTagRoutes: "192.168.1.0/24"}},
+		{Name: "c", Status: serf.StatusFailed, Tags: map[string]string{nodeTagPubKey: "pkC", nodeTagMeshIP: "10.232.0.3"}},
+		{Name: "d", Status: serf.StatusLeft, Tags: map[string]string{nodeTagPubKey: "pkD", nodeTagMeshIP: "10.232.0.4"}},
+		{Name: "e", Status: serf.StatusAlive, Tags: map[string]string{nodeTagPubKey: "pkE", nodeTagMeshIP: "10.232.0.5", nodeTagGroup: "edge"}},
+		{Name: "f", Status: serf.StatusAlive, Tags: map[string]string{nodeTagMeshIP: "10.232.0.6"}},
+		{Name: "g", Status: serf.StatusAlive, Tags: map[string]string{nodeTagPubKey: "pkG2", nodeTagPrevPubKey: "pkG1", nodeTagKeyActivation: activation, nodeTagMeshIP: "10.232.0.7"}},
+	}
+	pruned := []KnownPeer{
+		{NodeName: "h", Pubkey: "pkH", MeshIP: "10.232.0.8"},
+		// name or mesh ip taken over by an alive member
+		{NodeName: "b", Pubkey: "pkB0", MeshIP: "10.232.0.9"},
+		{NodeName: "i", Pubkey: "pkI", MeshIP: "10.232.0.2"},
+		{NodeName: "j", Pubkey: "pkJ", MeshIP: "10.232.0.10", Group: "edge"},
+	}
+
+	tests := []struct {
+		policy string
+		group  string
+		want   []string
+	}{
+		// without a policy, failed and left members are dropped
+		{"", "", []string{
+			"pkB=10.232.0.2/32,fd00:232::2/128,192.168.1.0/24",
+			"pkE=10.232.0.5/32",
+			"pkG1=10.232.0.7/32",
+			"pkG2=",
+			"pkH=10.232.0.8/32",
+			"pkJ=10.232.0.10/32",
+		}},
+		// an isolating policy keeps failed members during the prune delay
+		{"edge:core,core:*", "edge", []string{
+			"pkB=10.232.0.2/32,fd00:232::2/128,192.168.1.0/24",
+			"pkC=10.232.0.3/32",
+			"pkG1=10.232.0.7/32",
+			"pkG2=",
+			"pkH=10.232.0.8/32",
+		}},
+		{"edge:core,core:*", "core", []string{
+			"pkB=10.232.0.2/32,fd00:232::2/128,192.168.1.0/24",
+			"pkC=10.232.0.3/32",
+			"pkE=10.232.0.5/32",
+			"pkG1=10.232.0.7/32",
+			"pkG2=",
+			"pkH=10.232.0.8/32",
+			"pkJ=10.232.0.10/32",
+		}},
+		// a non isolating policy prunes failed members right away
+		{"core:*,edge:*", "edge", []string{
+			"pkB=10.232.0.2/32,fd00:232::2/128,192.168.1.0/24",
+			"pkE=10.232.0.5/32",
+			"pkG1=10.232.0.7/32",
+			"pkG2=",
+			"pkH=10.232.0.8/32",
+			"pkJ=10.232.0.10/32",
+		}},
+	}
+
+	for _, tt := range tests {
+		ms := newTestIPAMService(t, "10.232.0.0/16", "", "10.232.0.1")
+		ms.NodeName = "a"
+		ms.Group = tt.group
+		if err := ms.SetGroupPolicy(tt.policy); err != nil {
+			t.Fatalf("%q: unexpected error %s", tt.policy, err)
+		}
+		for _, peer := range pruned {
+			ms.prunedPeers.add(peer)
+		}
+
+		got := make([]string, 0)
+		for pubkey, peer := range ms.desiredPeersOf(members) {
+			allowedIPs := make([]string, 0, len(peer.AllowedIPs))
+			for _, a := range peer.AllowedIPs {
+				allowedIPs = append(allowedIPs, a.String())
+			}
+			got = append(got, pubkey+"="+strings.Join(allowedIPs, ","))
+		}
+		sort.Strings(got)
+
+		if strings.Join(got, " ") != strings.Join(tt.want, " ") {
+			t.Errorf("%q as %q: got\n%s\nwant\n%s", tt.policy, tt.group, strings.Join(got, "\n"), strings.Join(tt.want, "\n"))
+		}
+	}
+}
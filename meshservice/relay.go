@@ -0,0 +1,325 @@
+package meshservice
+
+import (
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"sync"
+	"time"
+
+	wgwrapper "github.com/aschmidt75/go-wg-wrapper/pkg/wgwrapper"
+	serf "github.com/hashicorp/serf/serf"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// how often the handshakes of all peers are checked
+	relayCheckInterval = 15 * time.Second
+
+	// keepalive interval for relayed peers, so that a direct handshake is
+	// still attempted, in case no persistent keepalive is configured
+	relayProbeKeepalive = 25 * time.Second
+)
+
+// RelayedPeer is a peer whose traffic goes through a relay node,
+// as it did not complete a direct handshake
+type RelayedPeer struct {
+	NodeName      string
+	RelayNodeName string
+	SinceTS       int64
+}
+
+// relayedPeer keeps the relay of a peer, by public keys
+type relayedPeer struct {
+	RelayedPeer
+
+	relayPubkey string
+}
+
+// relayState keeps track of peers which are unreachable
+// and of the relay nodes their traffic is routed through
+type relayState struct {
+	sync.Mutex
+
+	timeout time.Duration
+
+	// relayed peers, by public key
+	relayed map[string]relayedPeer
+
+	// peers which have been sent traffic, but did not complete a
+	// handshake since, by public key
+	unreachableSince map[string]time.Time
+
+	// transmitted bytes of all peers at the last check, by public key
+	transferTx map[string]int64
+}
+
+func newRelayState() *relayState {
+	return &relayState{
+		relayed:          make(map[string]relayedPeer),
+		unreachableSince: make(map[string]time.Time),
+		transferTx:       make(map[string]int64),
+	}
+}
+
+// RelayedPeers returns all peers currently reached through a relay node
+func (ms *MeshService) RelayedPeers() []RelayedPeer {
+	ms.relays.Lock()
+	defer ms.relays.Unlock()
+
+	res := make([]RelayedPeer, 0, len(ms.relays.relayed))
+	for _, rp := range ms.relays.relayed {
+		res = append(res, rp.RelayedPeer)
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].NodeName < res[j].NodeName })
+	return res
+}
+
+// EnableForwarding turns on ip forwarding, so that this node is able to relay
+// traffic between peers or route it into advertised subnets. As relayed packets
+// leave through the interface they came in, ICMP redirects are disabled for
+// the wireguard interface.
+func (ms *MeshService) EnableForwarding() error {
+	settings := map[string]string{
+		"/proc/sys/net/ipv4/ip_forward": "1",
+		fmt.Sprintf("/proc/sys/net/ipv4/conf/%s/send_redirects", ms.WireguardInterface.InterfaceName): "0",
+	}
+	if ms.MeshIP6.IP != nil {
+		settings["/proc/sys/net/ipv6/conf/all/forwarding"] = "1"
+	}
+
+	for path, value := range settings {
+		if err := ioutil.WriteFile(path, []byte(value), 0644); err != nil {
+			return fmt.Errorf("unable to write %s: %s", path, err)
+		}
+	}
+	return nil
+}
+
+// StartRelayMonitor periodically checks the handshakes of all peers. Traffic
+// to peers which have been sent traffic, but did not complete a handshake
+// within timeout, is routed through a relay node, by moving their mesh ips
+// into the allowed ips of the relay. Idle peers are never relayed. As soon
+// as a direct handshake succeeds again, the peer is reached directly.
+func (ms *MeshService) StartRelayMonitor(timeout time.Duration) {
+	ms.relays.Lock()
+	ms.relays.timeout = timeout
+	ms.relays.Unlock()
+
+	ticker := time.NewTicker(relayCheckInterval)
+
+	go func() {
+		for range ticker.C {
+			ms.checkRelays()
+		}
+	}()
+}
+
+// chooseRelay returns the name and public key of the alive relay node with
+// the lowest node name, except this node. It only depends on the member list
+// which is shared by all nodes, so both sides of an unreachable pair choose
+// the same relay, whether they are able to reach it or not.
+func (ms *MeshService) chooseRelay() (string, string) {
+	relayName, relayPubkey := "", ""
+	for _, member := range ms.Serf().Members() {
+		if member.Name == ms.NodeName || member.Status != serf.StatusAlive {
+			continue
+		}
+		if member.Tags[nodeTagRelay] != "1" || !ms.peersWith(member.Tags[nodeTagGroup]) {
+			continue
+		}
+		pubkey, _ := memberPubkeys(member.Tags)
+		if pubkey == "" {
+			continue
+		}
+		if relayName == "" || member.Name < relayName {
+			relayName, relayPubkey = member.Name, pubkey
+		}
+	}
+	return relayName, relayPubkey
+}
+
+func (ms *MeshService) checkRelays() {
+	if ms.Serf() == nil || ms.Relay {
+		// relay nodes talk to all peers directly
+		return
+	}
+
+	current, err := ms.interfacePeers()
+	if err != nil {
+		log.WithError(err).Error("Unable to read wireguard peers")
+		return
+	}
+	relayName, relayPubkey := ms.chooseRelay()
+
+	ms.relays.Lock()
+	timeout := ms.relays.timeout
+
+	changed := make([]string, 0)
+	alive := make(map[string]bool)
+	for _, member := range ms.Serf().Members() {
+		pubkey, _ := memberPubkeys(member.Tags)
+		if member.Name == ms.NodeName || member.Status != serf.StatusAlive || pubkey == "" {
+			continue
+		}
+		cur, ok := current[pubkey]
+		if !ok {
+			continue
+		}
+		alive[pubkey] = true
+
+		// traffic has been sent since the last check
+		tx, seen := ms.relays.transferTx[pubkey]
+		sent := seen && cur.transferTx > tx
+		ms.relays.transferTx[pubkey] = cur.transferTx
+
+		rp, relayed := ms.relays.relayed[pubkey]
+		since, waiting := ms.relays.unreachableSince[pubkey]
+		if waiting && cur.latestHandshake.After(since) {
+			delete(ms.relays.unreachableSince, pubkey)
+			if relayed {
+				log.WithField("node", member.Name).Info("Direct handshake succeeded, no longer relaying peer")
+				delete(ms.relays.relayed, pubkey)
+				changed = append(changed, pubkey, rp.relayPubkey)
+			}
+			continue
+		}
+		if !waiting {
+			// a peer is only unreachable if traffic is sent to it
+			// without a valid session, and no handshake follows.
+			// Idle peers without a recent handshake are fine.
+			if !sent || (!cur.latestHandshake.IsZero() && time.Since(cur.latestHandshake) < timeout) {
+				continue
+			}
+			ms.relays.unreachableSince[pubkey] = time.Now().Add(-relayCheckInterval)
+			continue
+		}
+		if member.Tags[nodeTagRelay] == "1" {
+			// relays are not reached through other relays
+			continue
+		}
+		if !relayed && time.Since(since) < timeout {
+			continue
+		}
+
+		if relayName == "" {
+			if relayed {
+				delete(ms.relays.relayed, pubkey)
+				changed = append(changed, pubkey, rp.relayPubkey)
+			}
+			log.WithField("node", member.Name).Debug("Peer is unreachable, but no relay is available")
+			continue
+		}
+		if relayed && rp.relayPubkey == relayPubkey {
+			continue
+		}
+
+		log.WithFields(log.Fields{
+			"node":  member.Name,
+			"relay": relayName,
+		}).Info("No direct handshake with peer, relaying its traffic")
+		if relayed {
+			changed = append(changed, rp.relayPubkey)
+		}
+		ms.relays.relayed[pubkey] = relayedPeer{
+			RelayedPeer: RelayedPeer{
+				NodeName:      member.Name,
+				RelayNodeName: relayName,
+				SinceTS:       time.Now().Unix(),
+			},
+			relayPubkey: relayPubkey,
+		}
+		changed = append(changed, relayPubkey, pubkey)
+	}
+
+	// forget peers which left the mesh. Their relay gives back their ips.
+	for pubkey, rp := range ms.relays.relayed {
+		if !alive[pubkey] {
+			delete(ms.relays.relayed, pubkey)
+			changed = append(changed, rp.relayPubkey)
+		}
+	}
+	for pubkey := range ms.relays.unreachableSince {
+		if !alive[pubkey] {
+			delete(ms.relays.unreachableSince, pubkey)
+		}
+	}
+	for pubkey := range ms.relays.transferTx {
+		if !alive[pubkey] {
+			delete(ms.relays.transferTx, pubkey)
+		}
+	}
+	ms.relays.Unlock()
+
+	if len(changed) == 0 {
+		return
+	}
+
+	// apply in order of changes: a peer taking over mesh ips
+	// comes first, as wireguard moves them from the other peer
+	desired := ms.desiredPeers()
+	applied := make(map[string]bool)
+	for _, pubkey := range changed {
+		peer, ok := desired[pubkey]
+		if !ok || applied[pubkey] {
+			continue
+		}
+		applied[pubkey] = true
+
+		if err := ms.applyDesiredPeer(peer); err != nil {
+			log.WithError(err).WithField("pk", pubkey).Error("Unable to update peer for relaying")
+		}
+	}
+}
+
+// relayOf returns the public key of the relay of a peer, if it is relayed
+func (ms *MeshService) relayOf(pubkey string) (string, bool) {
+	ms.relays.Lock()
+	defer ms.relays.Unlock()
+
+	rp, ok := ms.relays.relayed[pubkey]
+	return rp.relayPubkey, ok
+}
+
+// applyDesiredPeer sets allowed ips and keepalive of a peer as desired
+func (ms *MeshService) applyDesiredPeer(peer wgwrapper.WireguardPeer) error {
+	allowedIPs := make([]string, 0, len(peer.AllowedIPs))
+	for _, a := range peer.AllowedIPs {
+		allowedIPs = append(allowedIPs, a.String())
+	}
+	sort.Strings(allowedIPs)
+
+	if err := ms.setPeerAllowedIPs(peer.Pubkey, allowedIPs); err != nil {
+		return err
+	}
+	return ms.setPeerKeepalive(peer.Pubkey, peer.PersistentKeepaliveInterval)
+}
+
+// applyRelays changes the desired peers, by public key, for all relayed
+// peers: their mesh ips are moved to the allowed ips of the relay node.
+// Relayed peers keep sending keepalives, so that a direct handshake
+// is still attempted.
+func (ms *MeshService) applyRelays(peers map[string]wgwrapper.WireguardPeer) {
+	ms.relays.Lock()
+	defer ms.relays.Unlock()
+
+	for pubkey, rp := range ms.relays.relayed {
+		peer, ok := peers[pubkey]
+		if !ok {
+			continue
+		}
+		relay, ok := peers[rp.relayPubkey]
+		if !ok {
+			continue
+		}
+
+		relay.AllowedIPs = append(relay.AllowedIPs, peer.AllowedIPs...)
+		peers[rp.relayPubkey] = relay
+
+		peer.AllowedIPs = nil
+		if peer.PersistentKeepaliveInterval == 0 {
+			peer.PersistentKeepaliveInterval = relayProbeKeepalive
+		}
+		peers[pubkey] = peer
+	}
+}
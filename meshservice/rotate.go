@@ -0,0 +1,332 @@
+package meshservice
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"sync"
+	"time"
+
+	wgwrapper "github.com/aschmidt75/go-wg-wrapper/pkg/wgwrapper"
+	log "github.com/sirupsen/logrus"
+	"golang.org/x/crypto/curve25519"
+)
+
+// KeyRotationMinGrace is the minimum time between announcing a new
+// wireguard key and switching to it, so that it can be gossiped
+const KeyRotationMinGrace = 10 * time.Second
+
+// pendingKey is a new wireguard key of this node which has been
+// announced, but which this node only switches to at its activation time
+type pendingKey struct {
+	sync.Mutex
+
+	privateKey string
+	activation time.Time
+	grace      time.Duration
+}
+
+// RotateWireguardKey replaces the wireguard key of this node with a newly
+// generated one. The new public key is announced in the _pk tag while this
+// node keeps using its previous key, which is announced in the _pkp tag
+// together with the time the new key becomes active (_pka), after the grace
+// period. Other nodes add a peer entry for the new key next to the previous
+// one, and move the mesh ips of this node to it at the activation time,
+// when this node switches its own key as well. The _pkp and _pka tags are
+// removed one grace period later. Returns the new public key.
+func (ms *MeshService) RotateWireguardKey(grace time.Duration) (string, error) {
+	if grace < KeyRotationMinGrace {
+		return "", fmt.Errorf("grace period must be at least %s, so that the new key can be gossiped", KeyRotationMinGrace)
+	}
+
+	if ms.PendingWireguardKey() != "" {
+		return "", errors.New("a key rotation is already pending")
+	}
+	prevPubkey := ms.WireguardPubKey
+
+	privateKey, err := newWireguardPrivateKey()
+	if err != nil {
+		return "", err
+	}
+	pubkey, err := wireguardPublicKey(privateKey)
+	if err != nil {
+		return "", err
+	}
+	activation := time.Now().Add(grace)
+
+	// the interface keeps the previous key until the activation
+	// time, so nothing needs to be restored if this fails
+	err = ms.updateTags(func(tags map[string]string) error {
+		announceKeyTags(tags, pubkey, prevPubkey, activation)
+		return nil
+	})
+	if err != nil {
+		log.WithError(err).Error("unable to announce new public key")
+		return "", errors.New("unable to announce new public key")
+	}
+
+	ms.pendingKey.Lock()
+	ms.pendingKey.privateKey = privateKey
+	ms.pendingKey.activation = activation
+	ms.pendingKey.grace = grace
+	ms.pendingKey.Unlock()
+	time.AfterFunc(time.Until(activation), ms.activatePendingKey)
+
+	log.WithFields(log.Fields{
+		"pk":         pubkey,
+		"activation": activation,
+	}).Info("Announced new wireguard key")
+
+	// a restart must switch to the new key if it became active meanwhile
+	ms.SaveIdentity()
+
+	return pubkey, nil
+}
+
+// announceKeyTags sets the tags announcing a new public key, which
+// becomes active at the activation time
+func announceKeyTags(tags map[string]string, pubkey, prevPubkey string, activation time.Time) {
+	tags[nodeTagPubKey] = pubkey
+	tags[nodeTagPrevPubKey] = prevPubkey
+	tags[nodeTagKeyActivation] = strconv.FormatInt(activation.Unix(), 10)
+}
+
+// dropPrevKeyTags removes the tags of a key rotation once its grace
+// period is over, unless they belong to a later rotation
+func dropPrevKeyTags(tags map[string]string, prevPubkey string) {
+	if tags[nodeTagPrevPubKey] != prevPubkey {
+		return
+	}
+	delete(tags, nodeTagPrevPubKey)
+	delete(tags, nodeTagKeyActivation)
+}
+
+// PendingWireguardKey returns the announced, not yet active private
+// key of a pending key rotation, or an empty string
+func (ms *MeshService) PendingWireguardKey() string {
+	ms.pendingKey.Lock()
+	defer ms.pendingKey.Unlock()
+
+	return ms.pendingKey.privateKey
+}
+
+// activatePendingKey switches the wireguard interface to the pending
+// key. Other nodes move the peer entry of this node to the new public
+// key at the same time.
+func (ms *MeshService) activatePendingKey() {
+	ms.pendingKey.Lock()
+	privateKey := ms.pendingKey.privateKey
+	grace := ms.pendingKey.grace
+	ms.pendingKey.privateKey = ""
+	ms.pendingKey.Unlock()
+
+	if privateKey == "" {
+		return
+	}
+
+	prevPubkey := ms.WireguardPubKey
+	if err := ms.SetWireguardPrivateKey(privateKey); err != nil {
+		log.WithError(err).Error("unable to activate new wireguard key")
+		return
+	}
+	// preshared keys of pairs are derived from both public keys
+	if mode, _ := ms.PresharedKeys(); mode == PSKModePair {
+		ms.applyPresharedKeys()
+	}
+	if err := ms.leases.rekey(prevPubkey, ms.WireguardPubKey); err != nil {
+		log.WithError(err).Warn("unable to update own ipam lease")
+	}
+
+	// a restart must not bring back the previous key
+	ms.SaveIdentity()
+
+	log.WithField("pk", ms.WireguardPubKey).Info("Rotated wireguard key")
+
+	time.AfterFunc(grace, func() {
+		err := ms.updateTags(func(tags map[string]string) error {
+			dropPrevKeyTags(tags, prevPubkey)
+			return nil
+		})
+		if err != nil {
+			log.WithError(err).Error("unable to remove previous public key tag")
+		}
+	})
+}
+
+// RestoreWireguardKey sets the private key of a persisted identity. If a
+// key rotation was pending and its activation time has passed, the new key
+// is used, as the other nodes switched to it. Otherwise the rotation is
+// dropped, as this node announces its previous key again when it rejoins.
+func (ms *MeshService) RestoreWireguardKey(id *NodeIdentity) error {
+	if id.PendingPrivateKey != "" && !time.Now().Before(time.Unix(id.PendingKeyTS, 0)) {
+		return ms.SetWireguardPrivateKey(id.PendingPrivateKey)
+	}
+	return ms.SetWireguardPrivateKey(id.PrivateKey)
+}
+
+// memberPubkeys returns the public key a member currently uses, taken
+// from its tags. While a key rotation of the member is pending, this is
+// the previous key, and the new key is returned as well.
+func memberPubkeys(tags map[string]string) (string, string) {
+	pubkey := tags[nodeTagPubKey]
+	activation, ok := keyActivation(tags)
+	if !ok || tags[nodeTagPrevPubKey] == "" || !time.Now().Before(activation) {
+		return pubkey, ""
+	}
+	return tags[nodeTagPrevPubKey], pubkey
+}
+
+// keyActivation returns the activation time of a rotated key, if announced
+func keyActivation(tags map[string]string) (time.Time, bool) {
+	ts, err := strconv.ParseInt(tags[nodeTagKeyActivation], 10, 64)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return time.Unix(ts, 0), true
+}
+
+// pendingWireguardPeer builds the peer entry for the new key of a peer
+// with a pending key rotation. It has no allowed ips, which stay with the
+// entry of the current key until the new key is active.
+func (ms *MeshService) pendingWireguardPeer(peer KnownPeer) wgwrapper.WireguardPeer {
+	peer.Pubkey = peer.PendingPubkey
+	p := ms.wireguardPeer(peer)
+	p.AllowedIPs = nil
+	return p
+}
+
+// memberKeyActivations keeps the scheduled switches to announced keys
+// of members, so that each one is scheduled once only
+type memberKeyActivations struct {
+	sync.Mutex
+
+	scheduled map[string]bool
+}
+
+func newMemberKeyActivations() *memberKeyActivations {
+	return &memberKeyActivations{
+		scheduled: make(map[string]bool),
+	}
+}
+
+// add returns false if the activation has already been scheduled
+func (a *memberKeyActivations) add(key string) bool {
+	a.Lock()
+	defer a.Unlock()
+
+	if a.scheduled[key] {
+		return false
+	}
+	a.scheduled[key] = true
+	return true
+}
+
+func (a *memberKeyActivations) remove(key string) {
+	a.Lock()
+	defer a.Unlock()
+
+	delete(a.scheduled, key)
+}
+
+// scheduleMemberKeyActivation switches the peer entry of a member to its
+// announced key at the activation time, unless this is already scheduled
+func (ms *MeshService) scheduleMemberKeyActivation(nodeName, pubkey string, activation time.Time) {
+	key := fmt.Sprintf("%s/%s/%d", nodeName, pubkey, activation.Unix())
+	if !ms.keyActivations.add(key) {
+		return
+	}
+	time.AfterFunc(time.Until(activation), func() {
+		ms.keyActivations.remove(key)
+		ms.activateMemberKey(nodeName)
+	})
+}
+
+// activateMemberKey moves the peer entry of a member to its new key,
+// once the key rotation of the member became active
+func (ms *MeshService) activateMemberKey(nodeName string) {
+	for _, member := range ms.Serf().Members() {
+		if member.Name != nodeName {
+			continue
+		}
+		peer := memberPeer(member)
+		prev, ok := ms.memberPeers.get(nodeName)
+		if !ok || prev.Pubkey == peer.Pubkey {
+			return
+		}
+		ms.memberPeers.set(peer)
+		if ms.peersWith(peer.Group) {
+			ms.rekeyPeer(prev, peer)
+		}
+	}
+}
+
+// rekeyPeer replaces the peer entry of a member which switched its key.
+// The entry of the new key is set up before the previous one is removed.
+func (ms *MeshService) rekeyPeer(prev, peer KnownPeer) {
+	wg := wgwrapper.New()
+
+	ok, err := wg.AddPeer(ms.WireguardInterface, ms.wireguardPeer(peer))
+	if err != nil {
+		log.WithError(err).Error("unable to add peer after key rotation")
+		return
+	}
+	if err = ms.updatePeerAllowedIPs(peer.Pubkey); err != nil {
+		log.WithError(err).Error("unable to move allowed ips to rotated key")
+	}
+	if err = wg.RemovePeerByPubkey(ms.WireguardInterface, prev.Pubkey); err != nil {
+		log.WithError(err).Error("unable to remove previous wireguard peer")
+	}
+	if ok {
+		log.WithFields(log.Fields{
+			"node": peer.NodeName,
+			"pk":   peer.Pubkey,
+		}).Info("peer rotated its key")
+	}
+
+	if err = ms.leases.rekey(prev.Pubkey, peer.Pubkey); err != nil {
+		log.WithError(err).Error("unable to update ipam lease after key rotation")
+	}
+}
+
+// localTags returns a copy of the tags of this node
+func (ms *MeshService) localTags() map[string]string {
+	tags := make(map[string]string)
+	for key, value := range ms.Serf().LocalMember().Tags {
+		tags[key] = value
+	}
+	return tags
+}
+
+// updateTags applies update to a copy of the tags of this node and
+// announces the result, unless update fails or leaves the tags as they
+// are. Updates are serialized, so that concurrent ones do not overwrite
+// each other's changes.
+func (ms *MeshService) updateTags(update func(tags map[string]string) error) error {
+	ms.tagUpdates.Lock()
+	defer ms.tagUpdates.Unlock()
+
+	tags := ms.localTags()
+	if err := update(tags); err != nil {
+		return err
+	}
+	if reflect.DeepEqual(tags, ms.Serf().LocalMember().Tags) {
+		return nil
+	}
+	return ms.Serf().SetTags(tags)
+}
+
+// newWireguardPrivateKey generates a base64-encoded curve25519 private key
+func newWireguardPrivateKey() (string, error) {
+	b := make([]byte, curve25519.ScalarSize)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	b[0] &= 248
+	b[31] &= 127
+	b[31] |= 64
+
+	return base64.StdEncoding.EncodeToString(b), nil
+}
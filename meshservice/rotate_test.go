@@ -0,0 +1,83 @@
+package meshservice
+
+import (
+	"testing"
+	"time"
+)
+
+func TestKeyRotationTags(t *testing.T) {
+	now := time.Now()
+	activation := now.Add(KeyRotationMinGrace)
+
+	tags := map[string]string{nodeTagPubKey: "pk1"}
+	announceKeyTags(tags, "pk2", "pk1", activation)
+
+	// during the grace period, the previous key is in use
+	if pubkey, pending := memberPubkeys(tags); pubkey != "pk1" || pending != "pk2" {
+		t.Errorf("before activation: keys are %s/%s, want pk1/pk2", pubkey, pending)
+	}
+
+	// a later rotation keeps its own tags
+	later := copyTags(tags)
+	announceKeyTags(later, "pk3", "pk2", activation.Add(time.Minute))
+	dropPrevKeyTags(later, "pk1")
+	if later[nodeTagPrevPubKey] != "pk2" || later[nodeTagKeyActivation] == "" {
+		t.Errorf("tags of a later rotation dropped: %v", later)
+	}
+
+	// after the grace period, only the new key is announced
+	dropPrevKeyTags(tags, "pk1")
+	if _, ok := tags[nodeTagPrevPubKey]; ok {
+		t.Errorf("previous key tag not dropped: %v", tags)
+	}
+	if _, ok := tags[nodeTagKeyActivation]; ok {
+		t.Errorf("activation tag not dropped: %v", tags)
+	}
+	if pubkey, pending := memberPubkeys(tags); pubkey != "pk2" || pending != "" {
+		t.Errorf("after grace period: keys are %s/%s, want pk2", pubkey, pending)
+	}
+
+	// an activation time in the past switches to the new key
+	past := map[string]string{nodeTagPubKey: "pk1"}
+	announceKeyTags(past, "pk2", "pk1", now.Add(-time.Second))
+	if pubkey, pending := memberPubkeys(past); pubkey != "pk2" || pending != "" {
+		t.Errorf("after activation: keys are %s/%s, want pk2", pubkey, pending)
+	}
+}
+
+func copyTags(tags map[string]string) map[string]string {
+	res := make(map[string]string)
+	for key, value := range tags {
+		res[key] = value
+	}
+	return res
+}
+
+func TestRotateWireguardKeyChecks(t *testing.T) {
+	ms := NewMeshService("test")
+	if _, err := ms.RotateWireguardKey(KeyRotationMinGrace - time.Second); err == nil {
+		t.Errorf("rotation with a grace period below the minimum accepted")
+	}
+
+	ms.pendingKey.privateKey = "pending"
+	if _, err := ms.RotateWireguardKey(KeyRotationMinGrace); err == nil {
+		t.Errorf("rotation accepted while another one is pending")
+	}
+}
+
+func TestMemberKeyActivations(t *testing.T) {
+	a := newMemberKeyActivations()
+	if !a.add("node/pk2/1") {
+		t.Errorf("first activation not scheduled")
+	}
+	if a.add("node/pk2/1") {
+		t.Errorf("activation scheduled twice")
+	}
+	if !a.add("node/pk3/2") {
+		t.Errorf("activation of another key not scheduled")
+	}
+	a.remove("node/pk2/1")
+	if !a.add("node/pk2/1") {
+		t.Errorf("activation not scheduled again after it fired")
+	}
+}
@@ -0,0 +1,370 @@
+package meshservice
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net"
+	"os/exec"
+	"sort"
+	"strings"
+	"sync"
+
+	serf "github.com/hashicorp/serf/serf"
+	log "github.com/sirupsen/logrus"
+	"google.golang.org/protobuf/proto"
+)
+
+const (
+	// RoutePolicyManual makes advertised routes wait for approval by an operator
+	RoutePolicyManual = "manual"
+
+	// RoutePolicyAuto approves all advertised routes which do not overlap
+	RoutePolicyAuto = "auto"
+)
+
+// MeshRoute is a subnet advertised by a node of the mesh
+type MeshRoute struct {
+	NodeName string
+	CIDR     string
+	Approved bool
+}
+
+// routeTable keeps the kernel routes to subnets of other
+// nodes installed by this node, by cidr
+type routeTable struct {
+	sync.Mutex
+
+	routes map[string]string
+}
+
+func newRouteTable() *routeTable {
+	return &routeTable{
+		routes: make(map[string]string),
+	}
+}
+
+// parseRoutesTag returns the cidrs of a routes tag
+func parseRoutesTag(tag string) []string {
+	res := make([]string, 0)
+	for _, s := range strings.Split(tag, ",") {
+		if s = strings.TrimSpace(s); s != "" {
+			res = append(res, s)
+		}
+	}
+	return res
+}
+
+// formatRoutesTag returns the sorted cidrs as routes tag
+func formatRoutesTag(cidrs []string) string {
+	sorted := append([]string{}, cidrs...)
+	sort.Strings(sorted)
+	return strings.Join(sorted, ",")
+}
+
+// routeAllowedIPs returns the cidrs as allowed ips. Invalid ones are skipped.
+func routeAllowedIPs(cidrs []string) []net.IPNet {
+	res := make([]net.IPNet, 0, len(cidrs))
+	for _, cidr := range cidrs {
+		if _, ipnet, err := net.ParseCIDR(cidr); err == nil {
+			res = append(res, *ipnet)
+		}
+	}
+	return res
+}
+
+// checkRoutes parses the cidrs and makes sure that they neither overlap with the
+// mesh ranges, nor with each other, nor with routes approved for other alive
+// members. Returns the cidrs in canonical form.
+func (ms *MeshService) checkRoutes(nodeName string, cidrs []string, members []serf.Member) ([]string, error) {
+	nets := make([]*net.IPNet, 0, len(cidrs))
+	res := make([]string, 0, len(cidrs))
+	for _, cidr := range cidrs {
+		_, ipnet, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid route %s", cidr)
+		}
+		if netsOverlap(ipnet, &ms.CIDRRange) || (ms.CIDRRange6 != nil && netsOverlap(ipnet, ms.CIDRRange6)) {
+			return nil, fmt.Errorf("route %s overlaps with the mesh cidr range", ipnet)
+		}
+		for _, other := range nets {
+			if netsOverlap(ipnet, other) {
+				return nil, fmt.Errorf("routes %s and %s overlap", ipnet, other)
+			}
+		}
+		nets = append(nets, ipnet)
+		res = append(res, ipnet.String())
+	}
+
+	for _, member := range members {
+		if member.Name == nodeName || member.Status != serf.StatusAlive {
+			continue
+		}
+		for _, other := range routeAllowedIPs(parseRoutesTag(member.Tags[nodeTagRoutes])) {
+			for _, ipnet := range nets {
+				if netsOverlap(ipnet, &other) {
+					return nil, fmt.Errorf("route %s overlaps with route %s of node %s", ipnet, other.String(), member.Name)
+				}
+			}
+		}
+	}
+
+	return res, nil
+}
+
+// MeshRoutes returns the approved and pending routes of all alive nodes
+func (ms *MeshService) MeshRoutes() []MeshRoute {
+	res := make([]MeshRoute, 0)
+	for _, member := range ms.Serf().Members() {
+		if member.Status != serf.StatusAlive {
+			continue
+		}
+		for _, cidr := range parseRoutesTag(member.Tags[nodeTagRoutes]) {
+			res = append(res, MeshRoute{NodeName: member.Name, CIDR: cidr, Approved: true})
+		}
+		for _, cidr := range parseRoutesTag(member.Tags[nodeTagRoutesPending]) {
+			res = append(res, MeshRoute{NodeName: member.Name, CIDR: cidr, Approved: false})
+		}
+	}
+	sort.Slice(res, func(i, j int) bool {
+		if res[i].NodeName != res[j].NodeName {
+			return res[i].NodeName < res[j].NodeName
+		}
+		return res[i].CIDR < res[j].CIDR
+	})
+	return res
+}
+
+// AdvertiseRoutes announces subnets behind this node to the mesh. They
+// are pending until approved by a bootstrap node, and enable ip forwarding
+// on this node, so that it is able to route traffic into the subnets.
+func (ms *MeshService) AdvertiseRoutes(cidrs []string) error {
+	return ms.updateTags(func(tags map[string]string) error {
+		approved := parseRoutesTag(tags[nodeTagRoutes])
+		pending := parseRoutesTag(tags[nodeTagRoutesPending])
+
+		added := make([]string, 0, len(cidrs))
+		for _, cidr := range cidrs {
+			_, ipnet, err := net.ParseCIDR(cidr)
+			if err != nil {
+				return fmt.Errorf("invalid route %s", cidr)
+			}
+			if !containsString(approved, ipnet.String()) && !containsString(pending, ipnet.String()) {
+				added = append(added, ipnet.String())
+			}
+		}
+		if len(added) == 0 {
+			return nil
+		}
+		if _, err := ms.checkRoutes(ms.NodeName, append(append(approved, pending...), added...), ms.Serf().Members()); err != nil {
+			return err
+		}
+		pending = append(pending, added...)
+
+		if err := ms.EnableForwarding(); err != nil {
+			return err
+		}
+
+		tags[nodeTagRoutesPending] = formatRoutesTag(pending)
+		return nil
+	})
+}
+
+// WithdrawRoutes removes subnets previously advertised by this node
+func (ms *MeshService) WithdrawRoutes(cidrs []string) error {
+	withdrawn := make([]string, 0, len(cidrs))
+	for _, cidr := range cidrs {
+		_, ipnet, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return fmt.Errorf("invalid route %s", cidr)
+		}
+		withdrawn = append(withdrawn, ipnet.String())
+	}
+
+	return ms.updateTags(func(tags map[string]string) error {
+		for _, key := range []string{nodeTagRoutes, nodeTagRoutesPending} {
+			remaining := make([]string, 0)
+			for _, cidr := range parseRoutesTag(tags[key]) {
+				if !containsString(withdrawn, cidr) {
+					remaining = append(remaining, cidr)
+				}
+			}
+			tags[key] = formatRoutesTag(remaining)
+		}
+		return nil
+	})
+}
+
+// ApproveRoutes approves pending routes of a node, or all of its pending
+// routes if cidrs is empty. Only bootstrap nodes approve routes. The
+// approval is sent to the node, which then announces the routes as active.
+func (ms *MeshService) ApproveRoutes(nodeName string, cidrs []string) ([]string, error) {
+	if ms.Serf().LocalMember().Tags[nodeTagNodeType] != "b" {
+		return nil, errors.New("routes are approved by bootstrap nodes only")
+	}
+
+	var member *serf.Member
+	for _, m := range ms.Serf().Members() {
+		if m.Name == nodeName && m.Status == serf.StatusAlive {
+			member = &m
+			break
+		}
+	}
+	if member == nil {
+		return nil, fmt.Errorf("no alive node %s", nodeName)
+	}
+
+	pending := parseRoutesTag(member.Tags[nodeTagRoutesPending])
+	if len(cidrs) > 0 {
+		requested, err := ms.checkRoutes(nodeName, cidrs, ms.Serf().Members())
+		if err != nil {
+			return nil, err
+		}
+		for _, cidr := range requested {
+			if !containsString(pending, cidr) {
+				return nil, fmt.Errorf("route %s is not pending for node %s", cidr, nodeName)
+			}
+		}
+		pending = requested
+	}
+	if len(pending) == 0 {
+		return nil, fmt.Errorf("node %s has no pending routes", nodeName)
+	}
+
+	approved, err := ms.checkRoutes(nodeName, append(parseRoutesTag(member.Tags[nodeTagRoutes]), pending...), ms.Serf().Members())
+	if err != nil {
+		return nil, err
+	}
+	approved = approved[len(approved)-len(pending):]
+
+	buf, err := proto.Marshal(&RouteApproval{
+		NodeName: nodeName,
+		Routes:   approved,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err = ms.Serf().UserEvent(serfEventMarkerRoutes, buf, true); err != nil {
+		return nil, err
+	}
+
+	log.WithFields(log.Fields{
+		"node":   nodeName,
+		"routes": approved,
+	}).Info("Approved routes")
+	return approved, nil
+}
+
+// autoApproveRoutes approves the pending routes of a member if this is
+// a bootstrap node with the auto policy. Overlapping routes stay pending.
+func (ms *MeshService) autoApproveRoutes(member serf.Member) {
+	if ms.RoutePolicy != RoutePolicyAuto || member.Status != serf.StatusAlive {
+		return
+	}
+	for _, cidr := range parseRoutesTag(member.Tags[nodeTagRoutesPending]) {
+		if _, err := ms.ApproveRoutes(member.Name, []string{cidr}); err != nil {
+			log.WithError(err).WithField("node", member.Name).Warn("Unable to approve advertised route")
+		}
+	}
+}
+
+// serfHandleRouteApprovalEvent moves the approved routes of
+// this node from the pending to the active routes tag
+func (ms *MeshService) serfHandleRouteApprovalEvent(userEv serf.UserEvent) {
+	approval := &RouteApproval{}
+	if err := proto.Unmarshal(userEv.Payload, approval); err != nil {
+		log.WithError(err).Error("unable to unmarshal route approval event")
+		return
+	}
+	if approval.NodeName != ms.NodeName {
+		return
+	}
+
+	err := ms.updateTags(func(tags map[string]string) error {
+		approved := parseRoutesTag(tags[nodeTagRoutes])
+		pending := make([]string, 0)
+		for _, cidr := range parseRoutesTag(tags[nodeTagRoutesPending]) {
+			if containsString(approval.Routes, cidr) {
+				approved = append(approved, cidr)
+			} else {
+				pending = append(pending, cidr)
+			}
+		}
+		tags[nodeTagRoutes] = formatRoutesTag(approved)
+		tags[nodeTagRoutesPending] = formatRoutesTag(pending)
+		return nil
+	})
+	if err != nil {
+		log.WithError(err).Error("unable to set tags for approved routes")
+		return
+	}
+	log.WithField("routes", approval.Routes).Info("Advertised routes have been approved")
+}
+
+// syncRoutes installs kernel routes via the wireguard interface for the
+// approved routes of all alive nodes, and removes routes no longer advertised
+func (ms *MeshService) syncRoutes() {
+	if ms.Serf() == nil {
+		return
+	}
+
+	desired := make(map[string]string)
+	for _, member := range ms.Serf().Members() {
+		if member.Name == ms.NodeName || member.Status != serf.StatusAlive {
+			continue
+		}
+		for _, ipnet := range routeAllowedIPs(parseRoutesTag(member.Tags[nodeTagRoutes])) {
+			desired[ipnet.String()] = member.Name
+		}
+	}
+
+	ms.routes.Lock()
+	defer ms.routes.Unlock()
+
+	for cidr, nodeName := range desired {
+		if _, ok := ms.routes.routes[cidr]; ok {
+			continue
+		}
+		if err := ms.ipRoute("replace", cidr); err != nil {
+			log.WithError(err).WithField("route", cidr).Error("unable to add route")
+			continue
+		}
+		log.WithFields(log.Fields{
+			"route": cidr,
+			"node":  nodeName,
+		}).Info("Added route to subnet of node")
+		ms.routes.routes[cidr] = nodeName
+	}
+	for cidr := range ms.routes.routes {
+		if _, ok := desired[cidr]; ok {
+			continue
+		}
+		if err := ms.ipRoute("del", cidr); err != nil {
+			log.WithError(err).WithField("route", cidr).Error("unable to remove route")
+		} else {
+			log.WithField("route", cidr).Info("Removed route to subnet")
+		}
+		delete(ms.routes.routes, cidr)
+	}
+}
+
+// ipRoute adds (replace) or removes (del) a route via the wireguard interface
+func (ms *MeshService) ipRoute(action string, cidr string) error {
+	cmd := exec.Command("ip", "route", action, cidr, "dev", ms.WireguardInterface.InterfaceName)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		log.WithField("stderr", stderr.String()).Error("ip route reported an error")
+		return err
+	}
+	return nil
+}
+
+func containsString(arr []string, s string) bool {
+	for _, a := range arr {
+		if a == s {
+			return true
+		}
+	}
+	return false
+}
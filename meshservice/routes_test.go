@@ -0,0 +1,69 @@
+package meshservice
+
+import (
+	"strings"
+	"testing"
+
+	serf "github.com/hashicorp/serf/serf"
+)
+
+func TestCheckRoutes(t *testing.T) {
+	members := []serf.Member{
+		{Name: "a", Status: serf.StatusAlive, Tags: map[string]string{nodeTagRoutes: "192.168.100.0/24"}},
+		{Name: "b", Status: serf.StatusAlive, Tags: map[string]string{nodeTagRoutes: "172.16.1.0/24,fd01::/64"}},
+		{Name: "c", Status: serf.StatusFailed, Tags: map[string]string{nodeTagRoutes: "172.17.0.0/16"}},
+		{Name: "d", Status: serf.StatusAlive, Tags: map[string]string{nodeTagRoutes: "invalid"}},
+	}
+
+	tests := []struct {
+		cidr6    string
+		nodeName string
+		cidrs    string
+		want     string
+		wantErr  bool
+	}{
+		{"fd00:232::/64", "a", "192.168.1.0/24", "192.168.1.0/24", false},
+		{"fd00:232::/64", "a", "192.168.1.7/24,fd02::1/64", "192.168.1.0/24,fd02::/64", false},
+		{"fd00:232::/64", "a", "bogus", "", true},
+		// the mesh ranges
+		{"fd00:232::/64", "a", "10.232.1.0/24", "", true},
+		{"fd00:232::/64", "a", "10.0.0.0/8", "", true},
+		{"fd00:232::/64", "a", "fd00:232::/80", "", true},
+		{"", "a", "fd00:232::/80", "fd00:232::/80", false},
+		// the exit route is not a subnet route
+		{"fd00:232::/64", "a", "0.0.0.0/0", "", true},
+		{"fd00:232::/64", "a", "::/0", "", true},
+		// own routes
+		{"fd00:232::/64", "a", "192.168.0.0/16,192.168.1.0/24", "", true},
+		{"fd00:232::/64", "a", "192.168.1.0/24,192.168.1.0/24", "", true},
+		{"fd00:232::/64", "a", "192.168.100.0/24", "192.168.100.0/24", false},
+		// routes of other members
+		{"fd00:232::/64", "a", "172.16.0.0/12", "", true},
+		{"fd00:232::/64", "a", "fd01::/48", "", true},
+		{"fd00:232::/64", "a", "172.17.1.0/24", "172.17.1.0/24", false},
+		{"fd00:232::/64", "b", "172.16.1.0/24", "172.16.1.0/24", false},
+		{"fd00:232::/64", "b", "192.168.100.0/25", "", true},
+	}
+
+	for _, tt := range tests {
+		ms := newTestIPAMService(t, "10.232.0.0/16", "", "10.232.0.1")
+		if tt.cidr6 != "" {
+			ms.CIDRRange6 = mustParseCIDR(t, tt.cidr6)
+		}
+
+		res, err := ms.checkRoutes(tt.nodeName, strings.Split(tt.cidrs, ","), members)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("%s of %s: expected error, got %v", tt.cidrs, tt.nodeName, res)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s of %s: unexpected error %s", tt.cidrs, tt.nodeName, err)
+			continue
+		}
+		if got := strings.Join(res, ","); got != tt.want {
+			t.Errorf("%s of %s: got %s, want %s", tt.cidrs, tt.nodeName, got, tt.want)
+		}
+	}
+}
@@ -1,12 +1,12 @@
 package meshservice
 
 import (
-	"errors"
 	"fmt"
 	ioutil "io/ioutil"
 
 	"os"
 	reflect "reflect"
+	"sync"
 	"time"
 
 	memberlist "github.com/hashicorp/memberlist"
@@ -14,16 +14,37 @@ import (
 	log "github.com/sirupsen/logrus"
 )
 
+// SerfSettings contains the bind port and tuning parameters of the serf
+// cluster. Probe and gossip intervals left at 0 keep the defaults of the
+// LAN or WAN profile.
+type SerfSettings struct {
+	LANMode          bool
+	BindPort         int
+	ProbeInterval    time.Duration
+	ProbeTimeout     time.Duration
+	GossipInterval   time.Duration
+	ReapInterval     time.Duration
+	ReconnectTimeout time.Duration
+	TombstoneTimeout time.Duration
+	EventBuffer      int
+	QueryBuffer      int
+}
+
+// DefaultSerfBindPort is the port serf binds to if none is given. It used
+// to be 5353, which collides with mDNS.
+const DefaultSerfBindPort = 7946
+
 // NewSerfCluster sets up a cluster with a given nodeName,
 // a bind address. it also registers a user event listener
 // which acts upon Join and Leave user messages
-func (ms *MeshService) NewSerfCluster(lanMode bool) {
+func (ms *MeshService) NewSerfCluster(settings SerfSettings) {
 
-	cfg := serfCustomConfig(ms.NodeName, ms.MeshIP.IP.String(), lanMode)
+	cfg := serfCustomConfig(ms.NodeName, ms.MeshIP.IP.String(), settings)
 
 	// set up the event handler for all user events
 	ch := make(chan serf.Event, 1)
 	go ms.serfEventHandler(ch)
+	go ms.memberEventWorker()
 	cfg.EventCh = ch
 
 	if log.GetLevel() == log.TraceLevel {
@@ -38,40 +59,188 @@ func (ms *MeshService) NewSerfCluster(lanMode bool) {
 		cfg.MemberlistConfig.SecretKey = ms.serfEncryptionKey
 	}
 
+	// serf rejoins the members found in the snapshot by itself
+	cfg.SnapshotPath = ms.serfSnapshotPath
+
 	ms.cfg = cfg
+	ms.serfModeLAN = settings.LANMode
+
+}
+
+// SerfBindPort returns the port serf binds to, for nodes joining
+// the mesh. Returns 0 if the cluster has not been set up yet.
+func (ms *MeshService) SerfBindPort() int {
+	if ms.cfg == nil || ms.cfg.MemberlistConfig == nil {
+		return 0
+	}
+	return ms.cfg.MemberlistConfig.BindPort
+}
 
+const (
+	// leftMemberTimeout is the time the name of a failed or left member
+	// stays reserved for its public key.
+	leftMemberTimeout = 1 * time.Hour
+)
+
+// leftMemberStore remembers the public keys of failed and left members
+// by node name. serf forgets about them right away, as they are pruned.
+type leftMemberStore struct {
+	sync.Mutex
+
+	members map[string]leftMember
+}
+
+type leftMember struct {
+	pubkeys []string
+	leftTS  time.Time
 }
 
-func (ms *MeshService) isNodeNameInUse(nodeName string) bool {
+func newLeftMemberStore() *leftMemberStore {
+	return &leftMemberStore{
+		members: make(map[string]leftMember),
+	}
+}
+
+// add records a member as left, with all public keys it announced
+func (s *leftMemberStore) add(nodeName string, pubkeys []string) {
+	s.Lock()
+	defer s.Unlock()
+
+	s.members[nodeName] = leftMember{
+		pubkeys: pubkeys,
+		leftTS:  time.Now(),
+	}
+}
+
+// get returns the public keys of a member which left within
+// leftMemberTimeout. Older entries are dropped.
+func (s *leftMemberStore) get(nodeName string) ([]string, bool) {
+	s.Lock()
+	defer s.Unlock()
+
+	for name, m := range s.members {
+		if time.Since(m.leftTS) > leftMemberTimeout {
+			delete(s.members, name)
+		}
+	}
+	m, ok := s.members[nodeName]
+	return m.pubkeys, ok
+}
+
+// memberPubkeyTags returns the current and previous public
+// key a member announces in its tags
+func memberPubkeyTags(tags map[string]string) []string {
+	res := []string{}
+	for _, tag := range []string{nodeTagPubKey, nodeTagPrevPubKey} {
+		if tags[tag] != "" {
+			res = append(res, tags[tag])
+		}
+	}
+	return res
+}
+
+// isNodeNameInUse checks if the given name is taken by a member of the
+// serf cluster, or by a member which failed or left within leftMemberTimeout.
+// Failed and left members are pruned from serf, so their names are taken
+// from the left member store. Public keys are announced in the tags, so a
+// matching key is no proof of identity: it only allows to take over the name
+// of a member which is no longer alive, as a rejoining node does. The name of
+// an alive member is always in use.
+func (ms *MeshService) isNodeNameInUse(nodeName, pubkey string) bool {
 	if nodeName == "" {
 		return false
 	}
+	for _, member := range ms.Serf().Members() {
+		if member.Name == nodeName {
+			if member.Status == serf.StatusAlive || pubkey == "" {
+				return true
+			}
+			return !containsString(memberPubkeyTags(member.Tags), pubkey)
+		}
+	}
+	if pubkeys, ok := ms.leftMembers.get(nodeName); ok {
+		return pubkey == "" || !containsString(pubkeys, pubkey)
+	}
 	return false
 }
 
+// uniqueNodeName checks the node name requested by a joining node.
+// If it is already in use, it is either rejected, or - with the auto-suffix
+// policy and if allowSuffix is set - an alternative name is returned.
+func (ms *MeshService) uniqueNodeName(nodeName, pubkey string, allowSuffix bool) (string, error) {
+	if !ms.isNodeNameInUse(nodeName, pubkey) {
+		return nodeName, nil
+	}
+	if ms.NodeNamePolicy != NodeNamePolicyAutoSuffix || !allowSuffix {
+		return "", fmt.Errorf("Requested node name %s is already in use", nodeName)
+	}
+
+	for i := 2; i <= maxNodeNameSuffix; i++ {
+		alt := fmt.Sprintf("%s-%d", nodeName, i)
+		if !ms.isNodeNameInUse(alt, pubkey) {
+			log.WithFields(log.Fields{
+				"requested": nodeName,
+				"assigned":  alt,
+			}).Info("Requested node name is in use, assigning alternative")
+			return alt, nil
+		}
+	}
+	return "", fmt.Errorf("Requested node name %s is already in use, no alternative found", nodeName)
+}
+
 // StartSerfCluster is used by bootstrap to set up the initial serf cluster node
 // A set of node tags is derived from all parameters so that other nodes have
-// all data to connect.
-func (ms *MeshService) StartSerfCluster(isBootstrap bool, pubkey string, endpointIP string, endpointPort int, meshIP string) error {
+// all data to connect. Additional tags (e.g. from a pre-shared token) may be given.
+func (ms *MeshService) StartSerfCluster(isBootstrap bool, pubkey string, endpointIP string, endpointPort int, meshIP string, additionalTags map[string]string) error {
 
-	s, err := serf.Create(ms.cfg)
-	if err != nil {
-		return errors.New("Unable to set up serf cluster")
-	}
 	nodeType := "n"
 	if isBootstrap {
 		nodeType = "b"
 	}
-	tags := map[string]string{
-		nodeTagNodeType: nodeType,
-		nodeTagPubKey:   pubkey,
-		nodeTagAddr:     fmt.Sprintf("%s", endpointIP),
-		nodeTagPort:     fmt.Sprintf("%d", endpointPort),
-		nodeTagMeshIP:   meshIP,
+	tags := map[string]string{}
+	for key, value := range additionalTags {
+		tags[key] = value
 	}
+	tags[nodeTagNodeType] = nodeType
+	tags[nodeTagPubKey] = pubkey
+	tags[nodeTagAddr] = fmt.Sprintf("%s", endpointIP)
+	tags[nodeTagPort] = fmt.Sprintf("%d", endpointPort)
+	tags[nodeTagMeshIP] = meshIP
+	if ms.MeshIP6.IP != nil {
+		tags[nodeTagMeshIP6] = ms.MeshIP6.IP.String()
+	}
+	if ms.BehindNAT {
+		tags[nodeTagNAT] = "1"
+	}
+	if ms.Relay {
+		tags[nodeTagRelay] = "1"
+	}
+	if ms.IsExitNode {
+		tags[nodeTagExitNode] = "1"
+	}
+	if ms.Group != "" {
+		tags[nodeTagGroup] = ms.Group
+	}
+	if mode, _ := ms.PresharedKeys(); mode != PSKModeOff {
+		tags[nodeTagPSKEpoch] = fmt.Sprintf("%d", ms.PresharedKeyEpoch())
+	}
+	if isBootstrap && ms.CIDRRangeIPAM != nil {
+		tags[nodeTagIPAM] = ms.CIDRRangeIPAM.String()
+	}
+	if isBootstrap && ms.CIDRRangeIPAM6 != nil {
+		tags[nodeTagIPAM6] = ms.CIDRRangeIPAM6.String()
+	}
+
+	// serf announces the tags right away, also when replaying
+	// a snapshot and rejoining previous members on create
 	log.WithField("tags", tags).Trace("setting tags for this node")
-	s.SetTags(tags)
+	ms.cfg.Tags = tags
 
+	// serf fails if its port is in use, e.g. by another mesh
+	s, err := serf.Create(ms.cfg)
+	if err != nil {
+		return fmt.Errorf("Unable to set up serf cluster, choose another port with -serf-bind-port if %d is in use: %s", ms.cfg.MemberlistConfig.BindPort, err)
+	}
 	ms.s = s
 
 	log.Debug("started serf cluster")
@@ -79,12 +248,13 @@ func (ms *MeshService) StartSerfCluster(isBootstrap bool, pubkey string, endpoin
 	return nil
 }
 
-// JoinSerfCluster calls serf.Join, given a number of cluster nodes received from the bootstrap node
-func (ms *MeshService) JoinSerfCluster(clusterNodes []string) {
+// JoinSerfCluster calls serf.Join, given a number of cluster nodes received from the bootstrap node.
+// Returns the number of nodes successfully contacted.
+func (ms *MeshService) JoinSerfCluster(clusterNodes []string) (int, error) {
 	log.WithField("l", clusterNodes).Trace("cluster node list")
 
 	log.Debugf("Joining serf cluster via %d nodes", len(clusterNodes))
-	ms.Serf().Join(clusterNodes, true)
+	return ms.Serf().Join(clusterNodes, true)
 }
 
 // LeaveSerfCluster leaves the cluster
@@ -133,6 +303,12 @@ func (ms *MeshService) StartStatsUpdater() {
 				if ms.memberExportFile != "" {
 					ms.updateMemberExport()
 				}
+				if ms.identityFile != "" {
+					ms.updateIdentity()
+				}
+				if ms.serfSnapshotPath != "" {
+					ms.updatePeerCache()
+				}
 
 				if last == nil {
 					last = ms.getStats()
@@ -157,24 +333,39 @@ func (ms *MeshService) StartStatsUpdater() {
 				return
 			case _ = <-ticker2.C:
 				ms.StatsUpdate()
+				ms.expirePrunedPeers()
 			}
 		}
 	}()
 }
 
-func serfCustomConfig(nodeName string, bindAddr string, lanMode bool) *serf.Config {
+func serfCustomConfig(nodeName string, bindAddr string, settings SerfSettings) *serf.Config {
 
 	var ml *memberlist.Config
 
-	if lanMode {
+	if settings.LANMode {
 		ml = memberlist.DefaultLANConfig()
 	} else {
 		ml = memberlist.DefaultWANConfig()
 	}
-	ml.BindPort = 5353
+	ml.BindPort = DefaultSerfBindPort
+	if settings.BindPort > 0 {
+		ml.BindPort = settings.BindPort
+	}
 	ml.BindAddr = bindAddr
 
-	return &serf.Config{
+	// override the timers of the profile
+	if settings.ProbeInterval > 0 {
+		ml.ProbeInterval = settings.ProbeInterval
+	}
+	if settings.ProbeTimeout > 0 {
+		ml.ProbeTimeout = settings.ProbeTimeout
+	}
+	if settings.GossipInterval > 0 {
+		ml.GossipInterval = settings.GossipInterval
+	}
+
+	cfg := &serf.Config{
 		NodeName:                     nodeName,
 		BroadcastTimeout:             5 * time.Second,
 		LeavePropagateDelay:          1 * time.Second,
@@ -200,4 +391,22 @@ func serfCustomConfig(nodeName string, bindAddr string, lanMode bool) *serf.Conf
 		ValidateNodeNames:            false,
 		UserEventSizeLimit:           512,
 	}
+
+	if settings.ReapInterval > 0 {
+		cfg.ReapInterval = settings.ReapInterval
+	}
+	if settings.ReconnectTimeout > 0 {
+		cfg.ReconnectTimeout = settings.ReconnectTimeout
+	}
+	if settings.TombstoneTimeout > 0 {
+		cfg.TombstoneTimeout = settings.TombstoneTimeout
+	}
+	if settings.EventBuffer > 0 {
+		cfg.EventBuffer = settings.EventBuffer
+	}
+	if settings.QueryBuffer > 0 {
+		cfg.QueryBuffer = settings.QueryBuffer
+	}
+
+	return cfg
 }
@@ -3,6 +3,7 @@ package meshservice
 import (
 	"math/rand"
 	"net"
+	"sync"
 	"time"
 
 	wgwrapper "github.com/aschmidt75/go-wg-wrapper/pkg/wgwrapper"
@@ -21,20 +22,15 @@ func (ms *MeshService) serfHandleJoinRequestEvent(userEv serf.UserEvent) {
 	}
 	log.WithField("pa", peerAnnouncement).Trace("user event: peerAnnouncement")
 
+	if peerAnnouncement.Type == Peer_JOIN && !ms.peersWith(peerAnnouncement.Group) {
+		log.WithField("group", peerAnnouncement.Group).Debug("not peering with joined node, as denied by group policy")
+		return
+	}
+
 	if peerAnnouncement.Type == Peer_JOIN {
 
 		wg := wgwrapper.New()
-		ok, err := wg.AddPeer(ms.WireguardInterface, wgwrapper.WireguardPeer{
-			RemoteEndpointIP: peerAnnouncement.EndpointIP,
-			ListenPort:       int(peerAnnouncement.EndpointPort),
-			Pubkey:           peerAnnouncement.Pubkey,
-			AllowedIPs: []net.IPNet{
-				{
-					IP:   net.ParseIP(peerAnnouncement.MeshIP),
-					Mask: net.CIDRMask(32, 32),
-				},
-			},
-		})
+		ok, err := wg.AddPeer(ms.WireguardInterface, ms.wireguardPeer(announcedPeer(peerAnnouncement)))
 
 		if err != nil {
 			log.WithError(err).Error("unable to add peer after user event")
@@ -107,28 +103,295 @@ func (ms *MeshService) serfHandleRTTResponseEvent(userEv serf.UserEvent) {
 	}
 }
 
-func (ms *MeshService) serfHandleMemberEvent(ev serf.MemberEvent) {
+// memberEventQueue serializes the handling of leave, failed and reap
+// events. Pruning a member makes serf emit further events for it, so
+// queueing never blocks the serf event loop.
+type memberEventQueue struct {
+	sync.Mutex
+
+	events []queuedMemberEvent
+	notify chan struct{}
+}
+
+// queuedMemberEvent is a member event, delayed if its handling was
+// put off before
+type queuedMemberEvent struct {
+	serf.MemberEvent
+	delayed bool
+}
+
+func newMemberEventQueue() *memberEventQueue {
+	return &memberEventQueue{
+		events: make([]queuedMemberEvent, 0),
+		notify: make(chan struct{}, 1),
+	}
+}
+
+func (q *memberEventQueue) push(ev serf.MemberEvent) {
+	q.enqueue(queuedMemberEvent{MemberEvent: ev})
+}
+
+// pushAfter queues the event again after given delay
+func (q *memberEventQueue) pushAfter(ev serf.MemberEvent, delay time.Duration) {
+	time.AfterFunc(delay, func() {
+		q.enqueue(queuedMemberEvent{MemberEvent: ev, delayed: true})
+	})
+}
+
+func (q *memberEventQueue) enqueue(ev queuedMemberEvent) {
+	q.Lock()
+	q.events = append(q.events, ev)
+	q.Unlock()
+
+	select {
+	case q.notify <- struct{}{}:
+	default:
+	}
+}
+
+func (q *memberEventQueue) pop() []queuedMemberEvent {
+	q.Lock()
+	defer q.Unlock()
+
+	res := q.events
+	q.events = make([]queuedMemberEvent, 0)
+	return res
+}
+
+// handles queued leave, failed and reap events one after the other
+func (ms *MeshService) memberEventWorker() {
+	for range ms.memberEvents.notify {
+		for _, ev := range ms.memberEvents.pop() {
+			ms.serfHandleMemberEvent(ev.MemberEvent, ev.delayed)
+		}
+	}
+}
+
+// handles leave, failed and reap events. Failed members are pruned from
+// serf, but keep their wireguard peer while their lease is valid, so that
+// they are able to rejoin through this node. Members which left, gracefully
+// or forced by an operator, lose their peer right away. With a prune delay,
+// failed events are handled again after it, when delayed is set.
+func (ms *MeshService) serfHandleMemberEvent(ev serf.MemberEvent, delayed bool) {
 	for _, member := range ev.Members {
-		// remove this peer from wireguard interface
-		wg := wgwrapper.New()
+		peer := memberPeer(member)
 
-		err := wg.RemovePeerByPubkey(ms.WireguardInterface, member.Tags[nodeTagPubKey])
-		if err != nil {
-			log.WithError(err).Error("unable to remove failed/left wireguard peer")
+		switch ev.Type {
+		case serf.EventMemberFailed:
+			if delay := ms.failedPruneDelay(); delay > 0 && !delayed {
+				// isolated groups may fail each other, give serf time
+				// to refute it before pruning the member
+				log.WithFields(log.Fields{
+					"node":  member.Name,
+					"delay": delay,
+				}).Info("node failed, pruning it if it stays failed")
+				ms.memberEvents.pushAfter(serf.MemberEvent{
+					Type:    serf.EventMemberFailed,
+					Members: []serf.Member{member},
+				}, delay)
+				continue
+			}
+			if status, ok := ms.memberStatus(member.Name); !ok || status != serf.StatusFailed {
+				// it left or became alive again in the meantime
+				continue
+			}
+			if peer.Pubkey != "" {
+				ms.prunedPeers.add(peer)
+			}
+			ms.pruneMember(member, peer)
+
+		case serf.EventMemberLeave:
+			if _, ok := ms.memberStatus(member.Name); !ok && ms.prunedPeers.has(member.Name) {
+				// pruning a failed member makes it leave
+				continue
+			}
+			ms.prunedPeers.remove(member.Name)
+			ms.pruneMember(member, peer)
+
+		case serf.EventMemberReap:
+			ms.memberPeers.remove(member.Name)
+			if ms.prunedPeers.has(member.Name) {
+				// its lease is released when the pruned peer expires
+				continue
+			}
+			ms.removeMemberPeer(peer)
+			ms.releaseLease(member.Tags[nodeTagMeshIP])
 		}
+	}
+}
 
-		err = ms.Serf().RemoveFailedNodePrune(member.Name)
-		if err != nil {
-			log.WithError(err).Error("unable to remove failed/left serf node")
-		} else {
+// memberStatus returns the current status of a member, false
+// if serf does not know it (anymore)
+func (ms *MeshService) memberStatus(nodeName string) (serf.MemberStatus, bool) {
+	for _, member := range ms.Serf().Members() {
+		if member.Name == nodeName {
+			return member.Status, true
+		}
+	}
+	return serf.StatusNone, false
+}
+
+// pruneMember removes a failed or left member from serf, which emits a
+// reap event for it. Its name stays reserved for a while.
+func (ms *MeshService) pruneMember(member serf.Member, peer KnownPeer) {
+	ms.leftMembers.add(member.Name, memberPubkeyTags(member.Tags))
+
+	err := ms.Serf().RemoveFailedNodePrune(member.Name)
+	if err != nil {
+		log.WithError(err).Error("unable to remove failed/left serf node")
+		if !ms.prunedPeers.has(member.Name) {
+			ms.removeMemberPeer(peer)
+		}
+		return
+	}
+	log.WithFields(log.Fields{
+		"node": member.Name,
+		"ip":   member.Addr.String(),
+	}).Info("node left mesh")
+}
+
+// marks the ipam lease of a mesh ip as released, so
+// it can be reclaimed after some time
+func (ms *MeshService) releaseLease(meshIP string) {
+	ip := net.ParseIP(meshIP)
+	if ip == nil {
+		return
+	}
+	if err := ms.leases.markReleased(ip); err != nil {
+		log.WithError(err).Error("unable to release ipam lease")
+	}
+}
+
+// reconfigures the wireguard peers of members whose _pk, _addr, _port, _rt,
+// _nat or _grp tags changed, e.g. when a key rotation is announced, when a
+// node moved to another endpoint or behind a NAT, or changed its group.
+// Changes are detected by comparing against the tags seen before.
+func (ms *MeshService) serfHandleMemberUpdateEvent(ev serf.MemberEvent) {
+	wg := wgwrapper.New()
+
+	for _, member := range ev.Members {
+		if member.Name == ms.NodeName {
+			continue
+		}
+		peer := memberPeer(member)
+		prev, ok := ms.memberPeers.get(member.Name)
+		if !ok {
+			// without tags seen before, a key rotation is only
+			// known by the previous key announced in the _pkp tag
+			prev = peer
+			prev.PendingPubkey = ""
+			if prevPubkey := member.Tags[nodeTagPrevPubKey]; prevPubkey != "" {
+				prev.Pubkey = prevPubkey
+			}
+		}
+		ms.memberPeers.set(peer)
+
+		if !ms.peersWith(peer.Group) {
+			// the member moved to a group this node does not peer with
+			if ok && ms.peersWith(prev.Group) {
+				ms.removeMemberPeer(prev)
+			}
+			continue
+		}
+
+		// the member moved to a group this node peers with
+		if ok && !ms.peersWith(prev.Group) {
+			ms.addMemberPeer(member)
+			continue
+		}
+
+		// a member announced a new key: set up a peer entry for it next
+		// to the current one, and switch to it at the activation time
+		if peer.PendingPubkey != "" && peer.PendingPubkey != prev.PendingPubkey {
+			if _, err := wg.AddPeer(ms.WireguardInterface, ms.pendingWireguardPeer(peer)); err != nil {
+				log.WithError(err).Error("unable to add peer for announced key")
+			}
+			activation, _ := keyActivation(member.Tags)
+			ms.scheduleMemberKeyActivation(member.Name, peer.PendingPubkey, activation)
+		}
+
+		if prev.Pubkey != peer.Pubkey {
+			ms.rekeyPeer(prev, peer)
+			continue
+		}
+
+		if formatRoutesTag(prev.Routes) != formatRoutesTag(peer.Routes) {
+			if err := ms.updatePeerAllowedIPs(peer.Pubkey); err != nil {
+				log.WithError(err).Error("unable to update allowed ips of peer")
+			} else {
+				log.WithFields(log.Fields{
+					"node":   member.Name,
+					"routes": peer.Routes,
+				}).Info("peer changed its routes")
+			}
+		}
+
+		// peers behind NAT get a persistent keepalive
+		if prev.NAT != peer.NAT {
+			if p, ok := ms.desiredPeers()[peer.Pubkey]; ok {
+				if err := ms.setPeerKeepalive(peer.Pubkey, p.PersistentKeepaliveInterval); err != nil {
+					log.WithError(err).Error("unable to update keepalive of peer")
+				} else {
+					log.WithFields(log.Fields{
+						"node": member.Name,
+						"nat":  peer.NAT,
+					}).Info("peer changed its nat status")
+				}
+			}
+		}
+
+		if prev.EndpointIP != peer.EndpointIP || prev.EndpointPort != peer.EndpointPort {
+			if err := ms.setPeerEndpoint(peer); err != nil {
+				log.WithError(err).Error("unable to update endpoint of peer")
+				continue
+			}
 			log.WithFields(log.Fields{
 				"node": member.Name,
-				"ip":   member.Addr.String(),
-			}).Info("node left mesh")
+				"ip":   peer.EndpointIP,
+				"port": peer.EndpointPort,
+			}).Info("peer moved to new endpoint")
 		}
+	}
+}
 
+// adds the wireguard peer of a member, including its announced key
+// which is switched to at the activation time
+func (ms *MeshService) addMemberPeer(member serf.Member) {
+	peer := memberPeer(member)
+
+	wg := wgwrapper.New()
+	if _, err := wg.AddPeer(ms.WireguardInterface, ms.wireguardPeer(peer)); err != nil {
+		log.WithError(err).Error("unable to add wireguard peer")
+		return
+	}
+	if peer.PendingPubkey != "" {
+		if _, err := wg.AddPeer(ms.WireguardInterface, ms.pendingWireguardPeer(peer)); err != nil {
+			log.WithError(err).Error("unable to add peer for announced key")
+		}
+		activation, _ := keyActivation(member.Tags)
+		ms.scheduleMemberKeyActivation(peer.NodeName, peer.PendingPubkey, activation)
 	}
+	log.WithFields(log.Fields{
+		"node":  peer.NodeName,
+		"group": peer.Group,
+	}).Info("now peering with node")
+}
 
+// removes the wireguard peer of a member, including its announced key
+func (ms *MeshService) removeMemberPeer(peer KnownPeer) {
+	wg := wgwrapper.New()
+	for _, pubkey := range []string{peer.Pubkey, peer.PendingPubkey} {
+		if pubkey == "" {
+			continue
+		}
+		if err := wg.RemovePeerByPubkey(ms.WireguardInterface, pubkey); err != nil {
+			log.WithError(err).Error("unable to remove wireguard peer")
+		}
+	}
+	log.WithFields(log.Fields{
+		"node":  peer.NodeName,
+		"group": peer.Group,
+	}).Info("no longer peering with node")
 }
 
 func (ms *MeshService) serfEventHandler(ch <-chan serf.Event) {
@@ -160,7 +423,24 @@ func (ms *MeshService) serfEventHandler(ch <-chan serf.Event) {
 					log.WithField("ev", userEv).Debug("received rtt response event")
 					go ms.serfHandleRTTResponseEvent(userEv)
 				}
+				if userEv.Name == serfEventMarkerPSK {
+					log.Debug("received preshared key update event")
+					go ms.serfHandlePSKUpdateEvent(userEv)
+				}
+				if userEv.Name == serfEventMarkerRoutes {
+					log.WithField("ev", userEv).Debug("received route approval event")
+					go ms.serfHandleRouteApprovalEvent(userEv)
+				}
+
+			}
+
+			if ev.EventType() == serf.EventQuery {
+				query := ev.(*serf.Query)
 
+				if query.Name == serfQueryPSK {
+					log.Debug("received preshared key query")
+					go ms.serfHandlePSKQuery(query)
+				}
 			}
 
 			if ev.EventType() == serf.EventMemberJoin {
@@ -168,12 +448,30 @@ func (ms *MeshService) serfEventHandler(ch <-chan serf.Event) {
 
 				log.WithField("members", evJoin.Members).Debug("received join event")
 				ms.lastUpdatedTS = time.Now()
+
+				for _, member := range evJoin.Members {
+					ms.prunedPeers.remove(member.Name)
+					ms.memberPeers.set(memberPeer(member))
+					go ms.autoApproveRoutes(member)
+				}
+				go ms.syncRoutes()
+				go ms.applyACLOnChange()
+				go ms.checkPresharedKeyEpoch(evJoin.Members)
 			}
 			if ev.EventType() == serf.EventMemberUpdate {
 				evUpdate := ev.(serf.MemberEvent)
 
 				log.WithField("members", evUpdate.Members).Debug("received member update")
 				ms.lastUpdatedTS = time.Now()
+
+				go ms.serfHandleMemberUpdateEvent(evUpdate)
+				for _, member := range evUpdate.Members {
+					go ms.autoApproveRoutes(member)
+				}
+				go ms.syncRoutes()
+				go ms.checkExitNode(evUpdate.Members)
+				go ms.applyACLOnChange()
+				go ms.checkPresharedKeyEpoch(evUpdate.Members)
 			}
 			if ev.EventType() == serf.EventMemberLeave || ev.EventType() == serf.EventMemberFailed || ev.EventType() == serf.EventMemberReap {
 				evMember := ev.(serf.MemberEvent)
@@ -181,8 +479,10 @@ func (ms *MeshService) serfEventHandler(ch <-chan serf.Event) {
 				log.WithField("members", evMember.Members).Debug("received leave/failed event")
 				ms.lastUpdatedTS = time.Now()
 
-				go ms.serfHandleMemberEvent(evMember)
-
+				ms.memberEvents.push(evMember)
+				go ms.syncRoutes()
+				go ms.checkExitNode(evMember.Members)
+				go ms.applyACLOnChange()
 			}
 
 		}
@@ -0,0 +1,84 @@
+package meshservice
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// SetSerfSnapshotPath makes serf persist the cluster state to given file,
+// so that a restarted node knows the members of the mesh. As serf snapshots
+// do not contain tags, the wireguard details of all peers are cached next to it.
+func (ms *MeshService) SetSerfSnapshotPath(path string) {
+	ms.serfSnapshotPath = path
+}
+
+func (ms *MeshService) peerCacheFile() string {
+	return ms.serfSnapshotPath + ".peers"
+}
+
+// CachedPeers returns the peers cached next to the serf snapshot,
+// or nil if there is no snapshot
+func (ms *MeshService) CachedPeers() ([]KnownPeer, error) {
+	if ms.serfSnapshotPath == "" {
+		return nil, nil
+	}
+
+	b, err := ioutil.ReadFile(ms.peerCacheFile())
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	peers := make([]KnownPeer, 0)
+	if err = json.Unmarshal(b, &peers); err != nil {
+		return nil, err
+	}
+	return peers, nil
+}
+
+// updatePeerCache writes the wireguard details of all
+// peers if the member state changed
+func (ms *MeshService) updatePeerCache() {
+	if ms.lastUpdatedTS.Unix() <= ms.lastPeerCacheTS.Unix() {
+		return
+	}
+	log.Debug("updatePeerCache")
+
+	b, err := json.MarshalIndent(ms.knownPeers(), "", "  ")
+	if err != nil {
+		log.WithError(err).Error("Unable to marshal peer cache")
+		return
+	}
+
+	tmpPath := ms.peerCacheFile() + ".tmp"
+	if err = ioutil.WriteFile(tmpPath, b, 0600); err != nil {
+		log.WithError(err).Error("Unable to write peer cache")
+		return
+	}
+	if err = os.Rename(tmpPath, ms.peerCacheFile()); err != nil {
+		log.WithError(err).Error("Unable to write peer cache")
+		return
+	}
+	ms.lastPeerCacheTS = ms.lastUpdatedTS
+}
+
+// MergeKnownPeers combines lists of peers, dropping duplicates by public key
+func MergeKnownPeers(lists ...[]KnownPeer) []KnownPeer {
+	res := make([]KnownPeer, 0)
+	seen := make(map[string]bool)
+	for _, peers := range lists {
+		for _, peer := range peers {
+			if peer.Pubkey == "" || seen[peer.Pubkey] {
+				continue
+			}
+			seen[peer.Pubkey] = true
+			res = append(res, peer)
+		}
+	}
+	return res
+}
@@ -1,62 +1,222 @@
 package meshservice
 
 import (
+	"errors"
 	"net"
+	"sync"
+	"time"
 
 	log "github.com/sirupsen/logrus"
 	"gortc.io/stun"
 )
 
+const (
+	// DefaultSTUNServer is queried if no STUN servers are configured
+	DefaultSTUNServer = "stun.l.google.com:19302"
+
+	// DefaultSTUNTimeout is the time to wait for a response of a single STUN server
+	DefaultSTUNTimeout = 3 * time.Second
+)
+
 // STUNService ...
 type STUNService struct {
 	ip4, ip6 bool
 
-	stunServerURI string
+	stunServerURIs []string
+
+	timeout time.Duration
+}
+
+// STUNResponse is the answer of a single STUN server
+type STUNResponse struct {
+	Server  string
+	Network string
+
+	// mapped address as seen by the server, nil if it did not respond
+	MappedAddr *net.UDPAddr
+	Err        error
+}
+
+// STUNResult collects the answers of all STUN servers
+type STUNResult struct {
+	Responses []STUNResponse
+
+	// external ips, ipv4 first
+	IPs []net.IP
+
+	// servers reported different mapped addresses for the same local
+	// socket, so NAT mappings depend on the destination
+	SymmetricNAT bool
+
+	QueriedTS int64
 }
 
 // NewSTUNService creates a new STUNService struct with
 // default settings working for IPv4
 func NewSTUNService() STUNService {
+	return NewSTUNServiceWithServers([]string{DefaultSTUNServer}, false, DefaultSTUNTimeout)
+}
+
+// NewSTUNServiceWithServers creates a new STUNService struct which queries
+// all given servers for IPv4 (and IPv6, if ip6 is set), waiting for timeout
+// on each server
+func NewSTUNServiceWithServers(servers []string, ip6 bool, timeout time.Duration) STUNService {
 	return STUNService{
-		ip4:           true,
-		ip6:           false,
-		stunServerURI: "stun.l.google.com:19302",
+		ip4:            true,
+		ip6:            ip6,
+		stunServerURIs: servers,
+		timeout:        timeout,
 	}
 }
 
 // GetExternalIP retrieves my own external ip by querying it
 // from the STUN server
 func (st *STUNService) GetExternalIP() ([]net.IP, error) {
+	r, err := st.Query()
+	if err != nil {
+		return make([]net.IP, 0), err
+	}
+	return r.IPs, nil
+}
 
-	ips := make([]net.IP, 0)
+// Query asks all STUN servers for the external address of this node. All
+// servers are queried from the same local socket, so that the mapped addresses
+// can be compared to detect symmetric NAT. Servers which do not respond are
+// skipped, an error is returned only if no server responded at all.
+func (st *STUNService) Query() (*STUNResult, error) {
+	res := &STUNResult{
+		Responses: make([]STUNResponse, 0),
+		IPs:       make([]net.IP, 0),
+		QueriedTS: time.Now().Unix(),
+	}
 
-	log.Info("Fetching external IP from STUN server")
+	networks := make([]string, 0, 2)
 	if st.ip4 {
-		c, err := stun.Dial("udp4", st.stunServerURI)
-		if err != nil {
-			return ips, err
+		networks = append(networks, "udp4")
+	}
+	if st.ip6 {
+		networks = append(networks, "udp6")
+	}
+
+	for _, network := range networks {
+		log.WithField("network", network).Debug("Fetching external IP from STUN servers")
+
+		responses := st.queryAll(network)
+		res.Responses = append(res.Responses, responses...)
+
+		var first *net.UDPAddr
+		for _, r := range responses {
+			if r.MappedAddr == nil {
+				continue
+			}
+			if first == nil {
+				first = r.MappedAddr
+				res.IPs = append(res.IPs, first.IP)
+				continue
+			}
+			if !r.MappedAddr.IP.Equal(first.IP) || r.MappedAddr.Port != first.Port {
+				res.SymmetricNAT = true
+			}
+		}
+	}
+
+	if res.SymmetricNAT {
+		log.Warn("STUN servers reported different mapped addresses, this node seems to be behind symmetric NAT")
+	}
+	if len(res.IPs) == 0 {
+		return res, errors.New("no STUN server responded")
+	}
+
+	return res, nil
+}
+
+// queryAll sends binding requests to all servers, using a single local socket
+func (st *STUNService) queryAll(network string) []STUNResponse {
+	res := make([]STUNResponse, 0, len(st.stunServerURIs))
+
+	conn, err := net.ListenUDP(network, nil)
+	if err != nil {
+		for _, server := range st.stunServerURIs {
+			res = append(res, STUNResponse{Server: server, Network: network, Err: err})
 		}
+		return res
+	}
+	defer conn.Close()
 
-		message, err := stun.Build(stun.TransactionID, stun.BindingRequest)
+	for _, server := range st.stunServerURIs {
+		addr, err := st.query(conn, network, server)
 		if err != nil {
-			return ips, err
+			log.WithError(err).WithField("server", server).Debug("STUN server did not respond")
 		}
+		res = append(res, STUNResponse{
+			Server:     server,
+			Network:    network,
+			MappedAddr: addr,
+			Err:        err,
+		})
+	}
 
-		if err := c.Do(message, func(res stun.Event) {
-			if res.Error != nil {
-				return
-			}
+	return res
+}
 
-			var xorAddr stun.XORMappedAddress
-			if err := xorAddr.GetFrom(res.Message); err != nil {
-				return
-			}
-			ips = append(ips, xorAddr.IP)
-		}); err != nil {
-			return ips, err
+// query sends a binding request to a single server and waits for its response
+func (st *STUNService) query(conn *net.UDPConn, network, server string) (*net.UDPAddr, error) {
+	serverAddr, err := net.ResolveUDPAddr(network, server)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := stun.Build(stun.TransactionID, stun.BindingRequest)
+	if err != nil {
+		return nil, err
+	}
+	if _, err = conn.WriteToUDP(req.Raw, serverAddr); err != nil {
+		return nil, err
+	}
+
+	if err = conn.SetReadDeadline(time.Now().Add(st.timeout)); err != nil {
+		return nil, err
+	}
+	buf := make([]byte, 1500)
+	for {
+		n, _, err := conn.ReadFromUDP(buf)
+		if err != nil {
+			return nil, err
 		}
 
+		res := &stun.Message{Raw: append([]byte{}, buf[:n]...)}
+		if err = res.Decode(); err != nil || res.TransactionID != req.TransactionID {
+			// not a response to this request, e.g. a late response of another server
+			continue
+		}
+
+		var xorAddr stun.XORMappedAddress
+		if err = xorAddr.GetFrom(res); err != nil {
+			return nil, err
+		}
+		return &net.UDPAddr{IP: xorAddr.IP, Port: xorAddr.Port}, nil
 	}
+}
+
+// stunState keeps the latest STUN result of this node
+type stunState struct {
+	sync.Mutex
+
+	result *STUNResult
+}
+
+// SetSTUNResult keeps the latest STUN result, e.g. to show it in `wgmesh info`
+func (ms *MeshService) SetSTUNResult(r *STUNResult) {
+	ms.stun.Lock()
+	defer ms.stun.Unlock()
+
+	ms.stun.result = r
+}
+
+// STUNResult returns the latest STUN result, or nil if STUN has not been used
+func (ms *MeshService) STUNResult() *STUNResult {
+	ms.stun.Lock()
+	defer ms.stun.Unlock()
 
-	return ips, nil
+	return ms.stun.result
 }
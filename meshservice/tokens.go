@@ -0,0 +1,271 @@
+package meshservice
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// presharedToken is created on a bootstrap node ahead of time
+// and presented by joining nodes in the handshake.
+type presharedToken struct {
+	id    string
+	token string
+
+	// zero value means this token does not expire
+	expiresAt time.Time
+
+	// number of joins allowed, 0 for unlimited
+	maxUses int
+	uses    int
+
+	// optional, fixed name and mesh ip of the joining node
+	nodeName string
+	meshIP   net.IP
+
+	// tags the joining node has to set. They are advisory, the
+	// bootstrap node does not check the tags of joined nodes.
+	tags map[string]string
+}
+
+// storedToken is the persisted form of a pre-shared token
+type storedToken struct {
+	ID        string            `json:"id"`
+	Token     string            `json:"token"`
+	ExpiresAt int64             `json:"expiresAt,omitempty"`
+	MaxUses   int               `json:"maxUses"`
+	Uses      int               `json:"uses"`
+	NodeName  string            `json:"nodeName,omitempty"`
+	MeshIP    string            `json:"meshIP,omitempty"`
+	Tags      map[string]string `json:"tags,omitempty"`
+}
+
+// presharedTokenStore keeps all pre-shared tokens of a bootstrap node.
+// If a path is set, tokens are persisted as JSON.
+type presharedTokenStore struct {
+	sync.Mutex
+
+	path   string
+	tokens map[string]*presharedToken
+}
+
+func newPresharedTokenStore() *presharedTokenStore {
+	return &presharedTokenStore{
+		tokens: make(map[string]*presharedToken),
+	}
+}
+
+// load reads tokens from given file and persists all
+// further changes there. A missing file is not an error.
+func (s *presharedTokenStore) load(path string) error {
+	s.Lock()
+	defer s.Unlock()
+
+	s.path = path
+
+	b, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	stored := make([]storedToken, 0)
+	if err = json.Unmarshal(b, &stored); err != nil {
+		return err
+	}
+	for _, st := range stored {
+		pt := &presharedToken{
+			id:       st.ID,
+			token:    st.Token,
+			maxUses:  st.MaxUses,
+			uses:     st.Uses,
+			nodeName: st.NodeName,
+			meshIP:   net.ParseIP(st.MeshIP),
+			tags:     st.Tags,
+		}
+		if st.ExpiresAt != 0 {
+			pt.expiresAt = time.Unix(st.ExpiresAt, 0)
+		}
+		s.tokens[pt.id] = pt
+	}
+	log.WithField("num", len(stored)).Debug("Loaded pre-shared tokens")
+
+	return nil
+}
+
+// save writes all tokens to the file. Must be called with lock held.
+func (s *presharedTokenStore) save() error {
+	if s.path == "" {
+		return nil
+	}
+
+	stored := make([]storedToken, 0, len(s.tokens))
+	for _, pt := range s.sorted() {
+		st := storedToken{
+			ID:       pt.id,
+			Token:    pt.token,
+			MaxUses:  pt.maxUses,
+			Uses:     pt.uses,
+			NodeName: pt.nodeName,
+			Tags:     pt.tags,
+		}
+		if !pt.expiresAt.IsZero() {
+			st.ExpiresAt = pt.expiresAt.Unix()
+		}
+		if pt.meshIP != nil {
+			st.MeshIP = pt.meshIP.String()
+		}
+		stored = append(stored, st)
+	}
+
+	b, err := json.MarshalIndent(stored, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmpPath := s.path + ".tmp"
+	if err = ioutil.WriteFile(tmpPath, b, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmpPath, s.path)
+}
+
+// presharedTokenID derives the (public) ID of a pre-shared token
+func presharedTokenID(token string) string {
+	return JoinTokenID(token)[:12]
+}
+
+// create generates a new pre-shared token
+func (s *presharedTokenStore) create(expiresAt time.Time, maxUses int, nodeName string, meshIP net.IP, tags map[string]string) (presharedToken, error) {
+	token, err := randomTokenAsString()
+	if err != nil {
+		return presharedToken{}, err
+	}
+
+	pt := &presharedToken{
+		id:        presharedTokenID(token),
+		token:     token,
+		expiresAt: expiresAt,
+		maxUses:   maxUses,
+		nodeName:  nodeName,
+		meshIP:    meshIP,
+		tags:      tags,
+	}
+
+	s.Lock()
+	defer s.Unlock()
+
+	s.tokens[pt.id] = pt
+	if err = s.save(); err != nil {
+		delete(s.tokens, pt.id)
+		return presharedToken{}, err
+	}
+
+	return *pt, nil
+}
+
+// valid checks that the token has neither expired nor been used up
+func (pt *presharedToken) valid() error {
+	if !pt.expiresAt.IsZero() && time.Now().After(pt.expiresAt) {
+		return errors.New("token expired")
+	}
+	if pt.maxUses > 0 && pt.uses >= pt.maxUses {
+		return errors.New("token has been used up")
+	}
+	return nil
+}
+
+// lookup returns the ID of a token if it is valid
+func (s *presharedTokenStore) lookup(token string) (string, error) {
+	s.Lock()
+	defer s.Unlock()
+
+	pt, ok := s.tokens[presharedTokenID(token)]
+	if !ok || subtle.ConstantTimeCompare([]byte(pt.token), []byte(token)) != 1 {
+		return "", errors.New("unknown token")
+	}
+	if err := pt.valid(); err != nil {
+		return "", err
+	}
+
+	return pt.id, nil
+}
+
+// use counts a join for the token with given ID. It is counted right
+// away, so that concurrent joins do not exceed the allowed uses, and has
+// to be given back with release if the join fails.
+func (s *presharedTokenStore) use(id string) (presharedToken, error) {
+	s.Lock()
+	defer s.Unlock()
+
+	pt, ok := s.tokens[id]
+	if !ok {
+		return presharedToken{}, errors.New("unknown or revoked token")
+	}
+	if err := pt.valid(); err != nil {
+		return presharedToken{}, err
+	}
+	pt.uses++
+	if err := s.save(); err != nil {
+		log.WithError(err).Warn("Unable to persist pre-shared tokens")
+	}
+
+	return *pt, nil
+}
+
+// release gives back a use of the token with given ID, for a failed join
+func (s *presharedTokenStore) release(id string) {
+	s.Lock()
+	defer s.Unlock()
+
+	if pt, ok := s.tokens[id]; ok && pt.uses > 0 {
+		pt.uses--
+		if err := s.save(); err != nil {
+			log.WithError(err).Warn("Unable to persist pre-shared tokens")
+		}
+	}
+}
+
+// revoke removes a token, returns false if it does not exist
+func (s *presharedTokenStore) revoke(id string) bool {
+	s.Lock()
+	defer s.Unlock()
+
+	if _, ok := s.tokens[id]; !ok {
+		return false
+	}
+	delete(s.tokens, id)
+	if err := s.save(); err != nil {
+		log.WithError(err).Warn("Unable to persist pre-shared tokens")
+	}
+
+	return true
+}
+
+// list returns all tokens, sorted by id
+func (s *presharedTokenStore) list() []presharedToken {
+	s.Lock()
+	defer s.Unlock()
+
+	return s.sorted()
+}
+
+func (s *presharedTokenStore) sorted() []presharedToken {
+	res := make([]presharedToken, 0, len(s.tokens))
+	for _, pt := range s.tokens {
+		res = append(res, *pt)
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].id < res[j].id })
+
+	return res
+}
@@ -0,0 +1,52 @@
+package meshservice
+
+import (
+	"net"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestPresharedTokenStorePersistence(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tokens.json")
+
+	s := newPresharedTokenStore()
+	if err := s.load(path); err != nil {
+		t.Fatalf("unable to load missing token file: %s", err)
+	}
+	expiresAt := time.Now().Add(time.Hour).Truncate(time.Second)
+	pt, err := s.create(expiresAt, 2, "node", net.ParseIP("10.232.5.5"), map[string]string{"role": "db"})
+	if err != nil {
+		t.Fatalf("unable to create token: %s", err)
+	}
+	revoked, err := s.create(time.Time{}, 0, "", nil, nil)
+	if err != nil {
+		t.Fatalf("unable to create token: %s", err)
+	}
+	if _, err = s.use(pt.id); err != nil {
+		t.Fatalf("unable to use token: %s", err)
+	}
+	s.revoke(revoked.id)
+
+	s2 := newPresharedTokenStore()
+	if err = s2.load(path); err != nil {
+		t.Fatalf("unable to load tokens: %s", err)
+	}
+	tokens := s2.list()
+	if len(tokens) != 1 {
+		t.Fatalf("loaded %d tokens, want 1", len(tokens))
+	}
+	got := tokens[0]
+	if got.id != pt.id || got.token != pt.token || !got.expiresAt.Equal(expiresAt) ||
+		got.maxUses != 2 || got.uses != 1 || got.nodeName != "node" ||
+		!got.meshIP.Equal(pt.meshIP) || got.tags["role"] != "db" {
+		t.Errorf("loaded token %+v differs from created one %+v", got, pt)
+	}
+
+	if id, err := s2.lookup(pt.token); err != nil || id != pt.id {
+		t.Errorf("lookup of loaded token: %s, %v", id, err)
+	}
+	if _, err := s2.lookup(pt.token + "x"); err == nil {
+		t.Errorf("lookup of a wrong token succeeded")
+	}
+}
@@ -36,7 +36,7 @@ func NewUIServer(agentGrpcSocket string, httpBindAddr string, httpBindPort int)
 	}
 	box, err := conf.FindBox("../web/dist")
 	if err != nil {
-		log.WithError(err).Fatalf("unable to serve web interface\n", err)
+		log.WithError(err).Fatal("unable to serve web interface")
 	}
 
 	log.WithField("box", box).Trace("Loaded assets")